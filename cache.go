@@ -0,0 +1,103 @@
+package geval
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/myfstd/geval/core"
+)
+
+// DefaultCacheSize is the number of compiled expressions an Engine keeps unless told otherwise.
+const DefaultCacheSize = 1024
+
+// expressionCache is a least-recently-used cache of compiled expressions, keyed by expression text.
+type expressionCache struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	recency *list.List
+}
+
+type cacheEntry struct {
+	expression string
+	compiled   *core.TEvaluableExpression
+
+	// the generation of registered functions the expression was compiled with; see core.TRegisteredFunctionsGeneration.
+	generation uint64
+}
+
+func newExpressionCache(size int) *expressionCache {
+	return &expressionCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		recency: list.New(),
+	}
+}
+
+// get returns the cached compilation of [expression], unless there is none, or it was compiled with functions
+// other than those of the registered [generation], in which case it is discarded.
+func (c *expressionCache) get(expression string, generation uint64) (*core.TEvaluableExpression, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, found := c.entries[expression]
+	if !found {
+		return nil, false
+	}
+
+	if element.Value.(*cacheEntry).generation != generation {
+		c.recency.Remove(element)
+		delete(c.entries, expression)
+		return nil, false
+	}
+
+	c.recency.MoveToFront(element)
+	return element.Value.(*cacheEntry).compiled, true
+}
+
+func (c *expressionCache) put(expression string, compiled *core.TEvaluableExpression, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.size <= 0 {
+		return
+	}
+
+	element, found := c.entries[expression]
+	if found {
+		entry := element.Value.(*cacheEntry)
+		entry.compiled = compiled
+		entry.generation = generation
+		c.recency.MoveToFront(element)
+		return
+	}
+
+	c.entries[expression] = c.recency.PushFront(&cacheEntry{expression: expression, compiled: compiled, generation: generation})
+	c.evict()
+}
+
+// resize changes the number of entries kept, evicting the least recently used entries if there are now too many.
+// A size of zero or less disables caching.
+func (c *expressionCache) resize(size int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.size = size
+	c.evict()
+}
+
+func (c *expressionCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.recency.Init()
+}
+
+func (c *expressionCache) evict() {
+	for c.recency.Len() > 0 && c.recency.Len() > c.size {
+		oldest := c.recency.Back()
+		c.recency.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).expression)
+	}
+}
@@ -0,0 +1,35 @@
+// Command geval-server serves expression compilation, validation, and evaluation over HTTP,
+// for services which are not written in Go. See package server for its endpoints.
+//
+//	geval-server [-addr :8080] [-rate 0] [-burst 10] [-cache 1024]
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/myfstd/geval"
+	"github.com/myfstd/geval/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "the address to listen on")
+	rate := flag.Float64("rate", 0, "the requests per second allowed to each client; 0 for no limit")
+	burst := flag.Int("burst", 10, "the requests each client may make at once, before being held to -rate")
+	cache := flag.Int("cache", geval.DefaultCacheSize, "the number of compiled expressions to keep")
+	flag.Parse()
+
+	engine := geval.NewEngine().SetCacheSize(*cache)
+	handler := server.NewHandler(engine, server.Options{RatePerSecond: *rate, Burst: *burst})
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Printf("geval-server listening on %s", *addr)
+	log.Fatal(httpServer.ListenAndServe())
+}
@@ -0,0 +1,92 @@
+//go:build js && wasm
+
+// Command geval-wasm exposes expression compilation, validation, and evaluation to JavaScript,
+// so that web rule editors can check and preview expressions with the same semantics as a Go backend.
+//
+//	GOOS=js GOARCH=wasm go build -o geval.wasm ./cmd/geval-wasm
+//
+// Once loaded with wasm_exec.js, it defines a global "geval" object, whose functions return an object with
+// an "error" message if they fail:
+//
+//	geval.compile("score > limit")                          // {expression: "score > limit", vars: ["score", "limit"]}
+//	geval.validate("score >")                               // {valid: false, error: "Unexpected end of expression"}
+//	geval.evaluate("score > limit", {score: 12, limit: 10}) // {result: true}
+//
+// Parameters and results pass through JSON, so numbers are float64s, and times are RFC 3339 strings,
+// just as they are for geval-server.
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"github.com/myfstd/geval"
+)
+
+var engine = geval.NewEngine()
+
+func main() {
+	js.Global().Set("geval", js.ValueOf(map[string]interface{}{
+		"compile":  js.FuncOf(compile),
+		"validate": js.FuncOf(validate),
+		"evaluate": js.FuncOf(evaluate),
+	}))
+
+	// keep the exported functions alive.
+	select {}
+}
+
+func compile(this js.Value, arguments []js.Value) interface{} {
+	compiled, err := engine.Compile(argument(arguments, 0))
+	if err != nil {
+		return failure(err)
+	}
+
+	vars := make([]interface{}, 0)
+	for _, name := range compiled.TVars() {
+		vars = append(vars, name)
+	}
+	return map[string]interface{}{"expression": compiled.TFormat(), "vars": vars}
+}
+
+func validate(this js.Value, arguments []js.Value) interface{} {
+	err := engine.Validate(argument(arguments, 0))
+	if err != nil {
+		return map[string]interface{}{"valid": false, "error": err.Error()}
+	}
+	return map[string]interface{}{"valid": true}
+}
+
+func evaluate(this js.Value, arguments []js.Value) interface{} {
+	parameters := make(map[string]interface{})
+	if len(arguments) > 1 && arguments[1].Type() == js.TypeObject {
+		err := json.Unmarshal([]byte(js.Global().Get("JSON").Call("stringify", arguments[1]).String()), &parameters)
+		if err != nil {
+			return failure(err)
+		}
+	}
+
+	result, err := engine.Evaluate(argument(arguments, 0), parameters)
+	if err != nil {
+		return failure(err)
+	}
+
+	// js.ValueOf only accepts a few types, so the result takes the same path through JSON as parameters.
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return failure(err)
+	}
+	return map[string]interface{}{"result": js.Global().Get("JSON").Call("parse", string(encoded))}
+}
+
+// argument returns the argument at [index] as a string, or an empty one if it was not given.
+func argument(arguments []js.Value, index int) string {
+	if index >= len(arguments) || arguments[index].Type() != js.TypeString {
+		return ""
+	}
+	return arguments[index].String()
+}
+
+func failure(err error) map[string]interface{} {
+	return map[string]interface{}{"error": err.Error()}
+}
@@ -0,0 +1,229 @@
+// Command geval evaluates expressions from the command line, for debugging rules outside the application.
+//
+//	geval [-p name=value]... [-f parameters.json]... [expression]...
+//
+// Each expression given as an argument is evaluated and its result printed. Without arguments, expressions are
+// read from standard input, one per line. With -repl, lines are read interactively instead, and `let name = expression`
+// stores a result as a parameter for the lines which follow.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/myfstd/geval"
+)
+
+// parameterFlags collects repeated -p name=value flags.
+type parameterFlags map[string]interface{}
+
+func (p parameterFlags) String() string {
+	return fmt.Sprintf("%v", map[string]interface{}(p))
+}
+
+func (p parameterFlags) Set(value string) error {
+	name, raw, found := strings.Cut(value, "=")
+	if !found || name == "" {
+		return fmt.Errorf("expected name=value, got %q", value)
+	}
+
+	p[name] = parseValue(raw)
+	return nil
+}
+
+// fileFlags collects repeated -f flags.
+type fileFlags []string
+
+func (f *fileFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	parameters := make(parameterFlags)
+	var files fileFlags
+
+	flag.Var(parameters, "p", "set a parameter, as name=value; may be repeated")
+	flag.Var(&files, "f", "read parameters from a JSON object in this file; may be repeated")
+	repl := flag.Bool("repl", false, "read expressions interactively, keeping variables set with `let`")
+	flag.Parse()
+
+	merged := make(map[string]interface{})
+	for _, file := range files {
+		err := readParameterFile(file, merged)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "geval:", err)
+			os.Exit(2)
+		}
+	}
+	for name, value := range parameters {
+		merged[name] = value
+	}
+
+	engine := geval.NewEngine()
+
+	if *repl {
+		runREPL(engine, merged, os.Stdin, os.Stdout)
+		return
+	}
+
+	failed := false
+	evaluate := func(expression string) {
+		result, err := engine.Evaluate(expression, merged)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "geval:", err)
+			failed = true
+			return
+		}
+		fmt.Println(formatResult(result))
+	}
+
+	if flag.NArg() > 0 {
+		for _, expression := range flag.Args() {
+			evaluate(expression)
+		}
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" {
+				evaluate(line)
+			}
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// runREPL reads lines from [in] until it is exhausted or `:quit` is entered.
+func runREPL(engine *geval.Engine, parameters map[string]interface{}, in io.Reader, out io.Writer) {
+	variables := make(map[string]interface{}, len(parameters))
+	for name, value := range parameters {
+		variables[name] = value
+	}
+
+	fmt.Fprintln(out, "geval REPL. `let name = expression` stores a variable, :vars lists them, :quit exits.")
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+
+		case line == ":quit":
+			return
+
+		case line == ":vars":
+			names := make([]string, 0, len(variables))
+			for name := range variables {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				fmt.Fprintf(out, "%s = %s\n", name, formatResult(variables[name]))
+			}
+			continue
+		}
+
+		name, expression, isLet := parseLet(line)
+
+		result, err := engine.Evaluate(expression, variables)
+		if err != nil {
+			fmt.Fprintln(out, "error:", err)
+			continue
+		}
+
+		if isLet {
+			variables[name] = result
+		}
+		fmt.Fprintln(out, formatResult(result))
+	}
+}
+
+// parseLet splits a line of the form `let name = expression`. Other lines are returned as the expression.
+func parseLet(line string) (string, string, bool) {
+	rest, found := strings.CutPrefix(line, "let ")
+	if !found {
+		return "", line, false
+	}
+
+	name, expression, found := strings.Cut(rest, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || strings.ContainsAny(name, " \t") {
+		return "", line, false
+	}
+	return name, strings.TrimSpace(expression), true
+}
+
+func readParameterFile(path string, into map[string]interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var parameters map[string]interface{}
+	err = json.Unmarshal(data, &parameters)
+	if err != nil {
+		return errors.New(path + ": expected a JSON object of parameters: " + err.Error())
+	}
+
+	for name, value := range parameters {
+		into[name] = value
+	}
+	return nil
+}
+
+// parseValue reads a -p value as a number, bool, or nil if it looks like one, or as a string otherwise.
+func parseValue(raw string) interface{} {
+	switch raw {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "nil":
+		return nil
+	}
+
+	number, err := strconv.ParseFloat(raw, 64)
+	if err == nil {
+		return number
+	}
+	return raw
+}
+
+func formatResult(result interface{}) string {
+	switch typed := result.(type) {
+	case string:
+		return strconv.Quote(typed)
+	case nil:
+		return "nil"
+	case []interface{}, map[string]interface{}:
+		encoded, err := json.Marshal(typed)
+		if err == nil {
+			return string(encoded)
+		}
+	}
+	return fmt.Sprintf("%v", result)
+}
@@ -0,0 +1,152 @@
+// Package govaluate exposes the API of github.com/Knetic/govaluate, backed by geval, so that code written against
+// govaluate can switch to geval by changing only its import path:
+//
+//	import "github.com/myfstd/geval/compat/govaluate"
+//
+// Expressions are compiled and evaluated by geval, but lexed as govaluate lexes them: a backslash in a string only
+// escapes the character after it, and there are no built-in functions, so that calls to functions such as `len`
+// must be given their function as they are to govaluate. OperatorSymbol is exposed as govaluate exposes it, although
+// no part of the API takes or gives one; govaluate's stages are unexported, so there are none to expose.
+package govaluate
+
+import (
+	"context"
+	"errors"
+
+	"github.com/myfstd/geval/core"
+)
+
+// ExpressionFunction is a function which can be called from an expression.
+type ExpressionFunction = core.TExpressionFunction
+
+// Parameters is a source of parameter values, looked up by name as an expression refers to them.
+type Parameters interface {
+	Get(name string) (interface{}, error)
+}
+
+// MapParameters is a Parameters backed by a map.
+type MapParameters map[string]interface{}
+
+// Get returns the value of the parameter [name], or an error if there is no such parameter.
+func (p MapParameters) Get(name string) (interface{}, error) {
+	value, found := p[name]
+	if !found {
+		return nil, errors.New("No parameter '" + name + "' found.")
+	}
+	return value, nil
+}
+
+// isoDateFormat is the format ToSQLQuery writes times in unless QueryDateFormat is changed, as govaluate's is.
+const isoDateFormat = "2006-01-02T15:04:05.999999999Z0700"
+
+// options are those which make geval lex expressions as govaluate does.
+var options = core.TExpressionOptions{
+	LegacyStringEscapes:  true,
+	OmitBuiltinFunctions: true,
+}
+
+// DUMMY_PARAMETERS is used by expressions evaluated without parameters.
+var DUMMY_PARAMETERS = MapParameters(map[string]interface{}{})
+
+// EvaluableExpression is a compiled expression, which may be evaluated any number of times.
+type EvaluableExpression struct {
+
+	// QueryDateFormat is the format ToSQLQuery writes times in.
+	QueryDateFormat string
+
+	// ChecksTypes is whether operands are type-checked before being given to operators.
+	// Only set it to false when parameters are known to be of the right types; operators will panic on the wrong ones.
+	ChecksTypes bool
+
+	expression *core.TEvaluableExpression
+	input      string
+}
+
+// NewEvaluableExpression compiles [expression].
+func NewEvaluableExpression(expression string) (*EvaluableExpression, error) {
+	return NewEvaluableExpressionWithFunctions(expression, map[string]ExpressionFunction{})
+}
+
+// NewEvaluableExpressionWithFunctions compiles [expression], which may call any of [functions] by name.
+func NewEvaluableExpressionWithFunctions(expression string, functions map[string]ExpressionFunction) (*EvaluableExpression, error) {
+	compiled, err := core.TNewEvaluableExpressionWithFunctionsAndOptions(expression, functions, options)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(compiled, expression), nil
+}
+
+// NewEvaluableExpressionFromTokens compiles an expression from already-lexed [tokens],
+// such as those returned by another expression's Tokens.
+func NewEvaluableExpressionFromTokens(tokens []ExpressionToken) (*EvaluableExpression, error) {
+	converted := make([]core.TExpressionToken, len(tokens))
+	for i, token := range tokens {
+		converted[i] = core.TExpressionToken{Kind: token.Kind.core(), Value: token.Value}
+	}
+
+	compiled, err := core.TNewEvaluableExpressionFromTokens(converted)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(compiled, compiled.TDisplayString()), nil
+}
+
+func wrap(compiled *core.TEvaluableExpression, input string) *EvaluableExpression {
+	return &EvaluableExpression{
+		QueryDateFormat: isoDateFormat,
+		ChecksTypes:     true,
+		expression:      compiled,
+		input:           input,
+	}
+}
+
+// Evaluate evaluates this expression against [parameters], which may be nil if the expression has none.
+func (e EvaluableExpression) Evaluate(parameters map[string]interface{}) (interface{}, error) {
+	if parameters == nil {
+		return e.Eval(nil)
+	}
+	return e.Eval(MapParameters(parameters))
+}
+
+// Eval evaluates this expression against [parameters], which may be nil if the expression has none.
+func (e EvaluableExpression) Eval(parameters Parameters) (interface{}, error) {
+	options := core.TEvaluationOptions{SkipTypeChecks: !e.ChecksTypes}
+
+	switch typed := parameters.(type) {
+	case nil:
+		return e.expression.TEvaluateWithOptions(nil, options)
+	case MapParameters:
+		return e.expression.TEvaluateWithOptions(core.TNewMapParameters(typed), options)
+	}
+	return e.expression.TEvaluateWithOptions(core.TNewContextParameters(getter{parameters}), options)
+}
+
+// Tokens returns the tokens this expression was lexed into.
+func (e EvaluableExpression) Tokens() []ExpressionToken {
+	tokens := e.expression.TTokenList()
+
+	ret := make([]ExpressionToken, len(tokens))
+	for i, token := range tokens {
+		ret[i] = ExpressionToken{Kind: kindOf(token.Kind), Value: token.Value}
+	}
+	return ret
+}
+
+// String returns the text this expression was compiled from.
+func (e EvaluableExpression) String() string {
+	return e.input
+}
+
+// Vars returns the names of the parameters this expression refers to.
+func (e EvaluableExpression) Vars() []string {
+	return e.expression.TVars()
+}
+
+// getter adapts Parameters to the parameter sources geval evaluates against.
+type getter struct {
+	parameters Parameters
+}
+
+func (g getter) TGet(_ context.Context, name string) (interface{}, error) {
+	return g.parameters.Get(name)
+}
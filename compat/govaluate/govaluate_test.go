@@ -0,0 +1,81 @@
+package govaluate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLexesAsGovaluate(t *testing.T) {
+
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{`'a\nb'`, "anb"},
+		{`'it\'s'`, "it's"},
+		{`"hello ${name}"`, "hello ${name}"},
+		{`len + 1`, 3.0},
+		{`name == 'Ada'`, true},
+	}
+
+	for _, c := range cases {
+
+		expression, err := NewEvaluableExpression(c.expression)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expression, err)
+		}
+		result, err := expression.Evaluate(map[string]interface{}{"name": "Ada", "len": 2.0})
+		if err != nil || result != c.expected {
+			t.Errorf("%s: expected %v, got %v (%v)", c.expression, c.expected, result, err)
+		}
+	}
+}
+
+func TestHasNoBuiltinFunctions(t *testing.T) {
+
+	for _, text := range []string{"len('abc') > 1", "now() > 0", "max(1, 2)"} {
+
+		_, err := NewEvaluableExpression(text)
+		if err == nil || !strings.Contains(err.Error(), "function") {
+			t.Errorf("%s: expected an undefined function, got %v", text, err)
+		}
+	}
+
+	length := func(arguments ...interface{}) (interface{}, error) {
+		return float64(len(arguments[0].(string))), nil
+	}
+	expression, err := NewEvaluableExpressionWithFunctions("len('abc')", map[string]ExpressionFunction{"len": length})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := expression.Evaluate(nil)
+	if err != nil || result != 3.0 {
+		t.Errorf("expected a given len to be called, got %v (%v)", result, err)
+	}
+}
+
+func TestOperatorSymbol(t *testing.T) {
+
+	cases := []struct {
+		symbol   OperatorSymbol
+		expected string
+	}{
+		{VALUE, "VALUE"},
+		{EQ, "="},
+		{EXPONENT, "**"},
+		{TERNARY_FALSE, ":"},
+		{COALESCE, "??"},
+		{LITERAL, ""},
+		{SEPARATE, ""},
+	}
+
+	for _, c := range cases {
+		if c.symbol.String() != c.expected {
+			t.Errorf("expected %d to be %q, got %q", c.symbol, c.expected, c.symbol.String())
+		}
+	}
+
+	if !MINUS.IsModifierType([]OperatorSymbol{PLUS, MINUS}) || NEGATE.IsModifierType([]OperatorSymbol{PLUS, MINUS}) {
+		t.Errorf("expected IsModifierType to find exactly the symbols given")
+	}
+}
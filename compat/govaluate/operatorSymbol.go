@@ -0,0 +1,95 @@
+package govaluate
+
+// OperatorSymbol is the symbol of an operator, as govaluate plans them.
+type OperatorSymbol int
+
+const (
+	VALUE OperatorSymbol = iota
+	LITERAL
+	NOOP
+	EQ
+	NEQ
+	GT
+	LT
+	GTE
+	LTE
+	REQ
+	NREQ
+	IN
+
+	AND
+	OR
+
+	PLUS
+	MINUS
+	BITWISE_AND
+	BITWISE_OR
+	BITWISE_XOR
+	BITWISE_LSHIFT
+	BITWISE_RSHIFT
+	MULTIPLY
+	DIVIDE
+	MODULUS
+	EXPONENT
+
+	NEGATE
+	INVERT
+	BITWISE_NOT
+
+	TERNARY_TRUE
+	TERNARY_FALSE
+	COALESCE
+
+	FUNCTIONAL
+	ACCESS
+	SEPARATE
+)
+
+var symbolNames = map[OperatorSymbol]string{
+	NOOP:           "NOOP",
+	VALUE:          "VALUE",
+	EQ:             "=",
+	NEQ:            "!=",
+	GT:             ">",
+	LT:             "<",
+	GTE:            ">=",
+	LTE:            "<=",
+	REQ:            "=~",
+	NREQ:           "!~",
+	AND:            "&&",
+	OR:             "||",
+	IN:             "in",
+	BITWISE_AND:    "&",
+	BITWISE_OR:     "|",
+	BITWISE_XOR:    "^",
+	BITWISE_LSHIFT: "<<",
+	BITWISE_RSHIFT: ">>",
+	PLUS:           "+",
+	MINUS:          "-",
+	MULTIPLY:       "*",
+	DIVIDE:         "/",
+	MODULUS:        "%",
+	EXPONENT:       "**",
+	NEGATE:         "-",
+	INVERT:         "!",
+	BITWISE_NOT:    "~",
+	TERNARY_TRUE:   "?",
+	TERNARY_FALSE:  ":",
+	COALESCE:       "??",
+}
+
+// IsModifierType returns whether the symbol is one of [candidate].
+func (symbol OperatorSymbol) IsModifierType(candidate []OperatorSymbol) bool {
+	for _, symbolType := range candidate {
+		if symbol == symbolType {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the operator the symbol is written as, such as "&&", or its name for NOOP and VALUE.
+// Symbols which are not written as operators, such as LITERAL, give "".
+func (symbol OperatorSymbol) String() string {
+	return symbolNames[symbol]
+}
@@ -0,0 +1,117 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ToSQLQuery returns this expression as the predicate of a SQL WHERE clause, as govaluate does.
+// Parameters are written as [bracketed] column names, times in QueryDateFormat, and `=~` as RLIKE.
+// Functions, accessors, ternaries, exponents, and bitwise operators have no SQL form, and fail with an error.
+func (e EvaluableExpression) ToSQLQuery() (string, error) {
+	tokens := e.Tokens()
+	rendered := make([]string, 0, len(tokens))
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		var text string
+
+		switch token.Kind {
+		case STRING:
+			text = quoteSQL(token.Value.(string))
+		case PATTERN:
+			text = quoteSQL(token.Value.(*regexp.Regexp).String())
+		case TIME:
+			text = quoteSQL(token.Value.(time.Time).Format(e.QueryDateFormat))
+		case NUMERIC:
+			// hex literals too large to be exact as a float64 are kept as uint64s.
+			switch value := token.Value.(type) {
+			case uint64:
+				text = strconv.FormatUint(value, 10)
+			default:
+				text = strconv.FormatFloat(value.(float64), 'g', -1, 64)
+			}
+		case BOOLEAN:
+			text = "0"
+			if token.Value.(bool) {
+				text = "1"
+			}
+		case NIL:
+			text = "NULL"
+		case VARIABLE:
+			text = "[" + token.Value.(string) + "]"
+		case CLAUSE:
+			text = "("
+		case CLAUSE_CLOSE:
+			text = ")"
+		case SEPARATOR:
+			text = ","
+
+		case LOGICALOP:
+			switch token.Value {
+			case "&&":
+				text = "AND"
+			case "||":
+				text = "OR"
+			}
+
+		case PREFIX:
+			switch token.Value {
+			case "!":
+				text = "NOT"
+			case "-":
+				text = "-"
+			}
+
+		case MODIFIER:
+			switch token.Value {
+			case "+", "-", "*", "/", "%":
+				text = token.Value.(string)
+			}
+
+		case COMPARATOR:
+			// comparisons with NULL are never true in SQL, but are tested for with IS.
+			if i+1 < len(tokens) && tokens[i+1].Kind == NIL && (token.Value == "==" || token.Value == "!=") {
+				text = "IS NULL"
+				if token.Value == "!=" {
+					text = "IS NOT NULL"
+				}
+				i++
+				break
+			}
+
+			switch token.Value {
+			case "==":
+				text = "="
+			case "!=":
+				text = "<>"
+			case "=~":
+				text = "RLIKE"
+			case "!~":
+				text = "NOT RLIKE"
+			case "in":
+				text = "IN"
+			case ">", ">=", "<", "<=":
+				text = token.Value.(string)
+			}
+		}
+
+		if text == "" {
+			if token.Kind == FUNCTION {
+				return "", errors.New("Functions cannot be written as SQL")
+			}
+			return "", fmt.Errorf("Unable to write %v token '%v' as SQL", token.Kind, token.Value)
+		}
+		rendered = append(rendered, text)
+	}
+
+	return strings.Join(rendered, " "), nil
+}
+
+func quoteSQL(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
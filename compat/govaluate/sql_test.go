@@ -0,0 +1,29 @@
+package govaluate
+
+import (
+	"testing"
+)
+
+func TestToSQLQuery(t *testing.T) {
+
+	cases := []struct {
+		expression string
+		expected   string
+	}{
+		{"a > 1 && b == 'x'", "[a] > 1 AND [b] = 'x'"},
+		{"a == 0xFFFFFFFFFFFFFFFF", "[a] = 18446744073709551615"},
+		{"a == 0x10", "[a] = 16"},
+	}
+
+	for _, c := range cases {
+
+		expression, err := NewEvaluableExpression(c.expression)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expression, err)
+		}
+		query, err := expression.ToSQLQuery()
+		if err != nil || query != c.expected {
+			t.Errorf("%s: expected %s, got %s (%v)", c.expression, c.expected, query, err)
+		}
+	}
+}
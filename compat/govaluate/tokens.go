@@ -0,0 +1,109 @@
+package govaluate
+
+import (
+	"github.com/myfstd/geval/core"
+)
+
+// ExpressionToken is a single token of a lexed expression.
+// Function tokens hold a value which NewEvaluableExpressionFromTokens accepts, as well as an ExpressionFunction.
+type ExpressionToken struct {
+	Kind  TokenKind
+	Value interface{}
+}
+
+// TokenKind is the kind of an ExpressionToken.
+type TokenKind int
+
+const (
+	UNKNOWN TokenKind = iota
+
+	PREFIX
+	NUMERIC
+	BOOLEAN
+	STRING
+	PATTERN
+	TIME
+	VARIABLE
+	FUNCTION
+	SEPARATOR
+	ACCESSOR
+
+	COMPARATOR
+	LOGICALOP
+	MODIFIER
+
+	CLAUSE
+	CLAUSE_CLOSE
+
+	TERNARY
+
+	// NIL is the kind of the literal nil, which geval accepts, but govaluate does not.
+	NIL
+)
+
+// the kinds of geval's tokens, in the order of govaluate's.
+var coreKinds = []core.TTokenKind{
+	UNKNOWN:      core.TUNKNOWN,
+	PREFIX:       core.TPREFIX,
+	NUMERIC:      core.TNUMERIC,
+	BOOLEAN:      core.TBOOLEAN,
+	STRING:       core.TSTRING,
+	PATTERN:      core.TPATTERN,
+	TIME:         core.TTIME,
+	VARIABLE:     core.TVARIABLE,
+	FUNCTION:     core.TFUNCTION,
+	SEPARATOR:    core.TSEPARATOR,
+	ACCESSOR:     core.TACCESSOR,
+	COMPARATOR:   core.TCOMPARATOR,
+	LOGICALOP:    core.TLOGICALOP,
+	MODIFIER:     core.TMODIFIER,
+	CLAUSE:       core.TCLAUSE,
+	CLAUSE_CLOSE: core.TCLAUSE_CLOSE,
+	TERNARY:      core.TTERNARY,
+	NIL:          core.TNIL,
+}
+
+var kindNames = []string{
+	UNKNOWN:      "UNKNOWN",
+	PREFIX:       "PREFIX",
+	NUMERIC:      "NUMERIC",
+	BOOLEAN:      "BOOLEAN",
+	STRING:       "STRING",
+	PATTERN:      "PATTERN",
+	TIME:         "TIME",
+	VARIABLE:     "VARIABLE",
+	FUNCTION:     "FUNCTION",
+	SEPARATOR:    "SEPARATOR",
+	ACCESSOR:     "ACCESSOR",
+	COMPARATOR:   "COMPARATOR",
+	LOGICALOP:    "LOGICALOP",
+	MODIFIER:     "MODIFIER",
+	CLAUSE:       "CLAUSE",
+	CLAUSE_CLOSE: "CLAUSE_CLOSE",
+	TERNARY:      "TERNARY",
+	NIL:          "NIL",
+}
+
+// String returns the name of the kind, such as "NUMERIC".
+func (kind TokenKind) String() string {
+	if kind < 0 || int(kind) >= len(kindNames) {
+		return "UNKNOWN"
+	}
+	return kindNames[kind]
+}
+
+func (kind TokenKind) core() core.TTokenKind {
+	if kind < 0 || int(kind) >= len(coreKinds) {
+		return core.TUNKNOWN
+	}
+	return coreKinds[kind]
+}
+
+func kindOf(kind core.TTokenKind) TokenKind {
+	for i, coreKind := range coreKinds {
+		if coreKind == kind {
+			return TokenKind(i)
+		}
+	}
+	return UNKNOWN
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 const isoDateFormat string = "2006-01-02T15:04:05.999999999Z0700"
@@ -10,52 +11,203 @@ const shortCircuitHolder int = -1
 
 var tDUMMY_PARAMETERS = tMapParameters(map[string]interface{}{})
 
+/*
+A compiled expression. Compiled expressions are never modified once compiled, so one may be shared between
+any number of goroutines. Anything which varies from one evaluation to the next belongs in TEvaluationOptions instead.
+*/
 type tEvaluableExpression struct {
-	QueryDateFormat  string
-	ChecksTypes      bool
 	tokens           []tExpressionToken
 	evaluationStages *evaluationStage
+	referenceStages  *evaluationStage
 	inputExpression  string
+	options          TExpressionOptions
+
+	// whether operands are type-checked, unless TExpressionOptions.SkipTypeChecks or TEvaluationOptions.SkipTypeChecks says otherwise.
+	checksTypes bool
+
+	// how many results of common subexpressions each evaluation memoizes.
+	memoSlots int
+
+	// only set on the per-call copies made by TProfile and TEvaluateWithOptions.
+	profiler   *TStageProfiler
+	preemption *stagePreemption
+
+	// only set on the per-call copies made by tEval, if any stages are memoized.
+	memo []memoizedResult
 }
 
+/*
+TEvaluableExpression allows packages outside of core to refer to compiled expressions.
+*/
+type TEvaluableExpression = tEvaluableExpression
+
 func TNewEvaluableExpression(expression string) (*tEvaluableExpression, error) {
 	functions := make(map[string]tExpressionFunction)
-	return tNewEvaluableExpressionWithFunctions(expression, functions)
+	return TNewEvaluableExpressionWithFunctions(expression, functions)
+}
+
+func TNewEvaluableExpressionWithOptions(expression string, options TExpressionOptions) (*tEvaluableExpression, error) {
+	functions := make(map[string]tExpressionFunction)
+	return TNewEvaluableExpressionWithFunctionsAndOptions(expression, functions, options)
 }
 
-func tNewEvaluableExpressionWithFunctions(expression string, functions map[string]tExpressionFunction) (*tEvaluableExpression, error) {
+func TNewEvaluableExpressionWithFunctions(expression string, functions map[string]tExpressionFunction) (*tEvaluableExpression, error) {
+	return TNewEvaluableExpressionWithFunctionsAndOptions(expression, functions, TExpressionOptions{})
+}
+
+func TNewEvaluableExpressionWithFunctionsAndOptions(expression string, functions map[string]tExpressionFunction, options TExpressionOptions) (*tEvaluableExpression, error) {
 	var ret *tEvaluableExpression
 	var err error
 	ret = new(tEvaluableExpression)
-	ret.QueryDateFormat = isoDateFormat
 	ret.inputExpression = expression
-	ret.tokens, err = parseTokens(expression, functions)
+
+	options.Features, err = resolveFeatures(options.Features)
 	if err != nil {
 		return nil, err
 	}
+	ret.options = options
 
-	err = checkBalance(ret.tokens)
+	options.PureFunctions = mergeRegisteredPureFunctions(functions, options.PureFunctions)
+	functions = mergeRegisteredFunctions(functions, options)
+	tokens, err := parseTokens(expression, functions, options)
 	if err != nil {
 		return nil, err
 	}
-	err = checkExpressionSyntax(ret.tokens)
+
+	err = ret.compile(tokens, options)
 	if err != nil {
 		return nil, err
 	}
-	ret.tokens, err = optimizeTokens(ret.tokens)
+	return ret, nil
+}
+
+/*
+Compiles an expression from already-lexed [tokens], such as those of another expression, with default options.
+Function tokens may hold either the function they call, or the value a function token of another expression holds.
+*/
+func TNewEvaluableExpressionFromTokens(tokens []TExpressionToken) (*tEvaluableExpression, error) {
+
+	ret := new(tEvaluableExpression)
+
+	options := TExpressionOptions{}
+	var err error
+
+	options.Features, err = resolveFeatures(options.Features)
 	if err != nil {
 		return nil, err
 	}
+	ret.options = options
+
+	copied := make([]tExpressionToken, len(tokens))
+	for i, token := range tokens {
+
+		if function, isFunction := token.Value.(tExpressionFunction); isFunction && token.Kind == tFUNCTION {
+			token.Value = tNamedFunction{function: function}
+		}
+		copied[i] = token
+	}
 
-	ret.evaluationStages, err = planStages(ret.tokens)
+	err = ret.compile(copied, options)
 	if err != nil {
 		return nil, err
 	}
 
-	ret.ChecksTypes = true
+	ret.inputExpression = renderTokens(ret.tokens, nil)
 	return ret, nil
 }
 
+/*
+Checks and plans the given lexed [tokens] into this expression.
+*/
+func (t *tEvaluableExpression) compile(tokens []tExpressionToken, options TExpressionOptions) error {
+
+	var err error
+
+	err = checkBalance(tokens)
+	if err != nil {
+		return err
+	}
+	err = checkExpressionSyntax(tokens)
+	if err != nil {
+		return err
+	}
+	err = checkDenied(tokens, options.Deny)
+	if err != nil {
+		return err
+	}
+	err = checkAllowed(tokens, options.Allow)
+	if err != nil {
+		return err
+	}
+	t.tokens, err = optimizeTokens(tokens)
+	if err != nil {
+		return err
+	}
+
+	t.evaluationStages, err = planStages(t.tokens, options)
+	if err != nil {
+		return err
+	}
+	t.memoSlots = countMemoSlots(t.evaluationStages)
+
+	if options.Failover {
+		t.referenceStages, err = planReferenceStages(t.tokens, options)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.checksTypes = !options.SkipTypeChecks
+	return nil
+}
+
+/*
+Returns the tokens this expression was lexed into, as TTokens iterates over.
+*/
+func (t tEvaluableExpression) TTokenList() []TExpressionToken {
+
+	ret := make([]TExpressionToken, len(t.tokens))
+	copy(ret, t.tokens)
+	return ret
+}
+
+/*
+TEvaluationOptions changes how a single evaluation is performed, without changing the expression itself.
+The zero value gives the default behavior.
+*/
+type TEvaluationOptions struct {
+
+	// If set, operands are not type-checked before being given to operators.
+	// Only use this when parameters are known to be of the right types; operators will panic on the wrong ones.
+	SkipTypeChecks bool
+
+	// If set, evaluation gives up with TErrPreempted once this time has passed.
+	// The clock is only checked every so many stages, so evaluation may overrun it slightly.
+	Deadline time.Time
+
+	// If set, is told of every stage of this evaluation, in place of the expression's own TExpressionOptions.Tracer.
+	// A TDebugger is one.
+	Tracer TTracer
+}
+
+/*
+Evaluates this expression against [parameters] with the given per-call [options].
+*/
+func (t tEvaluableExpression) TEvaluateWithOptions(parameters tParameters, options TEvaluationOptions) (interface{}, error) {
+
+	// [t] is this call's own copy, so adjusting it cannot affect concurrent evaluations.
+	if options.SkipTypeChecks {
+		t.checksTypes = false
+	}
+	if !options.Deadline.IsZero() {
+		t.preemption = &stagePreemption{deadline: options.Deadline}
+	}
+	if options.Tracer != nil {
+		t.options.Tracer = options.Tracer
+	}
+	return t.tEval(parameters)
+}
+
 func (t tEvaluableExpression) TEvaluate(parameters map[string]interface{}) (interface{}, error) {
 
 	if parameters == nil {
@@ -65,6 +217,13 @@ func (t tEvaluableExpression) TEvaluate(parameters map[string]interface{}) (inte
 	return t.tEval(tMapParameters(parameters))
 }
 
+/*
+Evaluates this expression against any parameter source, such as those made by TNewMapParameters or TNewChainedParameters.
+*/
+func (t tEvaluableExpression) TEvaluateParameters(parameters tParameters) (interface{}, error) {
+	return t.tEval(parameters)
+}
+
 func (t tEvaluableExpression) tEval(parameters tParameters) (interface{}, error) {
 
 	if t.evaluationStages == nil {
@@ -72,18 +231,79 @@ func (t tEvaluableExpression) tEval(parameters tParameters) (interface{}, error)
 	}
 
 	if parameters != nil {
+		match := parameterNameMatcher(t.options)
+		if match != nil {
+			parameters = inexactParameters{parameters, match}
+		}
 		parameters = &sanitizedParameters{parameters}
 	} else {
 		parameters = tDUMMY_PARAMETERS
 	}
 
+	if t.options.LenientParameters {
+		parameters = lenientParameters{parameters}
+	}
+
+	if t.memoSlots > 0 {
+		t.memo = make([]memoizedResult, t.memoSlots)
+	}
+
+	if t.referenceStages != nil {
+		return t.evaluateWithFailover(parameters)
+	}
+
 	return t.evaluateStage(t.evaluationStages, parameters)
 }
 
 func (t tEvaluableExpression) evaluateStage(stage *evaluationStage, parameters tParameters) (interface{}, error) {
 
+	// [t.memo] is nil wherever stages are evaluated outside of tEval, such as when literals are folded.
+	if stage.memo == 0 || t.memo == nil {
+		return t.evaluateOperator(stage, parameters)
+	}
+
+	memoized := &t.memo[stage.memo-1]
+	if !memoized.evaluated {
+		memoized.value, memoized.err = t.evaluateOperator(stage, parameters)
+		memoized.evaluated = true
+	}
+	return memoized.value, memoized.err
+}
+
+/*
+Evaluates [stage] with its native closure, if it has one and nothing needs to observe its evaluation.
+Otherwise evaluates its operands, then its operator.
+*/
+func (t tEvaluableExpression) evaluateOperator(stage *evaluationStage, parameters tParameters) (interface{}, error) {
+
+	// native closures skip the tracing, profiling, and preemption of interpretation.
+	if stage.native != nil && t.options.Tracer == nil && t.profiler == nil && t.preemption == nil {
+		return stage.native(&t, parameters)
+	}
+	return t.interpretStage(stage, parameters)
+}
+
+/*
+Evaluates [stage] by evaluating its operands, then its operator.
+*/
+func (t tEvaluableExpression) interpretStage(stage *evaluationStage, parameters tParameters) (result interface{}, err error) {
+
 	var left, right interface{}
-	var err error
+
+	if t.preemption != nil && t.preemption.expired() {
+		return nil, TErrPreempted
+	}
+
+	if t.profiler != nil {
+		defer t.profiler.record(stage, time.Now())
+	}
+
+	if t.options.Tracer != nil {
+		exit := t.traceStage(t.options.Tracer, stage)
+		defer func() {
+			exit(left, right, result, err)
+		}()
+	}
 
 	if stage.leftStage != nil {
 		left, err = t.evaluateStage(stage.leftStage, parameters)
@@ -92,6 +312,11 @@ func (t tEvaluableExpression) evaluateStage(stage *evaluationStage, parameters t
 		}
 	}
 
+	// with TFeatureTruthiness, a ternary's condition is converted, but not its value.
+	if stage.truthy && stage.leftStage != nil {
+		left = isTruthy(left)
+	}
+
 	if stage.isShortCircuitable() {
 		switch stage.symbol {
 		case tAND:
@@ -123,33 +348,95 @@ func (t tEvaluableExpression) evaluateStage(stage *evaluationStage, parameters t
 		if err != nil {
 			return nil, err
 		}
+
+		if stage.truthy && stage.symbol != tTERNARY_TRUE {
+			right = isTruthy(right)
+		}
+	}
+
+	if stage.stringers {
+		left, right = coerceStringers(stage.symbol, left, right)
 	}
 
-	if t.ChecksTypes {
+	return t.applyOperator(stage, left, right, parameters)
+}
+
+/*
+Type-checks the evaluated operands of [stage], then gives them to its operator, or to its function.
+*/
+func (t tEvaluableExpression) applyOperator(stage *evaluationStage, left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+	var err error
+
+	left, right = timesAsSeconds(stage.symbol, left, right)
+
+	if t.checksTypes {
 		if stage.typeCheck == nil {
 
-			err = typeCheck(stage.leftTypeCheck, left, stage.symbol, stage.typeErrorFormat)
+			err = typeCheck(stage.leftTypeCheck, left, t.displayValue(stage.leftStage, left), stage.symbol, stage.typeErrorFormat)
 			if err != nil {
 				return nil, err
 			}
 
-			err = typeCheck(stage.rightTypeCheck, right, stage.symbol, stage.typeErrorFormat)
+			err = typeCheck(stage.rightTypeCheck, right, t.displayValue(stage.rightStage, right), stage.symbol, stage.typeErrorFormat)
 			if err != nil {
 				return nil, err
 			}
 		} else {
 			// special case where the type check needs to know both sides to determine if the operator can handle it
 			if !stage.typeCheck(left, right) {
-				errorMsg := fmt.Sprintf(stage.typeErrorFormat, left, stage.symbol.String())
+				errorMsg := fmt.Sprintf(stage.typeErrorFormat, t.displayValue(stage.leftStage, left), stage.symbol.String())
 				return nil, errors.New(errorMsg)
 			}
 		}
 	}
 
+	if stage.symbol == tFUNCTIONAL {
+
+		ret, err := callFunction(stage, right, parameters)
+		if err != nil {
+			return nil, t.functionError(stage, right, err)
+		}
+		return ret, nil
+	}
+
 	return stage.operator(left, right, parameters)
 }
 
-func typeCheck(check stageTypeCheck, value interface{}, symbol tOperatorSymbol, format string) error {
+/*
+Returns the names of every parameter this expression refers to, in the order they first appear.
+Accessors contribute the name of the parameter they access, such as "user" for `user.Name`.
+*/
+func (t tEvaluableExpression) TVars() []string {
+
+	var ret []string
+	seen := make(map[string]bool)
+
+	for _, token := range t.tokens {
+
+		var name string
+
+		switch token.Kind {
+		case tVARIABLE:
+			name = token.Value.(string)
+		case tACCESSOR:
+			name = token.Value.([]string)[0]
+		default:
+			continue
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+/*
+Checks [value] with [check], returning a type error which shows [displayed] in its place if the check fails.
+*/
+func typeCheck(check stageTypeCheck, value interface{}, displayed interface{}, symbol tOperatorSymbol, format string) error {
 
 	if check == nil {
 		return nil
@@ -159,6 +446,6 @@ func typeCheck(check stageTypeCheck, value interface{}, symbol tOperatorSymbol,
 		return nil
 	}
 
-	errorMsg := fmt.Sprintf(format, value, symbol.String())
+	errorMsg := fmt.Sprintf(format, displayed, symbol.String())
 	return errors.New(errorMsg)
 }
@@ -7,3 +7,8 @@ type tExpressionToken struct {
 	Kind  tTokenKind
 	Value interface{}
 }
+
+/*
+TExpressionToken allows packages outside of core to refer to tokens.
+*/
+type TExpressionToken = tExpressionToken
@@ -21,6 +21,7 @@ const (
 
 	tAND
 	tOR
+	tXOR
 
 	tPLUS
 	tMINUS
@@ -31,6 +32,7 @@ const (
 	tBITWISE_RSHIFT
 	tMULTIPLY
 	tDIVIDE
+	tFLOOR_DIVIDE
 	tMODULUS
 	tEXPONENT
 
@@ -94,6 +96,8 @@ func findOperatorPrecedenceForSymbol(symbol tOperatorSymbol) operatorPrecedence
 	case tAND:
 		return logicalAndPrecedence
 	case tOR:
+		fallthrough
+	case tXOR:
 		return logicalOrPrecedence
 	case tBITWISE_AND:
 		fallthrough
@@ -113,6 +117,8 @@ func findOperatorPrecedenceForSymbol(symbol tOperatorSymbol) operatorPrecedence
 		fallthrough
 	case tDIVIDE:
 		fallthrough
+	case tFLOOR_DIVIDE:
+		fallthrough
 	case tMODULUS:
 		return multiplicativePrecedence
 	case tEXPONENT:
@@ -158,8 +164,16 @@ var comparatorSymbols = map[string]tOperatorSymbol{
 }
 
 var logicalSymbols = map[string]tOperatorSymbol{
-	"&&": tAND,
-	"||": tOR,
+	"&&":  tAND,
+	"||":  tOR,
+	"xor": tXOR,
+}
+
+// the word forms of logical operators, accepted with TExpressionOptions.WordOperators.
+var logicalWords = map[string]string{
+	"and": "&&",
+	"or":  "||",
+	"xor": "xor",
 }
 
 var bitwiseSymbols = map[string]tOperatorSymbol{
@@ -179,9 +193,10 @@ var additiveSymbols = map[string]tOperatorSymbol{
 }
 
 var multiplicativeSymbols = map[string]tOperatorSymbol{
-	"*": tMULTIPLY,
-	"/": tDIVIDE,
-	"%": tMODULUS,
+	"*":  tMULTIPLY,
+	"/":  tDIVIDE,
+	"//": tFLOOR_DIVIDE,
+	"%":  tMODULUS,
 }
 
 var exponentialSymbolsS = map[string]tOperatorSymbol{
@@ -206,6 +221,7 @@ var modifierSymbols = map[string]tOperatorSymbol{
 	"-":  tMINUS,
 	"*":  tMULTIPLY,
 	"/":  tDIVIDE,
+	"//": tFLOOR_DIVIDE,
 	"%":  tMODULUS,
 	"**": tEXPONENT,
 	"&":  tBITWISE_AND,
@@ -233,7 +249,7 @@ func (this tOperatorSymbol) String() string {
 	case tVALUE:
 		return "tVALUE"
 	case tEQ:
-		return "="
+		return "=="
 	case tNEQ:
 		return "!="
 	case tGT:
@@ -252,6 +268,8 @@ func (this tOperatorSymbol) String() string {
 		return "&&"
 	case tOR:
 		return "||"
+	case tXOR:
+		return "xor"
 	case tIN:
 		return "in"
 	case tBITWISE_AND:
@@ -272,6 +290,8 @@ func (this tOperatorSymbol) String() string {
 		return "*"
 	case tDIVIDE:
 		return "/"
+	case tFLOOR_DIVIDE:
+		return "//"
 	case tMODULUS:
 		return "%"
 	case tEXPONENT:
@@ -5,12 +5,18 @@ Represents all valid types of tokens that a token can be.
 */
 type tTokenKind int
 
+/*
+TTokenKind allows packages outside of core to refer to token kinds, such as those expected by a TTokenizer.
+*/
+type TTokenKind = tTokenKind
+
 const (
 	tUNKNOWN tTokenKind = iota
 
 	tPREFIX
 	tNUMERIC
 	tBOOLEAN
+	tNIL
 	tSTRING
 	tPATTERN
 	tTIME
@@ -29,6 +35,30 @@ const (
 	tTERNARY
 )
 
+/*
+The kinds of tokens, as exported for packages outside of core.
+*/
+const (
+	TUNKNOWN      = tUNKNOWN
+	TPREFIX       = tPREFIX
+	TNUMERIC      = tNUMERIC
+	TBOOLEAN      = tBOOLEAN
+	TNIL          = tNIL
+	TSTRING       = tSTRING
+	TPATTERN      = tPATTERN
+	TTIME         = tTIME
+	TVARIABLE     = tVARIABLE
+	TFUNCTION     = tFUNCTION
+	TSEPARATOR    = tSEPARATOR
+	TACCESSOR     = tACCESSOR
+	TCOMPARATOR   = tCOMPARATOR
+	TLOGICALOP    = tLOGICALOP
+	TMODIFIER     = tMODIFIER
+	TCLAUSE       = tCLAUSE
+	TCLAUSE_CLOSE = tCLAUSE_CLOSE
+	TTERNARY      = tTERNARY
+)
+
 /*
 GetTokenKindString returns a string that describes the given tTokenKind.
 e.g., when passed the tNUMERIC tTokenKind, this returns the string "tNUMERIC".
@@ -43,6 +73,8 @@ func (kind tTokenKind) tString() string {
 		return "tNUMERIC"
 	case tBOOLEAN:
 		return "tBOOLEAN"
+	case tNIL:
+		return "tNIL"
 	case tSTRING:
 		return "tSTRING"
 	case tPATTERN:
@@ -73,3 +105,7 @@ func (kind tTokenKind) tString() string {
 
 	return "tUNKNOWN"
 }
+
+func (kind tTokenKind) String() string {
+	return kind.tString()
+}
@@ -0,0 +1,281 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+/*
+`sum(values)` returns the sum of the array [values], or 0 if it is empty.
+Like the other aggregates, it accepts arrays of any type, and skips nil elements, as SQL aggregates do.
+Each also takes an optional selector, a path such as 'latency' or 'response.ms', which picks the value to aggregate
+out of each element - a map, struct, or array - as in `avg(requests, 'latency') > 250`.
+*/
+func sumFunction(arguments ...interface{}) (interface{}, error) {
+
+	numbers, err := aggregateNumbers("sum", arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := 0.0
+	for _, number := range numbers {
+		ret += number
+	}
+	return ret, nil
+}
+
+/*
+`avg(values)` returns the mean of the array [values], or nil if it is empty.
+*/
+func avgFunction(arguments ...interface{}) (interface{}, error) {
+
+	numbers, err := aggregateNumbers("avg", arguments)
+	if err != nil || len(numbers) == 0 {
+		return nil, err
+	}
+
+	ret := 0.0
+	for _, number := range numbers {
+		ret += number
+	}
+	return ret / float64(len(numbers)), nil
+}
+
+/*
+`min(values)` returns the least of the array [values], which must be all numbers or all strings, or nil if it is empty.
+Given values which are not an array, as in `min(a, b, 10)`, it returns the least of those values instead.
+*/
+func minFunction(arguments ...interface{}) (interface{}, error) {
+	return extremeOf("min", arguments, -1)
+}
+
+/*
+`max(values)` returns the greatest of the array [values], which must be all numbers or all strings, or nil if it is empty.
+Given values which are not an array, as in `max(a, b, 10)`, it returns the greatest of those values instead.
+*/
+func maxFunction(arguments ...interface{}) (interface{}, error) {
+	return extremeOf("max", arguments, 1)
+}
+
+/*
+`count(values)` returns the number of elements of the array [values] which are not nil.
+*/
+func countFunction(arguments ...interface{}) (interface{}, error) {
+
+	values, err := aggregateValues("count", arguments)
+	if err != nil {
+		return nil, err
+	}
+	return float64(len(values)), nil
+}
+
+/*
+Returns the least (if [direction] is negative) or greatest of the values selected by [arguments].
+*/
+func extremeOf(function string, arguments []interface{}, direction int) (interface{}, error) {
+
+	values, err := extremeValues(function, arguments)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+
+	ret := values[0]
+	for _, value := range values[1:] {
+
+		comparison, err := compareOrdered(value, ret)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects numbers or strings: %v", function, err)
+		}
+		if comparison*direction > 0 {
+			ret = value
+		}
+	}
+
+	// a single value is still checked, so that `max(names)` fails the same way however many names there are.
+	if _, err = compareOrdered(ret, ret); err != nil {
+		return nil, fmt.Errorf("%s expects numbers or strings: %v", function, err)
+	}
+	return ret, nil
+}
+
+/*
+Returns the values [arguments] select: those of the array they aggregate, if the first is an array - or nil, alone or
+with a selector - or else the arguments themselves, without any which are nil, so that `max(a, b)` compares a and b.
+*/
+func extremeValues(function string, arguments []interface{}) ([]interface{}, error) {
+
+	if len(arguments) == 0 || isList(reflect.ValueOf(arguments[0])) {
+		return aggregateValues(function, arguments)
+	}
+	if arguments[0] == nil && (len(arguments) == 1 || (len(arguments) == 2 && isString(arguments[1]))) {
+		return aggregateValues(function, arguments)
+	}
+
+	ret := make([]interface{}, 0, len(arguments))
+	for _, argument := range arguments {
+
+		value, err := unwrapValue(argument)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			ret = append(ret, castToFloat64(value))
+		}
+	}
+	return ret, nil
+}
+
+func compareOrdered(left interface{}, right interface{}) (int, error) {
+
+	switch typed := left.(type) {
+	case float64:
+		other, ok := right.(float64)
+		if ok {
+			switch {
+			case typed < other:
+				return -1, nil
+			case typed > other:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		other, ok := right.(string)
+		if ok {
+			switch {
+			case typed < other:
+				return -1, nil
+			case typed > other:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare '%v' with '%v'", left, right)
+}
+
+/*
+Returns the numbers selected by [arguments], failing if any is not a number.
+*/
+func aggregateNumbers(function string, arguments []interface{}) ([]float64, error) {
+
+	values, err := aggregateValues(function, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]float64, len(values))
+	for i, value := range values {
+
+		number, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s expects numbers, got '%v'", function, value)
+		}
+		ret[i] = number
+	}
+	return ret, nil
+}
+
+/*
+Returns the non-nil values of the array given by [arguments], as picked out of each element by the optional selector,
+with numbers converted to float64s.
+*/
+func aggregateValues(function string, arguments []interface{}) ([]interface{}, error) {
+
+	if len(arguments) < 1 || len(arguments) > 2 {
+		return nil, fmt.Errorf("%s expects an array, and optionally a selector", function)
+	}
+
+	var selector []string
+	if len(arguments) == 2 {
+
+		path, ok := arguments[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s expects its selector to be a path, such as 'latency'", function)
+		}
+
+		var err error
+		selector, err = parseJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if arguments[0] == nil {
+		return nil, nil
+	}
+
+	list := reflect.ValueOf(arguments[0])
+	if !isList(list) {
+		return nil, fmt.Errorf("%s expects an array, got '%v'", function, arguments[0])
+	}
+
+	ret := make([]interface{}, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+
+		value, found := selectPath(list.Index(i).Interface(), selector)
+		if !found || value == nil {
+			continue
+		}
+
+		value, err := unwrapValue(value)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			ret = append(ret, castToFloat64(value))
+		}
+	}
+	return ret, nil
+}
+
+/*
+Follows [path] through [value], by map keys, exported struct fields, and array indices.
+Returns false if there is nothing at that path.
+*/
+func selectPath(value interface{}, path []string) (interface{}, bool) {
+
+	for _, segment := range path {
+
+		reflected := reflect.ValueOf(value)
+		for reflected.Kind() == reflect.Ptr || reflected.Kind() == reflect.Interface {
+			if reflected.IsNil() {
+				return nil, false
+			}
+			reflected = reflected.Elem()
+		}
+
+		switch reflected.Kind() {
+		case reflect.Map:
+			if reflected.Type().Key().Kind() != reflect.String {
+				return nil, false
+			}
+
+			member := reflected.MapIndex(reflect.ValueOf(segment).Convert(reflected.Type().Key()))
+			if !member.IsValid() {
+				return nil, false
+			}
+			value = member.Interface()
+
+		case reflect.Struct:
+			field, found := reflected.Type().FieldByName(segment)
+			if !found || !field.IsExported() {
+				return nil, false
+			}
+			value = reflected.FieldByIndex(field.Index).Interface()
+
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= reflected.Len() {
+				return nil, false
+			}
+			value = reflected.Index(index).Interface()
+
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
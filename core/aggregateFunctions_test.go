@@ -0,0 +1,97 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAggregateFunctions(test *testing.T) {
+
+	parameters := map[string]interface{}{
+		"latencies": []interface{}{100, 300, nil, 200},
+		"names":     []string{"b", "a", "c"},
+		"requests": []map[string]interface{}{
+			{"latency": 100},
+			{"latency": 400},
+			{"other": 1},
+		},
+		"empty":   []interface{}{},
+		"a":       4,
+		"b":       7,
+		"missing": nil,
+	}
+
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"sum(latencies)", 600.0},
+		{"avg(latencies)", 200.0},
+		{"avg(latencies) > 150", true},
+		{"count(latencies)", 3.0},
+		{"min(latencies)", 100.0},
+		{"max(latencies)", 300.0},
+		{"min(names)", "a"},
+		{"max(names)", "c"},
+		{"avg(requests, 'latency')", 250.0},
+		{"max(requests, 'latency')", 400.0},
+		{"avg(empty)", nil},
+		{"max(empty)", nil},
+
+		// given values which are not an array, min and max compare those values.
+		{"max((1|2), 2)", 3.0},
+		{"max(1, 5, 2)", 5.0},
+		{"min(a, b, 10)", 4.0},
+		{"max(a, b, 10)", 10.0},
+		{"max(a)", 4.0},
+		{"max(a, b)", 7.0},
+		{"min(a, b)", 4.0},
+		{"max(missing, b)", 7.0},
+		{"max(missing)", nil},
+		{"max(missing, 'latency')", nil},
+		{"min('b', 'a')", "a"},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpression(c.expression)
+		if err != nil {
+			test.Errorf("%s: failed to compile: %v", c.expression, err)
+			continue
+		}
+
+		result, err := expression.TEvaluate(parameters)
+		if err != nil {
+			test.Errorf("%s: failed to evaluate: %v", c.expression, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, c.expected) {
+			test.Errorf("%s: expected %v, got %v", c.expression, c.expected, result)
+		}
+	}
+}
+
+func TestAggregateFunctionErrors(test *testing.T) {
+
+	for _, text := range []string{
+		"sum(1)",
+		"avg(names)",
+		"max(1, 'a')",
+		"max(latencies, 5)",
+	} {
+
+		expression, err := TNewEvaluableExpression(text)
+		if err != nil {
+			test.Errorf("%s: failed to compile: %v", text, err)
+			continue
+		}
+
+		_, err = expression.TEvaluate(map[string]interface{}{
+			"latencies": []interface{}{1, 2},
+			"names":     []string{"a"},
+		})
+		if err == nil {
+			test.Errorf("%s: expected an error", text)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+TAllowList names the only parameters and functions an expression may use, as given by TExpressionOptions.Allow,
+so that expressions written by untrusted users cannot probe parameters which were not meant for them.
+An expression which uses any other fails to compile, with an error which wraps TErrDenied.
+*/
+type TAllowList struct {
+
+	/*
+		The parameters which may be used. Accessors, such as `user.Name`, are allowed if the parameter they access
+		is allowed, or if the path up to any of their fields is - so that allowing "user.Name" allows `user.Name.First`,
+		but not `user.Password`. Names are those of the parameters, after any TExpressionOptions.Aliases are resolved.
+	*/
+	Parameters []string
+
+	/*
+		The functions which may be called, by name, whether they are built in or given to the expression.
+	*/
+	Functions []string
+}
+
+/*
+Returns an error if any of [tokens] uses a parameter or function not allowed by [allow].
+*/
+func checkAllowed(tokens []tExpressionToken, allow *TAllowList) error {
+
+	if allow == nil {
+		return nil
+	}
+
+	for _, token := range tokens {
+
+		switch token.Kind {
+		case tVARIABLE:
+			name := token.Value.(string)
+			if !containsString(allow.Parameters, name) {
+				return fmt.Errorf("%w: the parameter '%s' is not allowed", TErrDenied, name)
+			}
+
+		case tACCESSOR:
+			path := token.Value.([]string)
+			if !isAllowedPath(path, allow.Parameters) {
+				return fmt.Errorf("%w: the parameter '%s' is not allowed", TErrDenied, strings.Join(path, "."))
+			}
+
+		case tFUNCTION:
+			name := token.Value.(tNamedFunction).name
+			if !containsString(allow.Functions, name) {
+				return fmt.Errorf("%w: the function '%s' is not allowed", TErrDenied, name)
+			}
+		}
+	}
+	return nil
+}
+
+func isAllowedPath(path []string, parameters []string) bool {
+
+	for length := 1; length <= len(path); length++ {
+		if containsString(parameters, strings.Join(path[:length], ".")) {
+			return true
+		}
+	}
+	return false
+}
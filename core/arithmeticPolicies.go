@@ -0,0 +1,155 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+/*
+TDivisionByZero is what `/`, `//`, and `%` give when dividing by zero, as set by TExpressionOptions.DivisionByZero.
+*/
+type TDivisionByZero int
+
+const (
+	// +Inf, -Inf, or NaN, as float64 division gives. This is the default.
+	TDivisionByZeroFloat TDivisionByZero = iota
+
+	// fails evaluation with TErrDivisionByZero.
+	TDivisionByZeroError
+
+	// gives nil, so that the expression can handle it with `??`.
+	TDivisionByZeroNil
+)
+
+/*
+TOverflow is what integer operations give when their result does not fit in 64 bits, as set by TExpressionOptions.Overflow.
+The only operations on integers, rather than float64s, are the bitwise ones, of which only `<<` can overflow.
+*/
+type TOverflow int
+
+const (
+	// wraps around, as in two's complement. This is the default.
+	TOverflowWrap TOverflow = iota
+
+	// fails evaluation with TErrOverflow.
+	TOverflowError
+)
+
+/*
+TNaNComparison is what comparisons give when either operand is NaN, as set by TExpressionOptions.NaNComparisons.
+*/
+type TNaNComparison int
+
+const (
+	// as float64 comparisons give: false, except for `!=`, which is true. This is the default.
+	TNaNCompareFloat TNaNComparison = iota
+
+	// fails evaluation with TErrNaN.
+	TNaNCompareError
+
+	// false, even for `!=`.
+	TNaNCompareFalse
+)
+
+/*
+TErrDivisionByZero is returned by evaluations which divided by zero, if TDivisionByZeroError is set.
+*/
+var TErrDivisionByZero = errors.New("Division by zero")
+
+/*
+TErrOverflow is returned by evaluations whose integer operations overflowed, if TOverflowError is set.
+*/
+var TErrOverflow = errors.New("Integer overflow")
+
+/*
+TErrNaN is returned by evaluations which compared NaN, if TNaNCompareError is set.
+*/
+var TErrNaN = errors.New("Comparison with NaN")
+
+/*
+Wraps the given comparison [operator] to follow the given [policy] whenever either operand is NaN.
+*/
+func guardNaN(operator evaluationOperator, policy TNaNComparison) evaluationOperator {
+
+	if policy == TNaNCompareFloat {
+		return operator
+	}
+
+	return func(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+		if isNaN(left) || isNaN(right) {
+			if policy == TNaNCompareError {
+				return nil, TErrNaN
+			}
+			return false, nil
+		}
+		return operator(left, right, parameters)
+	}
+}
+
+func isNaN(value interface{}) bool {
+
+	number, ok := value.(float64)
+	return ok && math.IsNaN(number)
+}
+
+/*
+Wraps the given division [operator] to follow the given [policy] whenever its right operand is zero.
+*/
+func guardDivision(operator evaluationOperator, policy TDivisionByZero) evaluationOperator {
+
+	if policy == TDivisionByZeroFloat {
+		return operator
+	}
+
+	return func(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+		if right == 0.0 {
+			if policy == TDivisionByZeroError {
+				return nil, TErrDivisionByZero
+			}
+			return nil, nil
+		}
+		return operator(left, right, parameters)
+	}
+}
+
+/*
+Like divideStage, but the quotient of two whole numbers is truncated toward zero, as set by TExpressionOptions.IntegerMath.
+*/
+func integerDivideStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+	dividend, divisor := left.(float64), right.(float64)
+
+	ret := dividend / divisor
+	if dividend == math.Trunc(dividend) && divisor == math.Trunc(divisor) {
+		ret = math.Trunc(ret)
+	}
+	return ret, nil
+}
+
+/*
+Like leftShiftStage, but fails with TErrOverflow if any bits are shifted out, or the sign of a signed value changes.
+*/
+func checkedLeftShiftStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+	value, count, err := toShiftOperands(left, right)
+	if err != nil {
+		return nil, err
+	}
+
+	shifted := value
+	shifted.bits <<= count
+
+	var overflowed bool
+	if value.unsigned {
+		overflowed = shifted.bits>>count != value.bits
+	} else {
+		overflowed = int64(shifted.bits)>>count != int64(value.bits)
+	}
+	if overflowed {
+		return nil, fmt.Errorf("%w: %v << %d", TErrOverflow, value.value(), count)
+	}
+	return shifted.value(), nil
+}
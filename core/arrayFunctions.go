@@ -0,0 +1,137 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+/*
+`sort(values)` returns a copy of the array [values] in ascending order; the array itself is left as it is.
+Its elements must be all numbers or all strings, except for nils, which come first.
+`sort(values, key)` orders the elements by what the path [key], such as 'priority', picks out of each, as aggregates do.
+Elements which compare equal keep their order.
+*/
+func sortFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) < 1 || len(arguments) > 2 {
+		return nil, errors.New("sort expects an array, and optionally a key")
+	}
+
+	values, err := arrayArgument("sort", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	keys := values
+	if len(arguments) == 2 {
+
+		path, ok := arguments[1].(string)
+		if !ok {
+			return nil, errors.New("sort expects its key to be a path, such as 'priority'")
+		}
+
+		selector, err := parseJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = make([]interface{}, len(values))
+		for i, value := range values {
+			key, _ := selectPath(value, selector)
+			keys[i] = castToFloat64(key)
+		}
+	}
+
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+
+	var compareErr error
+	sort.SliceStable(order, func(i, j int) bool {
+
+		left, right := keys[order[i]], keys[order[j]]
+		if left == nil || right == nil {
+			return left == nil && right != nil
+		}
+
+		comparison, err := compareOrdered(left, right)
+		if err != nil && compareErr == nil {
+			compareErr = err
+		}
+		return comparison < 0
+	})
+
+	if compareErr != nil {
+		return nil, fmt.Errorf("sort expects numbers or strings: %v", compareErr)
+	}
+
+	ret := make([]interface{}, len(values))
+	for i, index := range order {
+		ret[i] = values[index]
+	}
+	return ret, nil
+}
+
+/*
+`distinct(values)` returns the elements of the array [values] without duplicates, in the order they first appear.
+*/
+func distinctFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 1 {
+		return nil, errors.New("distinct expects an array")
+	}
+
+	values, err := arrayArgument("distinct", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var seen valueSet
+	ret := make([]interface{}, 0, len(values))
+
+	for _, value := range values {
+		if seen.add(value) {
+			ret = append(ret, value)
+		}
+	}
+	return ret, nil
+}
+
+/*
+`reverse(values)` returns a copy of the array [values] in reverse order.
+*/
+func reverseFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 1 {
+		return nil, errors.New("reverse expects an array")
+	}
+
+	values, err := arrayArgument("reverse", arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+		values[i], values[j] = values[j], values[i]
+	}
+	return values, nil
+}
+
+/*
+Returns a copy of the elements of the slice or array [value], with numbers converted to float64s.
+Nil is taken to be an empty array.
+*/
+func arrayArgument(function string, value interface{}) ([]interface{}, error) {
+
+	if value == nil {
+		return []interface{}{}, nil
+	}
+
+	if !isList(reflect.ValueOf(value)) {
+		return nil, fmt.Errorf("%s expects an array, got '%v'", function, value)
+	}
+	return toSetArray(value), nil
+}
@@ -0,0 +1,292 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+const (
+	bitwiseErrorFormat string = "Value '%v' cannot be used with the bitwise operator '%v', it is not an integer"
+
+	// the largest magnitude below which every integer can be represented exactly as a float64.
+	maxExactFloat = 1 << 53
+)
+
+/*
+bitwiseOperand is an integer given to a bitwise operator, as its 64 bits, and whether they are signed.
+
+Operands convert as follows:
+float64s must be whole, and within the range of an int64 (or, if not negative, of a uint64);
+signed integers of any size are int64s, and unsigned ones are uint64s.
+If either operand of a binary operator is unsigned, so is the result - otherwise it is signed.
+Results which can be represented exactly as a float64 are returned as one, as is every other number;
+larger results are returned as an int64 or uint64, so that no bits are lost.
+*/
+type bitwiseOperand struct {
+	bits     uint64
+	unsigned bool
+}
+
+func toBitwiseOperand(value interface{}) (bitwiseOperand, bool) {
+
+	if number, isFloat := value.(float64); isFloat {
+		return floatToBitwiseOperand(number)
+	}
+
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return bitwiseOperand{bits: uint64(reflected.Int())}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return bitwiseOperand{bits: reflected.Uint(), unsigned: true}, true
+	case reflect.Float32, reflect.Float64:
+		return floatToBitwiseOperand(reflected.Float())
+	}
+	return bitwiseOperand{}, false
+}
+
+func floatToBitwiseOperand(number float64) (bitwiseOperand, bool) {
+
+	if number != math.Trunc(number) || math.IsInf(number, 0) {
+		return bitwiseOperand{}, false
+	}
+	if number >= math.MinInt64 && number < math.MaxInt64 {
+		return bitwiseOperand{bits: uint64(int64(number))}, true
+	}
+	if number >= 0 && number < math.MaxUint64 {
+		return bitwiseOperand{bits: uint64(number), unsigned: true}, true
+	}
+	return bitwiseOperand{}, false
+}
+
+func isBitwiseOperand(value interface{}) bool {
+	_, ok := toBitwiseOperand(value)
+	return ok
+}
+
+/*
+Returns this operand as the value an expression gives for it.
+*/
+func (o bitwiseOperand) value() interface{} {
+
+	if o.unsigned {
+		if o.bits <= maxExactFloat {
+			return float64(o.bits)
+		}
+		return o.bits
+	}
+
+	signed := int64(o.bits)
+	if signed >= -maxExactFloat && signed <= maxExactFloat {
+		return float64(signed)
+	}
+	return signed
+}
+
+func makeBitwiseStage(operation func(uint64, uint64) uint64) evaluationOperator {
+
+	return func(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+		leftOperand, rightOperand, err := toBitwiseOperands(left, right)
+		if err != nil {
+			return nil, err
+		}
+
+		return bitwiseOperand{
+			bits:     operation(leftOperand.bits, rightOperand.bits),
+			unsigned: leftOperand.unsigned || rightOperand.unsigned,
+		}.value(), nil
+	}
+}
+
+var bitwiseOrStage = makeBitwiseStage(func(left uint64, right uint64) uint64 { return left | right })
+var bitwiseAndStage = makeBitwiseStage(func(left uint64, right uint64) uint64 { return left & right })
+var bitwiseXORStage = makeBitwiseStage(func(left uint64, right uint64) uint64 { return left ^ right })
+
+/*
+Shifts the left operand by the right, which must not be negative.
+Shifting by 64 or more is well-defined, as in Go: left shifts give 0,
+as do right shifts of unsigned or non-negative values; right shifts of negative values give -1.
+Signed left shifts wrap around, as in two's complement.
+*/
+func leftShiftStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+	value, count, err := toShiftOperands(left, right)
+	if err != nil {
+		return nil, err
+	}
+
+	value.bits <<= count
+	return value.value(), nil
+}
+
+func rightShiftStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+	value, count, err := toShiftOperands(left, right)
+	if err != nil {
+		return nil, err
+	}
+
+	if value.unsigned {
+		value.bits >>= count
+	} else {
+		value.bits = uint64(int64(value.bits) >> count)
+	}
+	return value.value(), nil
+}
+
+func bitwiseNotStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+	operand, ok := toBitwiseOperand(right)
+	if !ok {
+		return nil, errors.New("Bitwise not requires an integer operand")
+	}
+
+	operand.bits = ^operand.bits
+	return operand.value(), nil
+}
+
+func toBitwiseOperands(left interface{}, right interface{}) (bitwiseOperand, bitwiseOperand, error) {
+
+	leftOperand, leftOk := toBitwiseOperand(left)
+	rightOperand, rightOk := toBitwiseOperand(right)
+
+	if !leftOk || !rightOk {
+		return bitwiseOperand{}, bitwiseOperand{}, errors.New("Bitwise operators require integer operands")
+	}
+	return leftOperand, rightOperand, nil
+}
+
+func toShiftOperands(left interface{}, right interface{}) (bitwiseOperand, uint64, error) {
+
+	value, count, err := toBitwiseOperands(left, right)
+	if err != nil {
+		return value, 0, err
+	}
+
+	if !count.unsigned && int64(count.bits) < 0 {
+		return value, 0, errors.New("Cannot shift by a negative amount")
+	}
+	return value, count.bits, nil
+}
+
+/*
+Recurses through all stages, making the parameters and accessors given to bitwise operators retrieve their values exactly,
+rather than as float64s - which cannot represent every integer above 2^53.
+*/
+func prepareExactOperands(stage *evaluationStage, options TExpressionOptions) {
+
+	if stage == nil {
+		return
+	}
+
+	if isBitwiseStage(stage) {
+		makeExact(stage.leftStage, options)
+		makeExact(stage.rightStage, options)
+	}
+
+	prepareExactOperands(stage.leftStage, options)
+	prepareExactOperands(stage.rightStage, options)
+}
+
+/*
+Recurses through all stages, making literals too large to be represented exactly as float64s, such as `0xFFFFFFFFFFFFFFFF`,
+float64s wherever they are not given to a bitwise operator - as parameters are float64s there too, and must compare equal.
+Literals given to a bitwise operator, whether [exact] is set for [stage], keep every bit.
+*/
+func widenInexactLiterals(stage *evaluationStage, exact bool) {
+
+	if stage == nil {
+		return
+	}
+
+	if stage.symbol == tLITERAL && !exact {
+
+		value, err := stage.operator(nil, nil, nil)
+		if unsigned, isUnsigned := value.(uint64); err == nil && isUnsigned {
+			stage.operator = makeLiteralStage(float64(unsigned))
+		}
+	}
+
+	switch stage.symbol {
+	case tBITWISE_AND, tBITWISE_OR, tBITWISE_XOR, tBITWISE_LSHIFT, tBITWISE_RSHIFT, tBITWISE_NOT:
+		exact = true
+	case tEQ, tNEQ:
+		// compared with what a bitwise operator gives, which is exact too.
+		exact = isBitwiseStage(unparenthesized(stage.leftStage)) || isBitwiseStage(unparenthesized(stage.rightStage))
+	case tNOOP:
+		// parentheses give what they hold.
+	default:
+		exact = false
+	}
+
+	widenInexactLiterals(stage.leftStage, exact)
+	widenInexactLiterals(stage.rightStage, exact)
+}
+
+func isBitwiseStage(stage *evaluationStage) bool {
+
+	if stage == nil {
+		return false
+	}
+
+	switch stage.symbol {
+	case tBITWISE_AND, tBITWISE_OR, tBITWISE_XOR, tBITWISE_LSHIFT, tBITWISE_RSHIFT, tBITWISE_NOT:
+		return true
+	}
+	return false
+}
+
+func makeExact(stage *evaluationStage, options TExpressionOptions) {
+
+	// look through parentheses
+	for stage != nil && stage.symbol == tNOOP {
+		stage = stage.rightStage
+	}
+	if stage == nil {
+		return
+	}
+
+	switch stage.symbol {
+	case tVALUE:
+		stage.operator = makeExactParameterStage(stage.name)
+	case tACCESS:
+		stage.operator = makeExactAccessorStage(stage.path, options)
+	}
+}
+
+/*
+Like makeParameterStage, but the parameter is retrieved as-is, rather than numbers being converted to float64.
+*/
+func makeExactParameterStage(parameterName string) evaluationOperator {
+
+	return func(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+		value, err := unsanitized(parameters).tGet(parameterName)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err = unwrapValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read parameter '%s': %w", parameterName, err)
+		}
+		return value, nil
+	}
+}
+
+/*
+Returns [parameters] without the float64 conversion of sanitizedParameters, keeping any other wrappers.
+*/
+func unsanitized(parameters tParameters) tParameters {
+
+	switch typed := parameters.(type) {
+	case *sanitizedParameters:
+		return typed.orig
+	case lenientParameters:
+		return lenientParameters{unsanitized(typed.orig)}
+	}
+	return parameters
+}
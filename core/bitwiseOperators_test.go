@@ -0,0 +1,41 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLargeHexLiterals(test *testing.T) {
+
+	parameters := map[string]interface{}{"x": uint64(math.MaxUint64), "y": uint64(0xFFFFFFFFFFFFFFF0)}
+
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		// outside of bitwise operators, hex literals are float64s, as the parameters they are compared to are.
+		{"x == 0xFFFFFFFFFFFFFFFF", true},
+		{"(0xFFFFFFFFFFFFFFFF) == x", true},
+		{"0xFFFFFFFFFFFFFFFF > 1", true},
+		{"x in (1, 0xFFFFFFFFFFFFFFFF)", true},
+		{"typeof(0xFFFFFFFFFFFFFFFF)", "number"},
+
+		// given to bitwise operators, they keep every bit.
+		{"y & 0xFFFFFFFFFFFFFFFF", uint64(0xFFFFFFFFFFFFFFF0)},
+		{"(y | 0xF) == 0xFFFFFFFFFFFFFFFF", true},
+		{"y ^ (0xFFFFFFFFFFFFFFFF)", 15.0},
+		{"(y | 0xE) != 0xFFFFFFFFFFFFFFFF", true},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpression(c.expression)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		result, err := expression.TEvaluate(parameters)
+		if err != nil || result != c.expected {
+			test.Errorf("%s: expected %v (%T), got %v (%T) (%v)", c.expression, c.expected, c.expected, result, result, err)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+Functions every expression can call, unless a registered or expression-specific function of the same name overrides them.
+All built-in functions are pure, so calls to them with literal arguments are folded when planned -
+except for those which return arrays or maps, such as union(), which are called on every evaluation
+so that each gets its own.
+*/
+var builtinFunctions = map[string]tExpressionFunction{
+	"matches":  matchesFunction,
+	"isNaN":    isNaNFunction,
+	"isInf":    isInfFunction,
+	"nan":      nanFunction,
+	"inf":      infFunction,
+	"jsonGet":  jsonGetFunction,
+	"toNumber": toNumberFunction,
+	"toString": toStringFunction,
+	"toBool":   toBoolFunction,
+	"toTime":   toTimeFunction,
+	"typeof":   typeofFunction,
+	"isNumber": makeTypeTest("isNumber", "number"),
+	"isString": makeTypeTest("isString", "string"),
+	"isBool":   makeTypeTest("isBool", "bool"),
+	"isTime":   makeTypeTest("isTime", "time"),
+	"isArray":  makeTypeTest("isArray", "array"),
+	"isMap":    makeTypeTest("isMap", "map"),
+
+	"union":      unionFunction,
+	"intersect":  intersectFunction,
+	"difference": differenceFunction,
+	"subset":     subsetFunction,
+
+	"sum":   sumFunction,
+	"avg":   avgFunction,
+	"min":   minFunction,
+	"max":   maxFunction,
+	"count": countFunction,
+	"len":   lenFunction,
+
+	"sort":     sortFunction,
+	"distinct": distinctFunction,
+	"reverse":  reverseFunction,
+}
+
+/*
+`matches(s, pattern)` matches [s] against [pattern], a string or pattern literal, returning nil if it does not match.
+Otherwise returns the capture groups as an array - or, if the pattern names its groups, as a map from name to group.
+A pattern without groups returns an array holding the whole match. Groups which did not participate are nil.
+*/
+func matchesFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 2 || !isString(arguments[0]) || !isRegexOrString(arguments[1]) {
+		return nil, errors.New("matches expects a string and a pattern")
+	}
+
+	var pattern *regexp.Regexp
+	var err error
+
+	switch typed := arguments[1].(type) {
+	case string:
+		pattern, err = regexp.Compile(typed)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to compile regexp pattern '%v': %v", typed, err)
+		}
+	case *regexp.Regexp:
+		pattern = typed
+	}
+
+	subject := arguments[0].(string)
+
+	indices := pattern.FindStringSubmatchIndex(subject)
+	if indices == nil {
+		return nil, nil
+	}
+
+	groups := make([]interface{}, len(indices)/2)
+	for i := range groups {
+		if indices[2*i] >= 0 {
+			groups[i] = subject[indices[2*i]:indices[2*i+1]]
+		}
+	}
+
+	if len(groups) == 1 {
+		return groups, nil
+	}
+
+	named := make(map[string]interface{})
+	for i, name := range pattern.SubexpNames() {
+		if name != "" {
+			named[name] = groups[i]
+		}
+	}
+
+	if len(named) > 0 {
+		return named, nil
+	}
+	return groups[1:], nil
+}
+
+/*
+`isNaN(x)` returns whether the number [x] is NaN, as given by `0 / 0` and the like.
+*/
+func isNaNFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 1 || !isFloat64(arguments[0]) {
+		return nil, errors.New("isNaN expects a number")
+	}
+	return math.IsNaN(arguments[0].(float64)), nil
+}
+
+/*
+`isInf(x)` returns whether the number [x] is infinite. `isInf(x, sign)` returns whether it is +Inf, if [sign] is positive,
+or -Inf, if [sign] is negative.
+*/
+func isInfFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) < 1 || len(arguments) > 2 || !isFloat64(arguments[0]) {
+		return nil, errors.New("isInf expects a number, and optionally a sign")
+	}
+
+	sign, err := signArgument("isInf", arguments[1:])
+	if err != nil {
+		return nil, err
+	}
+	return math.IsInf(arguments[0].(float64), sign), nil
+}
+
+/*
+`nan()` returns NaN.
+*/
+func nanFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 0 {
+		return nil, errors.New("nan expects no arguments")
+	}
+	return math.NaN(), nil
+}
+
+/*
+`inf()` returns +Inf, and `inf(sign)` returns -Inf if [sign] is negative.
+*/
+func infFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) > 1 {
+		return nil, errors.New("inf expects at most a sign")
+	}
+
+	sign, err := signArgument("inf", arguments)
+	if err != nil {
+		return nil, err
+	}
+	if sign < 0 {
+		return math.Inf(-1), nil
+	}
+	return math.Inf(1), nil
+}
+
+/*
+Returns the sign of the optional number in [arguments], or 0 if there is none.
+*/
+func signArgument(function string, arguments []interface{}) (int, error) {
+
+	if len(arguments) == 0 {
+		return 0, nil
+	}
+
+	number, ok := arguments[0].(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s expects its sign to be a number", function)
+	}
+
+	switch {
+	case number > 0:
+		return 1, nil
+	case number < 0:
+		return -1, nil
+	}
+	return 0, nil
+}
+
+/*
+`jsonGet(json, path)` parses the string [json] and returns the value found at [path], such as `order.items[0].sku`,
+or nil if there is none. Paths may begin with `$`, as in JSONPath, and members may be quoted, as in `$['order.id']`.
+Numbers are returned as float64s, objects as maps, and arrays as arrays.
+*/
+func jsonGetFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 2 || !isString(arguments[0]) || !isString(arguments[1]) {
+		return nil, errors.New("jsonGet expects a JSON string and a path")
+	}
+
+	path, err := parseJSONPath(strings.TrimPrefix(arguments[1].(string), "$"))
+	if err != nil {
+		return nil, err
+	}
+
+	value, _, err := readJSONPath([]byte(arguments[0].(string)), path)
+	if err != nil {
+		return nil, fmt.Errorf("jsonGet failed to read '%s': %v", arguments[1], err)
+	}
+	return value, nil
+}
+
+/*
+`toNumber(x)` converts [x] to a number: numbers are returned as they are, strings are parsed (ignoring surrounding space),
+booleans become 1 or 0, and times become seconds since the Unix epoch.
+Like the other conversions, it fails evaluation if [x] cannot be converted, unless given a fallback to return instead,
+as in `toNumber(x, 0)`. Nil can never be converted.
+*/
+func toNumberFunction(arguments ...interface{}) (interface{}, error) {
+
+	return convert("toNumber", "a number", "a value", arguments, func(value interface{}) (interface{}, bool) {
+
+		switch typed := castToFloat64(value).(type) {
+		case float64:
+			return typed, true
+		case string:
+			number, err := strconv.ParseFloat(strings.TrimSpace(typed), 64)
+			return number, err == nil
+		case bool:
+			if typed {
+				return 1.0, true
+			}
+			return 0.0, true
+		case time.Time:
+			return unixSeconds(typed), true
+		}
+		return nil, false
+	})
+}
+
+/*
+`toString(x)` converts [x] to a string, as concatenation would print it - or as its registered stringer does, if it has one.
+*/
+func toStringFunction(arguments ...interface{}) (interface{}, error) {
+
+	return convert("toString", "a string", "a value", arguments, func(value interface{}) (interface{}, bool) {
+
+		if value == nil {
+			return nil, false
+		}
+		if ret, found := stringifyValue(value); found {
+			return ret, true
+		}
+		return fmt.Sprintf("%v", value), true
+	})
+}
+
+/*
+`toBool(x)` converts [x] to a boolean: numbers are true unless zero,
+and strings are parsed as "true", "false", "1", "0", or the like, ignoring surrounding space.
+*/
+func toBoolFunction(arguments ...interface{}) (interface{}, error) {
+
+	return convert("toBool", "a boolean", "a value", arguments, func(value interface{}) (interface{}, bool) {
+
+		switch typed := castToFloat64(value).(type) {
+		case bool:
+			return typed, true
+		case float64:
+			return typed != 0, true
+		case string:
+			ret, err := strconv.ParseBool(strings.TrimSpace(typed))
+			return ret, err == nil
+		}
+		return nil, false
+	})
+}
+
+/*
+`toTime(x, format)` converts [x] to a time, as seconds since the Unix epoch - as time literals and `now()` give.
+Strings are parsed with [format], a Go time layout such as '02/01/2006', in the local time zone unless they give one;
+an empty [format] accepts the same formats that time literals do. Numbers are taken to be times already.
+*/
+func toTimeFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) < 2 || !isString(arguments[1]) {
+		return nil, errors.New("toTime expects a value, a format, and optionally a fallback")
+	}
+	format := arguments[1].(string)
+
+	// the format sits between the value and the fallback, so is set aside for convert.
+	arguments = append([]interface{}{arguments[0]}, arguments[2:]...)
+
+	return convert("toTime", "a time", "a value, a format", arguments, func(value interface{}) (interface{}, bool) {
+
+		switch typed := castToFloat64(value).(type) {
+		case float64:
+			return typed, true
+		case time.Time:
+			return unixSeconds(typed), true
+		case string:
+			var parsed time.Time
+			var found bool
+
+			if format == "" {
+				parsed, found = tryParseTime(typed)
+			} else {
+				parsed, found = tryParseExactTime(typed, format)
+			}
+			if found {
+				return unixSeconds(parsed), true
+			}
+		}
+		return nil, false
+	})
+}
+
+/*
+Applies [conversion] to the value in [arguments], returning the fallback which may follow it if the conversion fails,
+or an error if there is none. [usage] describes the arguments the function takes before the fallback.
+*/
+func convert(function string, kind string, usage string, arguments []interface{}, conversion func(interface{}) (interface{}, bool)) (interface{}, error) {
+
+	if len(arguments) < 1 || len(arguments) > 2 {
+		return nil, fmt.Errorf("%s expects %s, and optionally a fallback", function, usage)
+	}
+
+	ret, ok := conversion(arguments[0])
+	if ok {
+		return ret, nil
+	}
+	if len(arguments) == 2 {
+		return arguments[1], nil
+	}
+	return nil, fmt.Errorf("%s cannot convert '%v' to %s", function, arguments[0], kind)
+}
+
+func unixSeconds(value time.Time) float64 {
+	return float64(value.UnixNano()) / float64(time.Second)
+}
+
+/*
+`len(x)` returns the number of characters (runes, not bytes) in the string [x], or of elements in the array or map [x].
+Nil has no elements, so its length is 0; any other value fails evaluation.
+*/
+func lenFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 1 {
+		return nil, errors.New("len expects a single value")
+	}
+
+	switch typed := arguments[0].(type) {
+	case nil:
+		return 0.0, nil
+	case string:
+		return float64(utf8.RuneCountInString(typed)), nil
+	}
+
+	reflected := reflect.ValueOf(arguments[0])
+	switch reflected.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(reflected.Len()), nil
+	}
+	return nil, fmt.Errorf("len expects a string, array, or map, got a %s", typeofValue(arguments[0]))
+}
+
+/*
+`typeof(x)` returns the type of [x]: 'number', 'string', 'bool', 'time', 'array', 'map', or 'nil' -
+or 'object' for anything else, such as a struct.
+Only time parameters are 'time': time literals, such as '2014-01-02', are numbers of seconds, as are `now()` and `toTime(x, format)`.
+*/
+func typeofFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 1 {
+		return nil, errors.New("typeof expects a single value")
+	}
+	return typeofValue(arguments[0]), nil
+}
+
+/*
+Returns a built-in, such as `isNumber(x)`, which returns whether `typeof(x)` is [typeName].
+*/
+func makeTypeTest(function string, typeName string) tExpressionFunction {
+
+	return func(arguments ...interface{}) (interface{}, error) {
+
+		if len(arguments) != 1 {
+			return nil, fmt.Errorf("%s expects a single value", function)
+		}
+		return typeofValue(arguments[0]) == typeName, nil
+	}
+}
+
+func typeofValue(value interface{}) string {
+
+	switch castToFloat64(value).(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return "number"
+	case string, *regexp.Regexp:
+		return "string"
+	case bool:
+		return "bool"
+	case time.Time:
+		return "time"
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "map"
+	}
+	return "object"
+}
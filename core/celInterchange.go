@@ -0,0 +1,918 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// the literals and operators of CEL which are words, and the words it reserves, none of which may name a variable.
+var celKeywords = map[string]bool{
+	"true": true, "false": true, "null": true, "in": true,
+	"as": true, "break": true, "const": true, "continue": true, "else": true, "for": true, "function": true, "if": true,
+	"import": true, "let": true, "loop": true, "package": true, "namespace": true, "return": true, "var": true,
+	"void": true, "while": true,
+}
+
+var celOperators = map[tOperatorSymbol]string{
+	tEQ:       "==",
+	tNEQ:      "!=",
+	tGT:       ">",
+	tLT:       "<",
+	tGTE:      ">=",
+	tLTE:      "<=",
+	tIN:       "in",
+	tAND:      "&&",
+	tOR:       "||",
+	tPLUS:     "+",
+	tMINUS:    "-",
+	tMULTIPLY: "*",
+	tDIVIDE:   "/",
+}
+
+// built-in functions which CEL calls by other names, and CEL's names for them.
+// size() gives an int, so is converted to the double len() gives.
+var celFunctions = map[string]string{
+	"len":      "double(size",
+	"toNumber": "double",
+	"toString": "string",
+}
+
+// CEL's functions which are built in here under other names, and those names.
+// int() and uint() truncate, which toNumber() does not, so are not translated.
+var celBuiltins = map[string]string{
+	"size":   "len",
+	"double": "toNumber",
+	"string": "toString",
+}
+
+/*
+Writes this expression in the Common Expression Language (CEL), for policy systems which have standardized on it.
+Parameters are written as CEL variables, and accessors as field selections, so names must be valid CEL identifiers.
+Numbers are written as CEL doubles, as they are float64s here, so numeric variables must be declared to CEL as doubles.
+Time literals are written as timestamps; `=~` and `!~` as calls of matches(); and len(), toNumber(), and toString()
+as double(size()), double(), and string(). Other function calls are written as they are, to be declared to CEL under
+the same names. `%`, which CEL only has for ints, `**`, `??`, the bitwise operators, and anything else CEL has
+no equivalent for fail with an error naming it.
+*/
+func (t tEvaluableExpression) TToCEL() (string, error) {
+
+	stage := t.planSourceStages()
+	if stage == nil {
+		return "", nil
+	}
+	return celStage(stage)
+}
+
+func celStage(stage *evaluationStage) (string, error) {
+
+	stage = unparenthesized(stage)
+	if stage == nil {
+		return "", errors.New("Cannot write an empty clause as CEL")
+	}
+
+	// a parenthesized list, such as an array argument, is a CEL list.
+	if stage.symbol == tSEPARATE {
+
+		elements, err := celList(stage)
+		if err != nil {
+			return "", err
+		}
+		return "[" + elements + "]", nil
+	}
+
+	switch stage.symbol {
+	case tLITERAL:
+		return celLiteral(stage)
+
+	case tVALUE:
+		return celName(stage.name)
+
+	case tACCESS:
+		path, err := celName(strings.Join(stage.path, "."))
+		if err != nil || stage.rightStage == nil {
+			return path, err
+		}
+
+		arguments, err := celArguments(stage.rightStage)
+		if err != nil {
+			return "", err
+		}
+		return path + arguments, nil
+
+	case tFUNCTIONAL:
+		name, found := celFunctions[stage.name]
+		if !found {
+			name = stage.name
+		}
+
+		arguments, err := celArguments(stage.rightStage)
+		if err != nil {
+			return "", err
+		}
+
+		// a call nested in a conversion, such as `double(size(s))`, is closed by it too.
+		return name + arguments + strings.Repeat(")", strings.Count(name, "(")), nil
+
+	case tNEGATE, tINVERT:
+		operand, err := celSubexpression(stage.rightStage, celPower(stage), false)
+		if err != nil {
+			return "", err
+		}
+		return stage.symbol.String() + operand, nil
+
+	case tREQ, tNREQ:
+		receiver, err := celSubexpression(stage.leftStage, celPower(&evaluationStage{symbol: tREQ}), false)
+		if err != nil {
+			return "", err
+		}
+		pattern, err := celStage(stage.rightStage)
+		if err != nil {
+			return "", err
+		}
+
+		ret := receiver + ".matches(" + pattern + ")"
+		if stage.symbol == tNREQ {
+			ret = "!" + ret
+		}
+		return ret, nil
+
+	case tTERNARY_TRUE, tTERNARY_FALSE:
+		return celTernary(stage)
+
+	}
+
+	operator, found := celOperators[stage.symbol]
+	if !found {
+		return "", fmt.Errorf("Cannot write '%s' as CEL", renderStage(stage))
+	}
+
+	left, err := celSubexpression(stage.leftStage, celPower(stage), false)
+	if err != nil {
+		return "", err
+	}
+	right, err := celSubexpression(stage.rightStage, celPower(stage), true)
+	if err != nil {
+		return "", err
+	}
+	return left + " " + operator + " " + right, nil
+}
+
+/*
+Writes [stage] as the operand of an operator which binds with [power], in parentheses if it would otherwise be parsed
+as something else. CEL's binary operators are all left-associative, so only right-hand operands of the same power need them.
+*/
+func celSubexpression(stage *evaluationStage, power int, isRight bool) (string, error) {
+
+	rendered, err := celStage(stage)
+	if err != nil {
+		return "", err
+	}
+
+	operandPower := celPower(unparenthesized(stage))
+	if operandPower < power || (isRight && operandPower == power) {
+		return "(" + rendered + ")", nil
+	}
+	return rendered, nil
+}
+
+/*
+Returns how tightly CEL binds the operator of [stage]; higher binds tighter.
+*/
+func celPower(stage *evaluationStage) int {
+
+	switch stage.symbol {
+	case tTERNARY_TRUE, tTERNARY_FALSE:
+		return 1
+	case tOR:
+		return 2
+	case tAND:
+		return 3
+	case tEQ, tNEQ, tGT, tLT, tGTE, tLTE, tIN:
+		return 4
+	case tPLUS, tMINUS:
+		return 5
+	case tMULTIPLY, tDIVIDE, tMODULUS:
+		return 6
+	case tNEGATE, tINVERT, tNREQ:
+		return 7
+	}
+	return 8
+}
+
+func celTernary(stage *evaluationStage) (string, error) {
+
+	// `a ? b : c` is planned as the `:` of the `?` of [a] and [b], and [c]. A `?` on its own gives nil if false.
+	condition, value, otherwise := stage.leftStage, stage.rightStage, (*evaluationStage)(nil)
+	if stage.symbol == tTERNARY_FALSE {
+
+		if stage.leftStage.symbol != tTERNARY_TRUE {
+			return "", fmt.Errorf("Cannot write '%s' as CEL", renderStage(stage))
+		}
+		condition, value, otherwise = stage.leftStage.leftStage, stage.leftStage.rightStage, stage.rightStage
+	}
+
+	conditionText, err := celSubexpression(condition, celPower(stage), true)
+	if err != nil {
+		return "", err
+	}
+	valueText, err := celSubexpression(value, celPower(stage), true)
+	if err != nil {
+		return "", err
+	}
+
+	otherwiseText := "null"
+	if otherwise != nil {
+		otherwiseText, err = celSubexpression(otherwise, celPower(stage), false)
+		if err != nil {
+			return "", err
+		}
+	}
+	return conditionText + " ? " + valueText + " : " + otherwiseText, nil
+}
+
+func celLiteral(stage *evaluationStage) (string, error) {
+
+	value, err := stage.operator(nil, nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	switch typed := value.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(typed), nil
+	case string:
+		return strconv.Quote(typed), nil
+	case *regexp.Regexp:
+		return strconv.Quote(typed.String()), nil
+	case float64:
+		if stage.source != "" {
+			return "timestamp(" + strconv.Quote(time.Unix(int64(typed), 0).UTC().Format(time.RFC3339)) + ")", nil
+		}
+		if math.IsNaN(typed) || math.IsInf(typed, 0) {
+			break
+		}
+		// whole numbers are written with a fraction, which CEL would otherwise take to be ints.
+		if typed == math.Trunc(typed) && math.Abs(typed) < 1<<53 {
+			return strconv.FormatInt(int64(typed), 10) + ".0", nil
+		}
+		return strconv.FormatFloat(typed, 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("Cannot write '%s' as CEL", renderStage(stage))
+}
+
+/*
+Returns [name], a parameter or accessor path, if each of its dotted parts is a CEL identifier.
+*/
+func celName(name string) (string, error) {
+
+	for _, part := range strings.Split(name, ".") {
+		if !isCELIdentifier(part) {
+			return "", fmt.Errorf("Cannot write '%s' as CEL, since '%s' is not a CEL identifier", renderName(name), part)
+		}
+	}
+	return name, nil
+}
+
+func isCELIdentifier(name string) bool {
+
+	if name == "" || celKeywords[name] {
+		return false
+	}
+
+	for i, character := range name {
+		if character > unicode.MaxASCII || !(unicode.IsLetter(character) || character == '_' || (i > 0 && unicode.IsDigit(character))) {
+			return false
+		}
+	}
+	return true
+}
+
+func celArguments(stage *evaluationStage) (string, error) {
+
+	stage = unparenthesized(stage)
+	if stage == nil {
+		return "()", nil
+	}
+
+	arguments, err := celList(stage)
+	if err != nil {
+		return "", err
+	}
+	return "(" + arguments + ")", nil
+}
+
+/*
+Writes the elements of the list [stage], separated by commas.
+*/
+func celList(stage *evaluationStage) (string, error) {
+
+	if stage.symbol != tSEPARATE {
+		return celStage(stage)
+	}
+
+	left, err := celList(stage.leftStage)
+	if err != nil {
+		return "", err
+	}
+	right, err := celStage(stage.rightStage)
+	if err != nil {
+		return "", err
+	}
+	return left + ", " + right, nil
+}
+
+type celTokenKind int
+
+const (
+	celEnd celTokenKind = iota
+	celIdentifier
+	celNumber
+	celString
+	celSymbol
+)
+
+type celToken struct {
+	kind     celTokenKind
+	text     string
+	position int
+}
+
+var celSymbols = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "?", ":", "(", ")", "[", "]", ",", "."}
+
+/*
+Translates [cel], an expression in the Common Expression Language, into an equivalent expression,
+in the canonical form of TFormat, such as `user.Age >= 18 && name.matches("^a")` into `user.Age >= 18 && name =~ '^a'`.
+
+Only a subset of CEL translates: its operators (with `?:` as the ternary), literals other than bytes and maps,
+lists on the right of `in`, field selections, and function calls. Selections of capitalized fields translate to
+accessors, and others to parameters of the same name, such as `[request.path]`. matches(), size(), double(), string(),
+and timestamp() translate to their equivalents here; other calls are translated as they are written, to call functions
+of the same names. null translates to nil, which must be compiled with NilLiteral set.
+Numbers are float64s here, so whatever CEL would compute as an int must give the same as a double to translate:
+int() and uint(), which truncate, and the division of one int by another, fail to translate.
+Anything else, such as macros and has(), fails to translate with an error naming where it was found.
+*/
+func TFromCEL(cel string) (string, error) {
+
+	tokens, err := lexCEL(cel)
+	if err != nil {
+		return "", err
+	}
+
+	parser := celParser{tokens: tokens}
+	translated, err := parser.parseExpression()
+	if err != nil {
+		return "", err
+	}
+	if parser.peek().kind != celEnd {
+		return "", parser.unexpected()
+	}
+
+	expression, err := TNewEvaluableExpressionWithOptions(translated, TExpressionOptions{NilLiteral: true})
+	if err != nil {
+		return "", fmt.Errorf("Unable to translate CEL: %v", err)
+	}
+	return expression.TFormat(), nil
+}
+
+func lexCEL(cel string) ([]celToken, error) {
+
+	var tokens []celToken
+	runes := []rune(cel)
+
+	for i := 0; i < len(runes); {
+
+		character := runes[i]
+		start := i
+
+		switch {
+		case unicode.IsSpace(character):
+			i++
+			continue
+
+		case unicode.IsLetter(character) || character == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+
+			// raw and bytes literals, such as r"\d", are not translated.
+			if i < len(runes) && (runes[i] == '"' || runes[i] == '\'') {
+				return nil, fmt.Errorf("Unable to translate '%s' in CEL at %d", string(runes[start:i+1]), start)
+			}
+			tokens = append(tokens, celToken{kind: celIdentifier, text: string(runes[start:i]), position: start})
+			continue
+
+		case unicode.IsDigit(character):
+			// a dot which is not followed by a digit selects a member of the number, as in `0.matches("^a")`.
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || (runes[i] == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])) || runes[i] == 'e' || runes[i] == 'E' ||
+				runes[i] == 'x' || runes[i] == 'X' || (runes[i] >= 'a' && runes[i] <= 'f') || (runes[i] >= 'A' && runes[i] <= 'F') ||
+				((runes[i] == '+' || runes[i] == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+
+			// 1u is an unsigned 1.
+			if i < len(runes) && (runes[i] == 'u' || runes[i] == 'U') {
+				i++
+			}
+			tokens = append(tokens, celToken{kind: celNumber, text: string(runes[start:i]), position: start})
+			continue
+
+		case character == '"' || character == '\'':
+			for i++; i < len(runes) && runes[i] != character; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("Unclosed %c in CEL at %d", character, start)
+			}
+			i++
+
+			text, err := unquoteCEL(string(runes[start+1:i-1]), byte(character))
+			if err != nil {
+				return nil, fmt.Errorf("Unable to translate string in CEL at %d: %v", start, err)
+			}
+			tokens = append(tokens, celToken{kind: celString, text: text, position: start})
+			continue
+		}
+
+		found := false
+		for _, symbol := range celSymbols {
+			if strings.HasPrefix(string(runes[i:]), symbol) {
+				tokens = append(tokens, celToken{kind: celSymbol, text: symbol, position: start})
+				i += len(symbol)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unable to translate '%c' in CEL at %d", character, start)
+		}
+	}
+
+	return append(tokens, celToken{kind: celEnd, position: len(runes)}), nil
+}
+
+func unquoteCEL(quoted string, quote byte) (string, error) {
+
+	var ret strings.Builder
+	for len(quoted) > 0 {
+
+		character, _, tail, err := strconv.UnquoteChar(quoted, quote)
+		if err != nil {
+			return "", err
+		}
+		ret.WriteRune(character)
+		quoted = tail
+	}
+	return ret.String(), nil
+}
+
+/*
+Parses CEL tokens by recursive descent, translating each construct into (parenthesized) expression text as it goes.
+*/
+type celParser struct {
+	tokens []celToken
+	index  int
+
+	// the translations of what CEL would compute as ints, such as `2` and `len(s)`, as they were written.
+	ints map[string]bool
+}
+
+/*
+A primary expression, along with the dotted name it is, if it is one, so that selections can extend it.
+*/
+type celOperand struct {
+	text string
+	path []string
+}
+
+/*
+Notes that [text] is the translation of what CEL would compute as an int, and returns it.
+*/
+func (p *celParser) markInt(text string) string {
+
+	if p.ints == nil {
+		p.ints = make(map[string]bool)
+	}
+	p.ints[text] = true
+	return text
+}
+
+func (p *celParser) peek() celToken {
+	return p.tokens[p.index]
+}
+
+func (p *celParser) next() celToken {
+
+	token := p.tokens[p.index]
+	if token.kind != celEnd {
+		p.index++
+	}
+	return token
+}
+
+func (p *celParser) accept(text string) bool {
+
+	token := p.peek()
+	if (token.kind == celSymbol || token.kind == celIdentifier) && token.text == text {
+		p.index++
+		return true
+	}
+	return false
+}
+
+func (p *celParser) expect(text string) error {
+
+	if !p.accept(text) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *celParser) unexpected() error {
+
+	token := p.peek()
+	if token.kind == celEnd {
+		return errors.New("Unexpected end of CEL")
+	}
+	return fmt.Errorf("Unable to translate '%s' in CEL at %d", token.text, token.position)
+}
+
+func (p *celParser) parseExpression() (string, error) {
+
+	condition, err := p.parseOr()
+	if err != nil || !p.accept("?") {
+		return condition, err
+	}
+
+	value, err := p.parseOr()
+	if err != nil {
+		return "", err
+	}
+	if err = p.expect(":"); err != nil {
+		return "", err
+	}
+	otherwise, err := p.parseExpression()
+	if err != nil {
+		return "", err
+	}
+	return "(" + condition + " ? " + value + " : " + otherwise + ")", nil
+}
+
+func (p *celParser) parseOr() (string, error) {
+	return p.parseBinary([]string{"||"}, p.parseAnd)
+}
+
+func (p *celParser) parseAnd() (string, error) {
+	return p.parseBinary([]string{"&&"}, p.parseRelation)
+}
+
+func (p *celParser) parseRelation() (string, error) {
+
+	ret, err := p.parseAdditive()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if p.accept("in") {
+
+			ret, err = p.parseIn(ret)
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		token := p.peek()
+		switch token.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+			if token.kind != celSymbol {
+				return ret, nil
+			}
+		default:
+			return ret, nil
+		}
+		p.next()
+
+		right, err := p.parseAdditive()
+		if err != nil {
+			return "", err
+		}
+		ret = "(" + ret + " " + token.text + " " + right + ")"
+	}
+}
+
+func (p *celParser) parseIn(left string) (string, error) {
+
+	if !p.accept("[") {
+
+		right, err := p.parseAdditive()
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " in " + right + ")", nil
+	}
+
+	elements, err := p.parseList("]")
+	if err != nil {
+		return "", err
+	}
+
+	// a list of one cannot be written, since `(1)` is only a parenthesized 1.
+	switch len(elements) {
+	case 0:
+		return "false", nil
+	case 1:
+		return "(" + left + " == " + elements[0] + ")", nil
+	}
+	return "(" + left + " in (" + strings.Join(elements, ", ") + "))", nil
+}
+
+func (p *celParser) parseAdditive() (string, error) {
+	return p.parseBinary([]string{"+", "-"}, p.parseMultiplicative)
+}
+
+func (p *celParser) parseMultiplicative() (string, error) {
+	return p.parseBinary([]string{"*", "/", "%"}, p.parseUnary)
+}
+
+func (p *celParser) parseBinary(operators []string, parseOperand func() (string, error)) (string, error) {
+
+	ret, err := parseOperand()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		token := p.peek()
+		if token.kind != celSymbol || !containsString(operators, token.text) {
+			return ret, nil
+		}
+		p.next()
+
+		operand, err := parseOperand()
+		if err != nil {
+			return "", err
+		}
+
+		// CEL's arithmetic on ints gives ints, and its division of them is truncated.
+		ints := p.ints[ret] && p.ints[operand]
+		if ints && token.text == "/" {
+			return "", fmt.Errorf("Unable to translate '/' of ints in CEL at %d, since CEL truncates it", token.position)
+		}
+
+		ret = "(" + ret + " " + token.text + " " + operand + ")"
+		if ints && containsString([]string{"+", "-", "*", "%"}, token.text) {
+			p.markInt(ret)
+		}
+	}
+}
+
+func (p *celParser) parseUnary() (string, error) {
+
+	for _, prefix := range []string{"!", "-"} {
+		if p.accept(prefix) {
+
+			operand, err := p.parseUnary()
+			if err != nil {
+				return "", err
+			}
+			if prefix == "-" && p.ints[operand] {
+				return p.markInt(prefix + "(" + operand + ")"), nil
+			}
+			return prefix + "(" + operand + ")", nil
+		}
+	}
+
+	operand, err := p.parseMember()
+	if err != nil {
+		return "", err
+	}
+	return operand.render(), nil
+}
+
+func (p *celParser) parseMember() (celOperand, error) {
+
+	ret, err := p.parsePrimary()
+	if err != nil {
+		return ret, err
+	}
+
+	for p.accept(".") {
+
+		token := p.next()
+		if token.kind != celIdentifier {
+			p.index--
+			return ret, p.unexpected()
+		}
+
+		if !p.accept("(") {
+
+			if ret.path == nil {
+				return ret, fmt.Errorf("Unable to translate '.%s' in CEL at %d", token.text, token.position)
+			}
+			ret.path = append(ret.path, token.text)
+			continue
+		}
+
+		arguments, err := p.parseList(")")
+		if err != nil {
+			return ret, err
+		}
+
+		receiver := ret.render()
+		switch {
+		case token.text == "matches" && len(arguments) == 1:
+			ret = celOperand{text: "(" + receiver + " =~ " + arguments[0] + ")"}
+		case token.text == "size" && len(arguments) == 0:
+			ret = celOperand{text: p.markInt("len(" + receiver + ")")}
+		case ret.path != nil && isExportedPath(append(ret.path[1:], token.text)):
+			// methods of parameters, as accessors call them.
+			ret = celOperand{text: renderPath(append(ret.path, token.text)) + "(" + strings.Join(arguments, ", ") + ")"}
+		default:
+			return ret, fmt.Errorf("Unable to translate '.%s()' in CEL at %d", token.text, token.position)
+		}
+	}
+	return ret, nil
+}
+
+func (p *celParser) parsePrimary() (celOperand, error) {
+
+	token := p.next()
+
+	switch token.kind {
+	case celNumber:
+		number, err := parseCELNumber(token.text)
+		if err != nil {
+			p.index--
+			return celOperand{}, p.unexpected()
+		}
+
+		// doubles are written with a fraction, so that they are not taken to be ints.
+		text := strconv.FormatFloat(number, 'f', -1, 64)
+		if isCELInt(token.text) {
+			return celOperand{text: p.markInt(text)}, nil
+		}
+		if !strings.Contains(text, ".") {
+			text += ".0"
+		}
+		return celOperand{text: text}, nil
+
+	case celString:
+		return celOperand{text: quoteString(token.text)}, nil
+
+	case celIdentifier:
+		switch token.text {
+		case "true", "false":
+			return celOperand{text: token.text}, nil
+		case "null":
+			return celOperand{text: "nil"}, nil
+		}
+
+		if !p.accept("(") {
+			return celOperand{path: []string{token.text}}, nil
+		}
+
+		arguments, err := p.parseList(")")
+		if err != nil {
+			return celOperand{}, err
+		}
+
+		switch {
+		case token.text == "timestamp" && len(arguments) == 1 && strings.HasPrefix(arguments[0], "`"):
+			// strings which are times are written raw, so that they are strings; here, they are time literals.
+			return celOperand{text: "'" + strings.Trim(arguments[0], "`") + "'"}, nil
+		case token.text == "matches" && len(arguments) == 2:
+			return celOperand{text: "(" + arguments[0] + " =~ " + arguments[1] + ")"}, nil
+		case token.text == "has" || token.text == "duration" || token.text == "timestamp" || token.text == "dyn" || token.text == "type" ||
+			token.text == "int" || token.text == "uint":
+			return celOperand{}, fmt.Errorf("Unable to translate '%s()' in CEL at %d", token.text, token.position)
+		}
+
+		name, found := celBuiltins[token.text]
+		if !found {
+			name = token.text
+		}
+
+		ret := name + "(" + strings.Join(arguments, ", ") + ")"
+		if token.text == "size" {
+			p.markInt(ret)
+		}
+		return celOperand{text: ret}, nil
+
+	case celSymbol:
+		switch token.text {
+		case "(":
+			ret, err := p.parseExpression()
+			if err != nil {
+				return celOperand{}, err
+			}
+			if err = p.expect(")"); err != nil {
+				return celOperand{}, err
+			}
+			if p.ints[ret] {
+				return celOperand{text: p.markInt("(" + ret + ")")}, nil
+			}
+			return celOperand{text: "(" + ret + ")"}, nil
+
+		case "[":
+			elements, err := p.parseList("]")
+			if err != nil {
+				return celOperand{}, err
+			}
+			if len(elements) < 2 {
+				return celOperand{}, fmt.Errorf("Unable to translate a list of fewer than two elements in CEL at %d", token.position)
+			}
+			return celOperand{text: "(" + strings.Join(elements, ", ") + ")"}, nil
+		}
+	}
+
+	p.index--
+	return celOperand{}, p.unexpected()
+}
+
+/*
+Parses a comma-separated list of expressions up to, and including, the [closing] symbol.
+*/
+func (p *celParser) parseList(closing string) ([]string, error) {
+
+	var ret []string
+	for !p.accept(closing) {
+
+		if len(ret) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		element, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, element)
+	}
+	return ret, nil
+}
+
+/*
+Renders [o] as expression text. Selections of capitalized fields are accessors; others are taken to be parameters
+whose names contain dots, since accessors cannot reach unexported fields.
+*/
+func (o celOperand) render() string {
+
+	switch {
+	case o.path == nil:
+		return o.text
+	case len(o.path) > 1 && isExportedPath(o.path[1:]):
+		return renderPath(o.path)
+	}
+	return renderName(strings.Join(o.path, "."))
+}
+
+func isExportedPath(path []string) bool {
+
+	for _, part := range path {
+		if part == "" || !unicode.IsUpper(getFirstRune(part)) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseCELNumber(text string) (float64, error) {
+
+	text = strings.TrimRight(text, "uU")
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+
+		number, err := strconv.ParseUint(text[2:], 16, 64)
+		return float64(number), err
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+/*
+Whether [text], a CEL number, is an int (or uint) rather than a double.
+*/
+func isCELInt(text string) bool {
+	return strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") || !strings.ContainsAny(text, ".eE")
+}
+
+func containsString(values []string, value string) bool {
+
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestToCEL(test *testing.T) {
+
+	cases := []struct {
+		expression string
+		expected   string
+	}{
+		{"a >= 18 && country == 'US'", `a >= 18.0 && country == "US"`},
+		{"name =~ '^a'", `name.matches("^a")`},
+		{"name !~ '^a'", `!name.matches("^a")`},
+		{"len(s) > 2.5", "double(size(s)) > 2.5"},
+		{"len(s) + 1", "double(size(s)) + 1.0"},
+		{"a in (1, 2)", "a in [1.0, 2.0]"},
+		{"flag ? a : b", "flag ? a : b"},
+		{"user.Age >= 18 || !flag", "user.Age >= 18.0 || !flag"},
+		{"s + 'x' == t", `s + "x" == t`},
+		{"'it\\'s' == s", `"it's" == s`},
+		{"a != 1 && (b < 2 || c > 3)", "a != 1.0 && (b < 2.0 || c > 3.0)"},
+		{"t > '2024-01-01'", `t > timestamp("2024-01-01T00:00:00Z")`},
+
+		// numbers are doubles, so must be doubles in CEL, which has no arithmetic between ints and doubles.
+		{"price * 2 > 10", "price * 2.0 > 10.0"},
+		{"7 / 2", "7.0 / 2.0"},
+		{"1000000000000000000000 + 0.5", "1e+21 + 0.5"},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpression(c.expression)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+
+		cel, err := expression.TToCEL()
+		if err != nil {
+			test.Errorf("%s: failed to translate: %v", c.expression, err)
+			continue
+		}
+		if cel != c.expected {
+			test.Errorf("%s: expected %s, got %s", c.expression, c.expected, cel)
+		}
+	}
+}
+
+func TestToCELRejectsUntranslatable(test *testing.T) {
+
+	// CEL only has `%` for ints, and numbers are written as doubles; nor has it `??`, `**`, or `//`.
+	for _, text := range []string{"a % 2 == 1", "a ?? 1", "a ** 2", "a // 2"} {
+
+		expression, err := TNewEvaluableExpression(text)
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+		cel, err := expression.TToCEL()
+		if err == nil {
+			test.Errorf("%s: expected not to translate, got %s", text, cel)
+		}
+	}
+}
+
+func TestFromCEL(test *testing.T) {
+
+	cases := []struct {
+		cel      string
+		expected string
+	}{
+		{`user.Age >= 18 && name.matches("^a")`, "user.Age >= 18 && name =~ '^a'"},
+		{"size(s) > 2", "len(s) > 2"},
+		{"a in [1, 2]", "a in (1, 2)"},
+		{"request.path == '/'", "[request.path] == '/'"},
+		{"1.5 + 2.0", "1.5 + 2"},
+		{"7.0 / 2.0 == 3.5", "7 / 2 == 3.5"},
+		{"size(s) * 2 > 3", "len(s) * 2 > 3"},
+		{`timestamp("2024-01-01T00:00:00Z") < t`, "'2024-01-01T00:00:00Z' < t"},
+		{`s == "2024-01-01"`, "s == `2024-01-01`"},
+
+		// a dot which is not followed by a digit selects a member of a number, rather than being part of it.
+		{`0.matches("^a")`, "0 =~ '^a'"},
+		{`!1.matches("1")`, "!(1 =~ '1')"},
+	}
+
+	for _, c := range cases {
+
+		translated, err := TFromCEL(c.cel)
+		if err != nil {
+			test.Errorf("%s: failed to translate: %v", c.cel, err)
+			continue
+		}
+		if translated != c.expected {
+			test.Errorf("%s: expected %s, got %s", c.cel, c.expected, translated)
+		}
+	}
+}
+
+/*
+CEL expressions, and what CEL gives for them, as its specification defines - not as they evaluate here -
+so that translations which change what an expression means are caught.
+*/
+func TestFromCELKeepsCELResults(test *testing.T) {
+
+	cases := []struct {
+		cel      string
+		expected interface{}
+	}{
+		{"1 + 2 == 3", true},
+		{"10 - 2 - 3", 5.0},
+		{"2 * 3 + 4", 10.0},
+		{"-(3) * 2", -6.0},
+		{"7 % 3", 1.0},
+		{"-7 % 3", -1.0},
+		{"7.0 / 2.0", 3.5},
+		{`size("héllo")`, 5.0},
+		{`"héllo".size() == 5`, true},
+		{`"abc" < "abd"`, true},
+		{`"a" + "b"`, "ab"},
+		{"2 in [1, 2]", true},
+		{"true ? 1 : 2", 1.0},
+		{`!(1 > 2) && "x".matches("^x$")`, true},
+		{`double("2.5") + 1.0`, 3.5},
+		{`"2024-01-01" == "2024-01-01"`, true},
+	}
+
+	for _, c := range cases {
+
+		translated, err := TFromCEL(c.cel)
+		if err != nil {
+			test.Errorf("%s: failed to translate: %v", c.cel, err)
+			continue
+		}
+		expression, err := TNewEvaluableExpressionWithOptions(translated, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: translated into %s, which fails to compile: %v", c.cel, translated, err)
+		}
+		result, err := expression.TEvaluate(nil)
+		if err != nil || result != c.expected {
+			test.Errorf("%s: CEL gives %v, but its translation %s gives %v (%v)", c.cel, c.expected, translated, result, err)
+		}
+	}
+}
+
+/*
+CEL expressions which would give something other than CEL does, were they translated.
+*/
+func TestFromCELRejectsIntSemantics(test *testing.T) {
+
+	for _, cel := range []string{
+		"7 / 2 == 3",
+		"-4 / 3",
+		"(1 + 2) / 2",
+		"size(s) / 2",
+		`"ab".size() / 2`,
+		"int(2.7)",
+		"uint(1)",
+	} {
+		translated, err := TFromCEL(cel)
+		if err == nil {
+			test.Errorf("%s: expected not to translate, got %s", cel, translated)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+/*
+TClock tells the time to the built-in now(), as given by TExpressionOptions.Clock.
+*/
+type TClock interface {
+	TNow() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) TNow() time.Time {
+	return time.Now()
+}
+
+/*
+TFixedClock is a TClock which always tells the same time, so that rules which depend on the time can be tested.
+*/
+type TFixedClock time.Time
+
+func (c TFixedClock) TNow() time.Time {
+	return time.Time(c)
+}
+
+/*
+Returns the built-in `now()`, which reads [clock] (or the system clock, if nil) each time it is called,
+and returns the time in seconds since the Unix epoch - as time literals, such as '2014-01-02', are.
+Durations are given in seconds too, so that `now() - lastLogin > 90d` works as it reads, whether lastLogin is a time.Time
+or a time literal.
+*/
+func makeNowFunction(clock TClock) tExpressionFunction {
+
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	return func(arguments ...interface{}) (interface{}, error) {
+
+		if len(arguments) != 0 {
+			return nil, errors.New("now expects no arguments")
+		}
+		return epochSeconds(clock.TNow()), nil
+	}
+}
+
+/*
+Converts whichever of [left] and [right] are time.Times into seconds since the Unix epoch, where [symbol] adds, subtracts,
+or compares them - so that time.Time parameters work with time literals, durations, and `now()`, as in `now() - lastLogin > 90d`.
+*/
+func timesAsSeconds(symbol tOperatorSymbol, left interface{}, right interface{}) (interface{}, interface{}) {
+
+	switch symbol {
+	case tPLUS:
+		// times are still concatenated to strings as they print.
+		if isString(left) || isString(right) {
+			return left, right
+		}
+	case tMINUS, tEQ, tNEQ, tGT, tLT, tGTE, tLTE:
+	default:
+		return left, right
+	}
+
+	if typed, isTime := left.(time.Time); isTime {
+		left = epochSeconds(typed)
+	}
+	if typed, isTime := right.(time.Time); isTime {
+		right = epochSeconds(typed)
+	}
+	return left, right
+}
+
+func epochSeconds(value time.Time) float64 {
+	return float64(value.Unix()) + float64(value.Nanosecond())/float64(time.Second)
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimesAreSecondsSinceTheEpoch(test *testing.T) {
+
+	lastLogin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	options := TExpressionOptions{Clock: TFixedClock(lastLogin.Add(100 * 24 * time.Hour))}
+
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"now() - lastLogin > 90d", true},
+		{"now() - lastLogin > 120d", false},
+		{"now() - '2024-01-01'", 100 * 86400.0},
+		{"'2024-01-02' - '2024-01-01' == 1d", true},
+		{"lastLogin - lastLogin", 0.0},
+		{"lastLogin + 1d > '2024-01-01'", true},
+		{"lastLogin == '2024-01-01'", true},
+		{"lastLogin != '2024-01-01'", false},
+		{"lastLogin < '2023-12-31'", false},
+		{"lastLogin >= '2024-01-01' && lastLogin <= now()", true},
+		{"lastLogin", lastLogin},
+		{"'at ' + lastLogin", "at 2024-01-01 00:00:00 +0000 UTC"},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpressionWithOptions(c.expression, options)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		result, err := expression.TEvaluate(map[string]interface{}{"lastLogin": lastLogin})
+		if err != nil || result != c.expected {
+			test.Errorf("%s: expected %v, got %v (%v)", c.expression, c.expected, result, err)
+		}
+	}
+}
+
+func TestTimesAreNotMultiplied(test *testing.T) {
+
+	expression, err := TNewEvaluableExpression("lastLogin * 2")
+	if err != nil {
+		test.Fatal(err)
+	}
+	_, err = expression.TEvaluate(map[string]interface{}{"lastLogin": time.Now()})
+	if err == nil {
+		test.Errorf("expected a time not to be a number to multiply")
+	}
+}
@@ -0,0 +1,119 @@
+package core
+
+/*
+The result of a memoized stage within one evaluation.
+*/
+type memoizedResult struct {
+	evaluated bool
+	value     interface{}
+	err       error
+}
+
+/*
+Finds subtrees of [root] which appear more than once, such as `user.Profile.Age` in
+`user.Profile.Age > 18 && user.Profile.Age < 65`, and numbers each distinct one as a memo slot,
+so that it is evaluated once per evaluation rather than wherever it appears.
+
+Only subtrees which always give the same result within an evaluation are memoized: those made of literals, parameters,
+operators, accessors of fields, and calls to pure functions (see TExpressionOptions.PureFunctions). Methods and other
+functions may have side effects, so are called wherever they appear. Subtrees which neither access a field nor call
+a function are cheaper to evaluate again than to memoize, so are not memoized either.
+*/
+func eliminateCommonSubexpressions(root *evaluationStage) {
+
+	counts := make(map[string]int)
+	countSubexpressions(root, counts)
+	markSubexpressions(root, counts, make(map[string]int))
+}
+
+/*
+Counts each memoizable subtree of [stage] in [counts], by its rendered form.
+Returns whether [stage] itself is deterministic, and whether it is expensive enough to be worth memoizing.
+*/
+func countSubexpressions(stage *evaluationStage, counts map[string]int) (bool, bool) {
+
+	if stage == nil {
+		return true, false
+	}
+
+	leftDeterministic, leftExpensive := countSubexpressions(stage.leftStage, counts)
+	rightDeterministic, rightExpensive := countSubexpressions(stage.rightStage, counts)
+
+	deterministic := leftDeterministic && rightDeterministic
+	expensive := leftExpensive || rightExpensive
+
+	switch stage.symbol {
+	case tACCESS:
+		deterministic = deterministic && stage.rightStage == nil
+		expensive = true
+	case tFUNCTIONAL:
+		deterministic = deterministic && stage.pure
+		expensive = true
+	}
+
+	if deterministic && expensive && isMemoizable(stage) {
+		counts[renderStage(stage)]++
+	}
+	return deterministic, expensive
+}
+
+/*
+Numbers the memo slot of each subtree of [stage] which [counts] shows to appear more than once.
+*/
+func markSubexpressions(stage *evaluationStage, counts map[string]int, slots map[string]int) {
+
+	if stage == nil {
+		return
+	}
+
+	if isMemoizable(stage) {
+
+		key := renderStage(stage)
+		if counts[key] > 1 {
+
+			slot, found := slots[key]
+			if !found {
+				slot = len(slots)
+				slots[key] = slot
+			}
+
+			stage.memo = slot + 1
+		}
+	}
+
+	markSubexpressions(stage.leftStage, counts, slots)
+	markSubexpressions(stage.rightStage, counts, slots)
+}
+
+/*
+Whether [stage] gives a value of its own which could be memoized.
+Argument lists are given to functions as slices which those functions may modify, so are not.
+*/
+func isMemoizable(stage *evaluationStage) bool {
+
+	switch stage.symbol {
+	case tNOOP, tSEPARATE, tLITERAL, tVALUE:
+		return false
+	}
+	return true
+}
+
+/*
+Returns the highest memo slot numbered in the tree of [stage].
+*/
+func countMemoSlots(stage *evaluationStage) int {
+
+	if stage == nil {
+		return 0
+	}
+
+	ret := stage.memo
+	for _, child := range []*evaluationStage{stage.leftStage, stage.rightStage} {
+
+		count := countMemoSlots(child)
+		if count > ret {
+			ret = count
+		}
+	}
+	return ret
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+type memoCounter struct {
+	calls int
+}
+
+func (m *memoCounter) Next() float64 {
+	m.calls++
+	return float64(m.calls)
+}
+
+/*
+Compiles [text] with a function `f`, pure if [pure], which doubles its argument and counts its calls.
+*/
+func compileCounting(test *testing.T, text string, pure bool) (*tEvaluableExpression, *int) {
+
+	test.Helper()
+
+	var calls int
+	functions := map[string]tExpressionFunction{
+		"f": func(arguments ...interface{}) (interface{}, error) {
+			calls++
+			return arguments[0].(float64) * 2, nil
+		},
+	}
+
+	var options TExpressionOptions
+	if pure {
+		options.PureFunctions = []string{"f"}
+	}
+
+	expression, err := TNewEvaluableExpressionWithFunctionsAndOptions(text, functions, options)
+	if err != nil {
+		test.Fatalf("%s: %v", text, err)
+	}
+	return expression, &calls
+}
+
+func TestPureCallsAreMemoized(test *testing.T) {
+
+	cases := []struct {
+		expression string
+		pure       bool
+		result     interface{}
+		calls      int
+	}{
+		{"f(a) > 1 && f(a) < 10", true, true, 1},
+		{"f(a) + f(a) + f(a)", true, 12.0, 1},
+		{"f(a) + f(b)", true, 10.0, 2},
+		{"f(a) > 1 && f(a) < 10", false, true, 2},
+		{"a > 5 && f(a) > 1 || f(a) < 10", true, true, 1},
+	}
+
+	for _, c := range cases {
+
+		expression, calls := compileCounting(test, c.expression, c.pure)
+
+		// memoized results last only as long as one evaluation.
+		for i := 1; i <= 2; i++ {
+
+			result, err := expression.TEvaluate(map[string]interface{}{"a": 2.0, "b": 3.0})
+			if err != nil || result != c.result {
+				test.Fatalf("%s: expected %v, got %v (%v)", c.expression, c.result, result, err)
+			}
+			if *calls != c.calls*i {
+				test.Errorf("%s: expected %d calls after %d evaluations, got %d", c.expression, c.calls*i, i, *calls)
+			}
+		}
+	}
+}
+
+/*
+Renders each memoized subtree of [stage], in the order it is found.
+*/
+func memoizedSubtrees(stage *evaluationStage) []string {
+
+	if stage == nil {
+		return nil
+	}
+
+	var ret []string
+	if stage.memo != 0 {
+		ret = append(ret, renderStage(stage))
+	}
+	ret = append(ret, memoizedSubtrees(stage.leftStage)...)
+	return append(ret, memoizedSubtrees(stage.rightStage)...)
+}
+
+func TestMemoizedSubtrees(test *testing.T) {
+
+	cases := []struct {
+		expression string
+		expected   []string
+	}{
+		{"user.Profile.Age > 18 && user.Profile.Age < 65", []string{"user.Profile.Age", "user.Profile.Age"}},
+		{"len(s) > 1 || len(s) == 0", []string{"len(s)", "len(s)"}},
+		{"f(a) > 1 && f(a) < 10", []string{"f(a)", "f(a)"}},
+
+		// parameters and arithmetic are cheaper to evaluate again than to memoize.
+		{"a > 1 && a < 5", nil},
+		{"a + b > 1 && a + b < 5", nil},
+		{"len(s) > 1 || len(t) == 0", nil},
+	}
+
+	for _, c := range cases {
+
+		expression, _ := compileCounting(test, c.expression, true)
+		if memoized := memoizedSubtrees(expression.evaluationStages); !reflect.DeepEqual(memoized, c.expected) {
+			test.Errorf("%s: expected %q to be memoized, got %q", c.expression, c.expected, memoized)
+		}
+	}
+}
+
+func TestMethodsAreNotMemoized(test *testing.T) {
+
+	counter := &memoCounter{}
+
+	expression, err := TNewEvaluableExpression("(counter.Next()) < (counter.Next())")
+	if err != nil {
+		test.Fatal(err)
+	}
+	result, err := expression.TEvaluate(map[string]interface{}{"counter": counter})
+	if err != nil || result != true {
+		test.Errorf("expected each call to be made, got %v (%v)", result, err)
+	}
+	if counter.calls != 2 {
+		test.Errorf("expected 2 calls, got %d", counter.calls)
+	}
+}
+
+/*
+Repeats generated subexpressions within expressions, so that they are memoized, and checks that the memoized plan
+evaluates as the reference plan does - including where a repeat is only reached on some branches.
+*/
+func TestGeneratedMemoizedPlansMatchReferencePlan(test *testing.T) {
+
+	inputs := nativeInputs(20)
+	random := rand.New(rand.NewSource(3))
+
+	for i := 0; i < 1000; i++ {
+
+		data := make([]byte, 32)
+		random.Read(data)
+		generated := "(" + generateExpression(data) + ")"
+
+		for _, text := range []string{
+			generated + " == " + generated,
+			"user.Age + len(" + generated + ") > user.Age",
+			"flag ? " + generated + " : (" + generated + " ?? user.Name)",
+			"(flag && " + generated + ") || " + generated,
+		} {
+			assertMatchesReferencePlan(test, text, inputs)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package core
+
+/*
+TOption configures an expression compiled by TNew, such as TWithFunctions or TWithIntegerMath.
+Options are applied in the order they are given.
+*/
+type TOption func(*constructorOptions)
+
+type constructorOptions struct {
+	functions map[string]tExpressionFunction
+	options   TExpressionOptions
+}
+
+/*
+Parses and plans [expression], configured by the given [options], such as:
+
+	TNew("total / count > limit", TWithFunctions(functions), TWithIntegerMath())
+
+A compiled expression's configuration cannot change once it is compiled, so it may be shared between goroutines
+without care. New options are added as new TOptions, so that adding one does not change the signature of TNew.
+*/
+func TNew(expression string, options ...TOption) (*tEvaluableExpression, error) {
+
+	constructor := constructorOptions{
+		functions: make(map[string]tExpressionFunction),
+	}
+	for _, option := range options {
+		option(&constructor)
+	}
+
+	return TNewEvaluableExpressionWithFunctionsAndOptions(expression, constructor.functions, constructor.options)
+}
+
+/*
+Makes each of [functions] callable by its name. Given more than once, the functions are merged,
+with later ones replacing earlier ones of the same name.
+*/
+func TWithFunctions(functions map[string]tExpressionFunction) TOption {
+
+	return func(constructor *constructorOptions) {
+		for name, function := range functions {
+			constructor.functions[name] = function
+		}
+	}
+}
+
+/*
+Sets every TExpressionOptions field at once, for those which have no TOption of their own.
+Any options given before this one are replaced, so it should be given first.
+*/
+func TWithOptions(options TExpressionOptions) TOption {
+
+	return func(constructor *constructorOptions) {
+		constructor.options = options
+	}
+}
+
+/*
+Turns type checks of operands off (or back on) for every evaluation; see TExpressionOptions.SkipTypeChecks.
+Type checks are on by default.
+*/
+func TWithTypeChecking(enabled bool) TOption {
+
+	return func(constructor *constructorOptions) {
+		constructor.options.SkipTypeChecks = !enabled
+	}
+}
+
+/*
+Adds layouts which string literals are tried against to see if they are dates; see TExpressionOptions.DateFormats.
+*/
+func TWithDateFormats(formats ...string) TOption {
+
+	return func(constructor *constructorOptions) {
+		// copied, so that the formats of options given to TWithOptions are not appended to.
+		existing := constructor.options.DateFormats
+		constructor.options.DateFormats = append(existing[:len(existing):len(existing)], formats...)
+	}
+}
+
+/*
+Makes `/` between whole numbers truncate to a whole number; see TExpressionOptions.IntegerMath.
+*/
+func TWithIntegerMath() TOption {
+
+	return func(constructor *constructorOptions) {
+		constructor.options.IntegerMath = true
+	}
+}
+
+/*
+Denies the expression the capabilities named by [deny]; see TExpressionOptions.Deny.
+*/
+func TWithDenyList(deny TDenyList) TOption {
+
+	return func(constructor *constructorOptions) {
+		constructor.options.Deny = deny
+	}
+}
+
+/*
+Restricts the expression to the parameters and functions named by [allow]; see TExpressionOptions.Allow.
+*/
+func TWithAllowList(allow TAllowList) TOption {
+
+	return func(constructor *constructorOptions) {
+		constructor.options.Allow = &allow
+	}
+}
+
+/*
+Compiles conditions into native closures rather than interpreting them; see TExpressionOptions.NativeClosures.
+*/
+func TWithNativeClosures() TOption {
+
+	return func(constructor *constructorOptions) {
+		constructor.options.NativeClosures = true
+	}
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+/*
+Evaluates [expression], returning whether it panicked rather than returning.
+*/
+func evaluatePanics(expression *tEvaluableExpression, parameters tParameters, options TEvaluationOptions) (panicked bool, err error) {
+
+	defer func() {
+		if recover() != nil {
+			panicked = true
+		}
+	}()
+
+	_, err = expression.TEvaluateWithOptions(parameters, options)
+	return false, err
+}
+
+func TestTypeChecking(test *testing.T) {
+
+	parameters := tMapParameters(map[string]interface{}{"a": "text"})
+
+	checked, err := TNew("a > 1")
+	if err != nil {
+		test.Fatal(err)
+	}
+	unchecked, err := TNew("a > 1", TWithTypeChecking(false))
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	panicked, err := evaluatePanics(checked, parameters, TEvaluationOptions{})
+	if panicked || err == nil {
+		test.Errorf("expected a type error by default")
+	}
+
+	// skipped for one evaluation, checks must still be made by the next.
+	panicked, _ = evaluatePanics(checked, parameters, TEvaluationOptions{SkipTypeChecks: true})
+	if !panicked {
+		test.Errorf("expected the operator to be given the wrong type when type checks are skipped")
+	}
+	panicked, err = evaluatePanics(checked, parameters, TEvaluationOptions{})
+	if panicked || err == nil {
+		test.Errorf("expected skipping type checks for one evaluation not to skip them for the next")
+	}
+
+	panicked, _ = evaluatePanics(unchecked, parameters, TEvaluationOptions{})
+	if !panicked {
+		test.Errorf("expected TWithTypeChecking(false) to skip type checks")
+	}
+}
+
+func TestConstructorOptions(test *testing.T) {
+
+	expression, err := TNew("7 / 2", TWithIntegerMath())
+	if err != nil {
+		test.Fatal(err)
+	}
+	result, err := expression.TEvaluate(nil)
+	if err != nil || result != 3.0 {
+		test.Errorf("expected 3 with integer math, got %v (%v)", result, err)
+	}
+
+	expression, err = TNew("'25/12/2024' > '2024-12-24'", TWithDateFormats("02/01/2006"))
+	if err != nil {
+		test.Fatal(err)
+	}
+	result, err = expression.TEvaluate(nil)
+	if err != nil || result != true {
+		test.Errorf("expected the day-first date to be parsed, got %v (%v)", result, err)
+	}
+
+	expression, err = TNew("double(a)", TWithFunctions(map[string]tExpressionFunction{
+		"double": func(arguments ...interface{}) (interface{}, error) {
+			return arguments[0].(float64) * 2, nil
+		},
+	}))
+	if err != nil {
+		test.Fatal(err)
+	}
+	result, err = expression.TEvaluate(map[string]interface{}{"a": 4})
+	if err != nil || result != 8.0 {
+		test.Errorf("expected 8, got %v (%v)", result, err)
+	}
+}
+
+func TestDateFormatsDoNotModifyGivenOptions(test *testing.T) {
+
+	formats := make([]string, 1, 2)
+	formats[0] = time.RFC1123
+	options := TExpressionOptions{DateFormats: formats}
+
+	_, err := TNew("1", TWithOptions(options), TWithDateFormats("02/01/2006"))
+	if err != nil {
+		test.Fatal(err)
+	}
+	if len(options.DateFormats) != 1 || formats[:2][1] != "" {
+		test.Errorf("expected the formats given to TWithOptions to be left as they were, got %v", formats[:2])
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"errors"
+)
+
+/*
+TContextParameters is a parameter source which may need a context to look values up,
+such as one backed by a database or remote configuration service, which should honor deadlines and carry tracing.
+Sources report a parameter they do not have by returning TErrNoParameter (or an error wrapping it).
+*/
+type TContextParameters interface {
+	TGet(ctx context.Context, name string) (interface{}, error)
+}
+
+/*
+TErrNoParameter is returned by TContextParameters sources which do not have a requested parameter,
+so that the evaluator can tell missing parameters apart from failed lookups.
+*/
+var TErrNoParameter = errors.New("No such parameter")
+
+/*
+Implemented by parameter sources which can make use of the context of an evaluation.
+*/
+type tContextGetter interface {
+	tGetContext(ctx context.Context, name string) (interface{}, error)
+}
+
+type contextParameters struct {
+	source TContextParameters
+}
+
+/*
+Returns tParameters backed by the given [source]. When evaluated with TEvaluateContext, [source] is given that context;
+otherwise it is given context.Background().
+*/
+func TNewContextParameters(source TContextParameters) tParameters {
+	return contextParameters{source: source}
+}
+
+func (p contextParameters) tGet(name string) (interface{}, error) {
+	return p.tGetContext(context.Background(), name)
+}
+
+func (p contextParameters) tGetContext(ctx context.Context, name string) (interface{}, error) {
+
+	value, err := p.source.TGet(ctx, name)
+	if errors.Is(err, TErrNoParameter) {
+		return nil, tMissingParameterError{name}
+	}
+	return value, err
+}
+
+func (p tChainedParameters) tGetContext(ctx context.Context, name string) (interface{}, error) {
+
+	bound := make(tChainedParameters, len(p))
+	for i, source := range p {
+		if source != nil {
+			bound[i] = boundParameters{ctx: ctx, orig: source}
+		}
+	}
+	return bound.tGet(name)
+}
+
+/*
+boundParameters binds the context of one evaluation to a parameter source, for sources which can make use of it.
+*/
+type boundParameters struct {
+	ctx  context.Context
+	orig tParameters
+}
+
+func (p boundParameters) tGet(name string) (interface{}, error) {
+
+	getter, isGetter := p.orig.(tContextGetter)
+	if isGetter {
+		return getter.tGetContext(p.ctx, name)
+	}
+	return p.orig.tGet(name)
+}
+
+type expressionContextKey struct{}
+
+/*
+Returns the text of the expression being evaluated, from the context given to a TContextParameters source,
+so that sources can attribute lookups (in traces, for instance) to the rule which made them.
+*/
+func TExpressionFromContext(ctx context.Context) (string, bool) {
+
+	expression, found := ctx.Value(expressionContextKey{}).(string)
+	return expression, found
+}
+
+/*
+Evaluates this expression against [parameters], giving [ctx] to every TContextParameters source it consults.
+Returns the context's error if it is done before evaluation starts.
+*/
+func (t tEvaluableExpression) TEvaluateContext(ctx context.Context, parameters tParameters) (interface{}, error) {
+
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if parameters == nil {
+		return t.tEval(nil)
+	}
+
+	ctx = context.WithValue(ctx, expressionContextKey{}, t.inputExpression)
+	return t.tEval(boundParameters{ctx: ctx, orig: parameters})
+}
@@ -0,0 +1,68 @@
+package core
+
+/*
+The weights TEstimateCost gives each kind of stage, roughly in proportion to how long each takes to evaluate.
+*/
+const (
+	literalCost    = 1
+	parameterCost  = 1
+	operatorCost   = 2
+	exponentCost   = 4
+	accessorCost   = 8
+	functionCost   = 10
+	patternCost    = 20
+	newPatternCost = 100
+)
+
+/*
+Estimates how expensive this expression is to evaluate, without evaluating it, as a score which is only meaningful
+relative to the scores of other expressions - so that expressions which are far more expensive than usual can be
+rejected or deprioritized before they run. The score is the sum of the weights of every stage of the planned expression,
+so literals folded when planned cost no more than any other literal, and stages which may be short-circuited are
+counted as though they were not.
+
+Regular expression matches are the most expensive stages, especially those whose patterns are not literals,
+which are compiled anew on every evaluation. Then come function calls and accessors, whose fields and methods are found
+by reflection, then `**`, then other operators, and lastly parameters and literals. Functions are weighted by
+TExpressionOptions.FunctionCosts, or else as built-in functions are by default.
+*/
+func (t tEvaluableExpression) TEstimateCost() int {
+	return stageCost(t.evaluationStages, t.options.FunctionCosts)
+}
+
+func stageCost(stage *evaluationStage, functionCosts map[string]int) int {
+
+	if stage == nil {
+		return 0
+	}
+
+	ret := stageCost(stage.leftStage, functionCosts) + stageCost(stage.rightStage, functionCosts)
+
+	switch stage.symbol {
+	case tLITERAL:
+		return ret + literalCost
+	case tVALUE:
+		return ret + parameterCost
+	case tNOOP, tSEPARATE:
+		return ret
+	case tACCESS:
+		return ret + accessorCost
+	case tEXPONENT:
+		return ret + exponentCost
+
+	case tFUNCTIONAL:
+		cost, found := functionCosts[stage.name]
+		if !found {
+			cost = functionCost
+		}
+		return ret + cost
+
+	case tREQ, tNREQ:
+		if stage.rightStage != nil && stage.rightStage.symbol == tLITERAL {
+			return ret + patternCost
+		}
+		return ret + newPatternCost
+	}
+
+	return ret + operatorCost
+}
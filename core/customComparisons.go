@@ -0,0 +1,93 @@
+package core
+
+/*
+TEqualer is implemented by parameter values which decide for themselves what they equal, such as a Money type
+which equals another Money of the same amount and currency.
+When either operand of `==`, `!=`, or `in` is a TEqualer, the comparison delegates to it, rather than comparing deeply.
+*/
+type TEqualer interface {
+
+	/*
+		Returns whether this value equals [other], which is whatever the expression compares it to -
+		another parameter value, or a float64, string, bool, or nil.
+	*/
+	TEqual(other interface{}) bool
+}
+
+/*
+TComparer is implemented by parameter values which can be ordered, such as a Version type.
+When either operand of `<`, `<=`, `>`, or `>=` is a TComparer, the comparison delegates to it,
+rather than failing the type check. A TComparer which is not a TEqualer is equal to whatever it compares as 0 to.
+*/
+type TComparer interface {
+
+	/*
+		Returns a negative number if this value is less than [other], zero if they are equal, or a positive number if it is greater.
+		Returns an error if the two cannot be compared, which fails evaluation.
+	*/
+	TCompare(other interface{}) (int, error)
+}
+
+/*
+Returns whether [left] equals [right] as a TEqualer or TComparer operand decides,
+or false through the second return if neither operand decides for itself.
+*/
+func customEqual(left interface{}, right interface{}) (bool, bool) {
+
+	if equaler, ok := left.(TEqualer); ok {
+		return equaler.TEqual(right), true
+	}
+	if equaler, ok := right.(TEqualer); ok {
+		return equaler.TEqual(left), true
+	}
+
+	comparison, handled, err := customCompare(left, right)
+	if !handled {
+		return false, false
+	}
+
+	// values which cannot be compared are not equal.
+	return err == nil && comparison == 0, true
+}
+
+/*
+Compares [left] to [right] as a TComparer operand decides,
+or returns false through the second return if neither operand is a TComparer.
+*/
+func customCompare(left interface{}, right interface{}) (int, bool, error) {
+
+	if comparer, ok := left.(TComparer); ok {
+		comparison, err := comparer.TCompare(right)
+		return comparison, true, err
+	}
+	if comparer, ok := right.(TComparer); ok {
+		comparison, err := comparer.TCompare(left)
+		return -comparison, true, err
+	}
+	return 0, false, nil
+}
+
+func isComparer(value interface{}) bool {
+
+	_, ok := value.(TComparer)
+	return ok
+}
+
+func hasCustomEquality(value interface{}) bool {
+
+	_, ok := value.(TEqualer)
+	return ok || isComparer(value)
+}
+
+/*
+Compares [left] and [right], one of which must be a TComparer,
+deciding with [accept] whether the result of their comparison satisfies the operator.
+*/
+func delegateComparison(left interface{}, right interface{}, accept func(comparison int) bool) (interface{}, error) {
+
+	comparison, _, err := customCompare(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return boolIface(accept(comparison)), nil
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"strings"
+	"unicode"
+)
+
+/*
+What a TDebugger does once the function it pauses with returns.
+*/
+type TDebugAction int
+
+const (
+	// runs on until the next breakpoint.
+	TDebugContinue TDebugAction = iota
+
+	// pauses again at the very next stage to be entered or exited.
+	TDebugStep
+
+	// pauses again once the stage paused at has been exited, without pausing inside it unless a breakpoint is hit.
+	TDebugStepOver
+)
+
+/*
+A point at which a TDebugger paused.
+*/
+type TDebugFrame struct {
+
+	// the stage paused at. When entering, only its Label and Expression are set;
+	// when exiting, its operands and result can be inspected.
+	Event TTraceEvent
+
+	// whether the stage is being exited, rather than entered.
+	Exiting bool
+
+	// how many stages enclose this one, starting from 0 for the whole expression.
+	Depth int
+}
+
+/*
+TDebugger single-steps through an evaluation, pausing at breakpoints and at each step to call a function,
+which can inspect the stage paused at and decide how to go on.
+Give it as the Tracer of TEvaluationOptions to debug a single evaluation, as in:
+
+	debugger := TNewDebugger(func(frame TDebugFrame) TDebugAction {
+		fmt.Println(frame.Event.Expression, frame.Event.Left, frame.Event.Right, frame.Event.Result)
+		return TDebugStep
+	})
+	debugger.TBreakOnSymbol("&&")
+	expression.TEvaluateWithOptions(parameters, TEvaluationOptions{Tracer: debugger})
+
+A TDebugger follows one evaluation at a time.
+*/
+type TDebugger struct {
+	pause       func(frame TDebugFrame) TDebugAction
+	symbols     map[string]bool
+	expressions map[string]bool
+
+	depth    int
+	stepping bool
+
+	// if not negative, the depth at which to pause once a stage is exited.
+	overDepth int
+}
+
+/*
+Returns a debugger which calls [pause] wherever it pauses, and then does what it returns.
+The debugger only pauses at breakpoints until it is told to step.
+*/
+func TNewDebugger(pause func(frame TDebugFrame) TDebugAction) *TDebugger {
+
+	return &TDebugger{
+		pause:       pause,
+		symbols:     make(map[string]bool),
+		expressions: make(map[string]bool),
+		overDepth:   -1,
+	}
+}
+
+/*
+Pauses on entering any stage labeled [symbol], such as "&&", "matches()", or "[age]", as TTraceEvents are labeled.
+*/
+func (d *TDebugger) TBreakOnSymbol(symbol string) {
+	d.symbols[symbol] = true
+}
+
+/*
+Pauses on entering the sub-expression [expression], such as "age > 18", which is compared ignoring whitespace.
+*/
+func (d *TDebugger) TBreakOnExpression(expression string) {
+	d.expressions[compactExpression(expression)] = true
+}
+
+/*
+Removes all breakpoints.
+*/
+func (d *TDebugger) TClearBreakpoints() {
+
+	d.symbols = make(map[string]bool)
+	d.expressions = make(map[string]bool)
+}
+
+/*
+Makes the debugger pause at the next stage it is told of, such as the first stage of the next evaluation.
+*/
+func (d *TDebugger) TStep() {
+	d.stepping = true
+}
+
+func (d *TDebugger) TEnter(event TTraceEvent) {
+
+	frame := TDebugFrame{Event: event, Depth: d.depth}
+	d.depth++
+
+	if d.stepping || d.symbols[event.Label] || d.expressions[compactExpression(event.Expression)] {
+		d.resume(d.pause(frame), frame)
+	}
+}
+
+func (d *TDebugger) TExit(event TTraceEvent) {
+
+	d.depth--
+	frame := TDebugFrame{Event: event, Exiting: true, Depth: d.depth}
+
+	if d.stepping || d.overDepth == d.depth {
+		d.resume(d.pause(frame), frame)
+	}
+}
+
+func (d *TDebugger) resume(action TDebugAction, frame TDebugFrame) {
+
+	d.stepping = action == TDebugStep
+	d.overDepth = -1
+
+	if action == TDebugStepOver {
+		if frame.Exiting {
+			d.stepping = true
+		} else {
+			d.overDepth = frame.Depth
+		}
+	}
+}
+
+func compactExpression(expression string) string {
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, expression)
+}
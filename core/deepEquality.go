@@ -0,0 +1,106 @@
+package core
+
+import (
+	"reflect"
+)
+
+/*
+Returns a `==` (or `!=`, if [negate]) operator which compares arrays and maps by their elements, see TExpressionOptions.DeepEquality.
+*/
+func makeDeepEqualityStage(negate bool) evaluationOperator {
+
+	return func(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+		return boolIface(deepEqual(left, right) != negate), nil
+	}
+}
+
+/*
+Like inStage, but compares arrays and maps by their elements, see TExpressionOptions.DeepEquality.
+*/
+func deepInStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+	for _, value := range right.([]interface{}) {
+		if deepEqual(left, value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+/*
+Returns whether [left] and [right] are equal, comparing slices and arrays element by element, and maps entry by entry.
+Numbers of any type are compared as float64s, as parameters are, and TEqualers and TComparers decide for themselves.
+*/
+func deepEqual(left interface{}, right interface{}) bool {
+
+	left = castToFloat64(left)
+	right = castToFloat64(right)
+
+	if equal, handled := customEqual(left, right); handled {
+		return equal
+	}
+
+	leftValue := reflect.ValueOf(left)
+	rightValue := reflect.ValueOf(right)
+
+	if isList(leftValue) && isList(rightValue) {
+
+		if leftValue.Len() != rightValue.Len() {
+			return false
+		}
+
+		for i := 0; i < leftValue.Len(); i++ {
+			if !deepEqual(leftValue.Index(i).Interface(), rightValue.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if leftValue.Kind() == reflect.Map && rightValue.Kind() == reflect.Map {
+
+		if leftValue.Len() != rightValue.Len() {
+			return false
+		}
+
+		iterator := leftValue.MapRange()
+		for iterator.Next() {
+
+			value, found := deepMapIndex(rightValue, iterator.Key().Interface())
+			if !found || !deepEqual(iterator.Value().Interface(), value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return reflect.DeepEqual(left, right)
+}
+
+func isList(value reflect.Value) bool {
+	return value.Kind() == reflect.Slice || value.Kind() == reflect.Array
+}
+
+/*
+Returns the value [mapValue] holds for a key equal to [key], whose type may differ from that of the map's keys.
+*/
+func deepMapIndex(mapValue reflect.Value, key interface{}) (interface{}, bool) {
+
+	keyValue := reflect.ValueOf(key)
+	if keyValue.IsValid() && keyValue.Type().AssignableTo(mapValue.Type().Key()) {
+
+		value := mapValue.MapIndex(keyValue)
+		if value.IsValid() {
+			return value.Interface(), true
+		}
+	}
+
+	// keys of other types, such as an int key in a map[float64]string, can only be found by comparing with every key.
+	iterator := mapValue.MapRange()
+	for iterator.Next() {
+		if deepEqual(key, iterator.Key().Interface()) {
+			return iterator.Value().Interface(), true
+		}
+	}
+	return nil, false
+}
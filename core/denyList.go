@@ -0,0 +1,95 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+TDenyList names capabilities which an expression may not use, as given by TExpressionOptions.Deny,
+so that expressions written by untrusted users can be confined to what they need.
+An expression which uses a denied capability fails to compile, with an error which wraps TErrDenied.
+The zero value denies nothing.
+*/
+type TDenyList struct {
+
+	/*
+		Operators which may not be used, by their symbols, such as "**", or "=~" and "!~" for regular expression matching.
+		Logical operators are named by their symbols, even if WordOperators lets them be written as words:
+		"!" denies `not`, and "&&" denies `and`.
+	*/
+	Operators []string
+
+	/*
+		If set, pattern literals, such as `/^a+$/i`, may not be used. Together with denying "=~" and "!~",
+		this keeps regular expressions from being compiled from the expression.
+	*/
+	Patterns bool
+
+	/*
+		If set, accessors, such as `user.Name` or `user.IsAdmin()`, may not be used,
+		so that nothing of a parameter is read or called by reflection.
+	*/
+	Accessors bool
+
+	/*
+		Functions which may not be called, by name, whether they are built in or given to the expression.
+	*/
+	Functions []string
+}
+
+/*
+TErrDenied is wrapped by the errors of expressions which use a capability denied by TExpressionOptions.Deny.
+*/
+var TErrDenied = errors.New("Denied")
+
+/*
+Returns an error if any of [tokens] uses a capability denied by [deny].
+*/
+func checkDenied(tokens []tExpressionToken, deny TDenyList) error {
+
+	for _, symbol := range deny.Operators {
+		if !isOperatorSymbol(symbol) {
+			return fmt.Errorf("Cannot deny '%s', which is not an operator", symbol)
+		}
+	}
+
+	for _, token := range tokens {
+
+		switch token.Kind {
+		case tCOMPARATOR, tLOGICALOP, tMODIFIER, tPREFIX, tTERNARY:
+			if containsString(deny.Operators, token.Value.(string)) {
+				return fmt.Errorf("%w: the operator '%s' may not be used", TErrDenied, token.Value)
+			}
+
+		case tPATTERN:
+			if deny.Patterns {
+				return fmt.Errorf("%w: pattern literals may not be used", TErrDenied)
+			}
+
+		case tACCESSOR:
+			if deny.Accessors {
+				return fmt.Errorf("%w: the accessor '%s' may not be used", TErrDenied, renderToken(token))
+			}
+
+		case tFUNCTION:
+			name := token.Value.(tNamedFunction).name
+			if containsString(deny.Functions, name) {
+				return fmt.Errorf("%w: the function '%s' may not be called", TErrDenied, name)
+			}
+		}
+	}
+	return nil
+}
+
+func isOperatorSymbol(symbol string) bool {
+
+	for _, symbols := range []map[string]tOperatorSymbol{comparatorSymbols, logicalSymbols, modifierSymbols, prefixSymbols, ternarySymbols} {
+
+		_, found := symbols[symbol]
+		if found {
+			return true
+		}
+	}
+	return false
+}
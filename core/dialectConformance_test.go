@@ -0,0 +1,392 @@
+package core
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"testing"
+)
+
+type conformanceUser struct {
+	Age  float64
+	Name string
+}
+
+// every dialect has a null, so expressions are compiled with nil as a literal.
+var conformanceOptions = TExpressionOptions{NilLiteral: true}
+
+/*
+Expressions which every dialect can write. Each is translated to CEL and protobuf and back,
+and must then evaluate exactly as it did before on every generated input.
+*/
+var conformanceCorpus = []string{
+	"a > b",
+	"a >= 1 && b < 2.5",
+	"a == b || !flag",
+	"!(a != 3) && flag",
+	"a + b * 2 > 10",
+	"(a + b) * 2 > 10",
+	"a - b - 1",
+	"a / (b + 10)",
+	"a * 3 == b",
+	"-a < b",
+	"s == 'abc'",
+	"s + t",
+	"s != t && s > t",
+	"a in (1, 2, 3)",
+	"s in ('a', 'abc')",
+	"flag ? a : b",
+	"a > 1 ? s : t",
+	"s =~ '^a'",
+	"len(s) > 1",
+	"toString(a) + s",
+	"user.Age >= 18 && user.Name == s",
+	"true && flag",
+	"a == nil",
+}
+
+// SQL predicates, and the native expressions they are meant to translate into.
+var sqlConformanceCorpus = []struct {
+	sql    string
+	native string
+}{
+	{"a > b", "a > b"},
+	{"a >= 1 AND b < 2.5", "a >= 1 && b < 2.5"},
+	{"a = b OR NOT flag", "a == b || !flag"},
+	{"a <> 3", "a != 3"},
+	{"a + b * 2 > 10", "a + b * 2 > 10"},
+	{"a % 3 = 1", "a % 3 == 1"},
+	{"s = 'abc' -- trailing comment", "s == 'abc'"},
+	{"s || t = 'aa'", "s + t == 'aa'"},
+	{"a IN (1, 2, 3)", "a in (1, 2, 3)"},
+	{"a NOT IN (1, 2, 3)", "!(a in (1, 2, 3))"},
+	{"a BETWEEN 1 AND 3", "a >= 1 && a <= 3"},
+	{"s LIKE 'a%'", "s =~ '^a'"},
+	{"s LIKE '_b%'", "s =~ '^.b'"},
+	{"s NOT LIKE '%c'", "s !~ 'c$'"},
+	{"s IS NULL", "s == nil"},
+	{"s IS NOT NULL /* block comment */ AND a > 0", "s != nil && a > 0"},
+}
+
+/*
+Generates [count] parameter sets, the same ones on every run, over the parameters of the corpora.
+*/
+func conformanceInputs(count int) []map[string]interface{} {
+
+	numbers := []float64{-3, 0, 1, 2, 2.5, 3, 7, 18, 40}
+	texts := []string{"", "a", "aa", "abc", "xbc", "B"}
+	random := rand.New(rand.NewSource(1))
+
+	ret := make([]map[string]interface{}, count)
+	for i := range ret {
+
+		ret[i] = map[string]interface{}{
+			"a":    numbers[random.Intn(len(numbers))],
+			"b":    numbers[random.Intn(len(numbers))],
+			"s":    texts[random.Intn(len(texts))],
+			"t":    texts[random.Intn(len(texts))],
+			"flag": random.Intn(2) == 0,
+			"user": conformanceUser{Age: numbers[random.Intn(len(numbers))], Name: "abc"},
+		}
+
+		// every so often, a nullable parameter is nil.
+		if random.Intn(8) == 0 {
+			ret[i]["s"] = nil
+		}
+	}
+	return ret
+}
+
+/*
+Evaluates [original] and [translated] on each of [inputs], failing if they give different results,
+or if one fails where the other does not.
+*/
+func assertConformance(test *testing.T, dialect string, original string, translated string, inputs []map[string]interface{}) {
+
+	test.Helper()
+
+	expected, err := TNewEvaluableExpressionWithOptions(original, conformanceOptions)
+	if err != nil {
+		test.Fatalf("%s: %v", original, err)
+	}
+	actual, err := TNewEvaluableExpressionWithOptions(translated, conformanceOptions)
+	if err != nil {
+		test.Errorf("%s: translated through %s into %s, which fails to compile: %v", original, dialect, translated, err)
+		return
+	}
+
+	for _, parameters := range inputs {
+
+		expectedResult, expectedErr := expected.TEvaluate(parameters)
+		actualResult, actualErr := actual.TEvaluate(parameters)
+
+		if (expectedErr == nil) != (actualErr == nil) {
+			test.Errorf("%s: translated through %s into %s, which fails differently given %v: %v, and %v",
+				original, dialect, translated, parameters, expectedErr, actualErr)
+			return
+		}
+
+		if conformanceResult(expectedResult) != conformanceResult(actualResult) {
+			test.Errorf("%s: translated through %s into %s, which gives %v rather than %v given %v",
+				original, dialect, translated, actualResult, expectedResult, parameters)
+			return
+		}
+	}
+}
+
+/*
+Prints [result] so that results compare equal if a translation gives the same, even NaNs.
+Patterns are written in other dialects as the strings they match, so compare as those strings.
+*/
+func conformanceResult(result interface{}) string {
+
+	switch typed := result.(type) {
+	case *regexp.Regexp:
+		result = typed.String()
+	case []interface{}:
+		elements := make([]string, len(typed))
+		for i, element := range typed {
+			elements[i] = conformanceResult(element)
+		}
+		result = elements
+	}
+	return fmt.Sprintf("%#v", result)
+}
+
+func TestCELConformance(test *testing.T) {
+
+	inputs := conformanceInputs(200)
+
+	for _, original := range conformanceCorpus {
+
+		expression, err := TNewEvaluableExpressionWithOptions(original, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", original, err)
+		}
+
+		cel, err := expression.TToCEL()
+		if err != nil {
+			test.Errorf("%s: failed to translate to CEL: %v", original, err)
+			continue
+		}
+
+		translated, err := TFromCEL(cel)
+		if err != nil {
+			test.Errorf("%s: translated to CEL as %s, which fails to translate back: %v", original, cel, err)
+			continue
+		}
+		assertConformance(test, "CEL", original, translated, inputs)
+	}
+}
+
+func TestSQLConformance(test *testing.T) {
+
+	inputs := conformanceInputs(200)
+
+	for _, c := range sqlConformanceCorpus {
+
+		translated, err := TFromSQL(c.sql)
+		if err != nil {
+			test.Errorf("%s: failed to translate from SQL: %v", c.sql, err)
+			continue
+		}
+		assertConformance(test, "SQL", c.native, translated, inputs)
+	}
+}
+
+func TestProtoConformance(test *testing.T) {
+
+	inputs := conformanceInputs(200)
+
+	for _, original := range conformanceCorpus {
+
+		expression, err := TNewEvaluableExpressionWithOptions(original, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", original, err)
+		}
+
+		data, err := expression.TMarshalProto()
+		if err != nil {
+			test.Errorf("%s: failed to encode: %v", original, err)
+			continue
+		}
+
+		translated, err := TFromProto(data)
+		if err != nil {
+			test.Errorf("%s: failed to decode: %v", original, err)
+			continue
+		}
+		assertConformance(test, "protobuf", original, translated, inputs)
+	}
+}
+
+/*
+Generates an expression from [data], a fuzzer's input, out of the parameters of conformanceInputs,
+and the literals, operators, and functions which every dialect can write. The same data always gives the same expression.
+*/
+func generateExpression(data []byte) string {
+
+	generator := expressionGenerator{data: data}
+	return generator.expression(4)
+}
+
+type expressionGenerator struct {
+	data     []byte
+	position int
+}
+
+func (g *expressionGenerator) choose(count int) int {
+
+	if g.position >= len(g.data) {
+		return 0
+	}
+	g.position++
+	return int(g.data[g.position-1]) % count
+}
+
+func (g *expressionGenerator) pick(choices ...string) string {
+	return choices[g.choose(len(choices))]
+}
+
+func (g *expressionGenerator) expression(depth int) string {
+
+	// leaves only, once deep enough.
+	kinds := 11
+	if depth <= 0 {
+		kinds = 4
+	}
+
+	switch g.choose(kinds) {
+	case 0:
+		return g.pick("a", "b", "s", "t", "flag")
+	case 1:
+		return g.pick("0", "1", "2.5", "-3", "18", "1e3")
+	case 2:
+		return g.pick("'a'", "'abc'", "''", `'it\'s'`, `'say "hi"'`, `'back\\slash'`)
+	case 3:
+		return g.pick("true", "false", "nil", "user.Age", "user.Name")
+	case 4, 5:
+		operator := g.pick("==", "!=", ">", "<", ">=", "<=", "&&", "||", "+", "-", "*", "/", "%")
+		return "(" + g.expression(depth-1) + " " + operator + " " + g.expression(depth-1) + ")"
+	case 6:
+		return g.pick("!", "-") + "(" + g.expression(depth-1) + ")"
+	case 7:
+		return "(" + g.expression(depth-1) + " ? " + g.expression(depth-1) + " : " + g.expression(depth-1) + ")"
+	case 8:
+		return "(" + g.expression(depth-1) + " in (" + g.expression(depth-1) + ", " + g.expression(depth-1) + "))"
+	case 9:
+		return "(" + g.expression(depth-1) + " " + g.pick("=~", "!~") + " " + g.pick("'^a'", "'c$'", "'b+'", `'\\.'`) + ")"
+	default:
+		return g.pick("len", "toString", "toNumber") + "(" + g.expression(depth-1) + ")"
+	}
+}
+
+/*
+Whether [expression] evaluates without error given any of [inputs].
+Expressions which never do, such as `1 =~ 'a'`, are ill-typed, and need not translate to anything.
+*/
+func evaluatesOnAny(expression *tEvaluableExpression, inputs []map[string]interface{}) bool {
+
+	for _, parameters := range inputs {
+
+		_, err := expression.TEvaluate(parameters)
+		if err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Checks that [original] translates through CEL, and back, into an expression which evaluates the same on [inputs],
+unless it cannot be written as CEL, or is ill-typed.
+*/
+func assertCELRoundTrip(test *testing.T, original string, inputs []map[string]interface{}) {
+
+	test.Helper()
+
+	expression, err := TNewEvaluableExpressionWithOptions(original, conformanceOptions)
+	if err != nil || !evaluatesOnAny(expression, inputs) {
+		return
+	}
+	cel, err := expression.TToCEL()
+	if err != nil {
+		return
+	}
+
+	translated, err := TFromCEL(cel)
+	if err != nil {
+		test.Errorf("%s: translated to CEL as %s, which fails to translate back: %v", original, cel, err)
+		return
+	}
+	assertConformance(test, "CEL", original, translated, inputs)
+}
+
+/*
+Like assertCELRoundTrip, but through protobuf.
+*/
+func assertProtoRoundTrip(test *testing.T, original string, inputs []map[string]interface{}) {
+
+	test.Helper()
+
+	expression, err := TNewEvaluableExpressionWithOptions(original, conformanceOptions)
+	if err != nil || !evaluatesOnAny(expression, inputs) {
+		return
+	}
+	data, err := expression.TMarshalProto()
+	if err != nil {
+		return
+	}
+
+	translated, err := TFromProto(data)
+	if err != nil {
+		test.Errorf("%s: encoded as protobuf, which fails to decode: %v", original, err)
+		return
+	}
+	assertConformance(test, "protobuf", original, translated, inputs)
+}
+
+/*
+Round-trips expressions generated from pseudo-random data, the same on every run, through each dialect.
+*/
+func TestGeneratedConformance(test *testing.T) {
+
+	inputs := conformanceInputs(20)
+	random := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 2000; i++ {
+
+		data := make([]byte, 32)
+		random.Read(data)
+
+		original := generateExpression(data)
+		assertCELRoundTrip(test, original, inputs)
+		assertProtoRoundTrip(test, original, inputs)
+	}
+}
+
+/*
+Fuzzes generated expressions through CEL, and back. Run with `go test -fuzz FuzzCELRoundTrip ./core`.
+*/
+func FuzzCELRoundTrip(fuzz *testing.F) {
+
+	fuzz.Add([]byte{4, 0, 1, 3})
+	inputs := conformanceInputs(20)
+
+	fuzz.Fuzz(func(test *testing.T, data []byte) {
+		assertCELRoundTrip(test, generateExpression(data), inputs)
+	})
+}
+
+/*
+Fuzzes generated expressions through protobuf, and back. Run with `go test -fuzz FuzzProtoRoundTrip ./core`.
+*/
+func FuzzProtoRoundTrip(fuzz *testing.F) {
+
+	fuzz.Add([]byte{4, 0, 1, 3})
+	inputs := conformanceInputs(20)
+
+	fuzz.Fuzz(func(test *testing.T, data []byte) {
+		assertProtoRoundTrip(test, generateExpression(data), inputs)
+	})
+}
@@ -0,0 +1,276 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var rangeComparators = map[tOperatorSymbol]string{
+	tGT:  "gt",
+	tLT:  "lt",
+	tGTE: "gte",
+	tLTE: "lte",
+}
+
+/*
+Translates this expression into an Elasticsearch (or OpenSearch) query, ready to be encoded as JSON as the "query"
+of a search - so that rules can be pushed down to the search tier where they translate, and evaluated in memory otherwise.
+Parameters are taken to be fields of the document; accessors, such as `user.Address.City`, to be paths of fields.
+
+`&&`, `||`, and `!` translate to bool queries, which filter rather than score. `==`, `!=`, and `in` translate to
+term queries, which match exact values, so text fields should be compared as keywords. The other comparators translate
+to range queries, and `=~` and `!~` to regexp queries. Regexp queries match whole values, so patterns are unanchored
+by wrapping them in `.*`, unless they start with `^` or end with `$`; other than that, patterns must be written in
+the subset of syntax Lucene shares with Go, so that `\d` and `(?i)` do not translate. As for TMongoFilter, anything else fails to translate with an error naming it.
+*/
+func (t tEvaluableExpression) TElasticsearchQuery() (map[string]interface{}, error) {
+
+	stage, err := t.planFilterStages()
+	if err != nil {
+		return nil, err
+	}
+	if stage == nil {
+		return map[string]interface{}{"match_all": map[string]interface{}{}}, nil
+	}
+
+	return elasticsearchQuery(stage)
+}
+
+func elasticsearchQuery(stage *evaluationStage) (map[string]interface{}, error) {
+
+	switch stage.symbol {
+	case tNOOP:
+		if stage.rightStage != nil {
+			return elasticsearchQuery(stage.rightStage)
+		}
+
+	case tAND, tOR:
+		occurrence := "filter"
+		if stage.symbol == tOR {
+			occurrence = "should"
+		}
+
+		var clauses []interface{}
+		for _, operand := range []*evaluationStage{stage.leftStage, stage.rightStage} {
+
+			clause, err := elasticsearchQuery(operand)
+			if err != nil {
+				return nil, err
+			}
+
+			// nested operators of the same kind are flattened into one, as `a && b && c` is written.
+			if nested, isNested := boolClauses(clause, occurrence); isNested {
+				clauses = append(clauses, nested...)
+			} else {
+				clauses = append(clauses, clause)
+			}
+		}
+
+		query := map[string]interface{}{occurrence: clauses}
+		if stage.symbol == tOR {
+			query["minimum_should_match"] = 1
+		}
+		return map[string]interface{}{"bool": query}, nil
+
+	case tINVERT:
+		clause, err := elasticsearchQuery(stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+		return mustNot(clause), nil
+
+	case tVALUE, tACCESS:
+		field, isField := filterField(stage)
+		if isField {
+			return termQuery(field, true), nil
+		}
+
+	case tLITERAL:
+		value, _ := filterValue(stage)
+		if value == true {
+			return map[string]interface{}{"match_all": map[string]interface{}{}}, nil
+		}
+
+	case tEQ, tNEQ:
+		field, value, _, ok := filterComparison(stage)
+		if ok {
+
+			// a term query cannot match a missing value, but whether a field exists can be queried.
+			var query map[string]interface{}
+			if value == nil {
+				query = mustNot(map[string]interface{}{"exists": map[string]interface{}{"field": field}})
+			} else {
+				query = termQuery(field, value)
+			}
+
+			if stage.symbol == tNEQ {
+				return mustNot(query), nil
+			}
+			return query, nil
+		}
+
+	case tGT, tLT, tGTE, tLTE:
+		field, value, symbol, ok := filterComparison(stage)
+		if ok && value != nil {
+			return map[string]interface{}{
+				"range": map[string]interface{}{field: map[string]interface{}{rangeComparators[symbol]: value}},
+			}, nil
+		}
+
+	case tIN:
+		field, isField := filterField(stage.leftStage)
+		values, isList := filterList(stage.rightStage)
+		if isField && isList {
+			return map[string]interface{}{"terms": map[string]interface{}{field: values}}, nil
+		}
+
+	case tREQ, tNREQ:
+		field, isField := filterField(stage.leftStage)
+		pattern, isPattern := filterPattern(stage.rightStage)
+		if isPattern {
+			pattern, isPattern = wholeValuePattern(pattern)
+		}
+		if isField && isPattern {
+
+			query := map[string]interface{}{
+				"regexp": map[string]interface{}{field: map[string]interface{}{"value": pattern}},
+			}
+			if stage.symbol == tNREQ {
+				return mustNot(query), nil
+			}
+			return query, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Cannot translate '%s' into an Elasticsearch query", renderStage(stage))
+}
+
+func termQuery(field string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{"term": map[string]interface{}{field: value}}
+}
+
+func mustNot(query map[string]interface{}) map[string]interface{} {
+
+	// `!(a != 1)` is written as `a == 1` would be, rather than doubly negated.
+	if clauses, isNegation := boolClauses(query, "must_not"); isNegation && len(clauses) == 1 {
+		return clauses[0].(map[string]interface{})
+	}
+	return map[string]interface{}{"bool": map[string]interface{}{"must_not": []interface{}{query}}}
+}
+
+/*
+Returns the clauses of [query], if it is a bool query with no clauses but those of [occurrence].
+*/
+func boolClauses(query map[string]interface{}, occurrence string) ([]interface{}, bool) {
+
+	inner, isBool := query["bool"].(map[string]interface{})
+	if !isBool || len(query) != 1 {
+		return nil, false
+	}
+
+	for key := range inner {
+		if key != occurrence && key != "minimum_should_match" {
+			return nil, false
+		}
+	}
+
+	clauses, found := inner[occurrence].([]interface{})
+	return clauses, found
+}
+
+/*
+Returns [pattern], which matches anywhere in a value, as a Lucene pattern, which matches whole values - or false if
+it uses syntax which Lucene does not share with Go, or means something else in Lucene: anchors anywhere but at the ends,
+character class escapes such as `\d` and `\w` (which Lucene takes for the letter itself), flags and other groups which
+start with `(?`, and POSIX classes. Characters which are operators only in Lucene, such as `@` and `&`, are escaped.
+*/
+func wholeValuePattern(pattern string) (string, bool) {
+
+	var ret strings.Builder
+	var depth int
+	var class, alternation, end bool
+
+	start := strings.HasPrefix(pattern, "^")
+	if start {
+		pattern = pattern[1:]
+	}
+
+	for i := 0; i < len(pattern); i++ {
+
+		character := pattern[i]
+
+		switch {
+		case character == '\\':
+			if i+1 == len(pattern) {
+				return "", false
+			}
+			i++
+			if unicode.IsLetter(rune(pattern[i])) || unicode.IsDigit(rune(pattern[i])) {
+				return "", false
+			}
+			ret.WriteByte(character)
+			character = pattern[i]
+
+		case class:
+			if character == ']' {
+				class = false
+			}
+			if character == '[' && strings.HasPrefix(pattern[i+1:], ":") {
+				return "", false
+			}
+
+		case character == '[':
+			class = true
+			ret.WriteByte(character)
+
+			// a `]` first in a class is one of its characters, rather than its end.
+			if strings.HasPrefix(pattern[i+1:], "^") {
+				i++
+				ret.WriteByte(pattern[i])
+			}
+			if strings.HasPrefix(pattern[i+1:], "]") {
+				i++
+				ret.WriteByte(pattern[i])
+			}
+			continue
+
+		case character == '$' && i+1 == len(pattern):
+			end = true
+			continue
+
+		case character == '^', character == '$', character == '(' && strings.HasPrefix(pattern[i+1:], "?"):
+			return "", false
+
+		case character == '(':
+			depth++
+		case character == ')':
+			depth--
+		case character == '|' && depth == 0:
+			alternation = true
+
+		case strings.IndexByte("#@&<>~\"", character) >= 0:
+			ret.WriteByte('\\')
+		}
+
+		ret.WriteByte(character)
+	}
+
+	body := ret.String()
+
+	// an anchor at one end of a pattern only anchors the first or last of its alternatives.
+	if alternation {
+		if start || end {
+			return "", false
+		}
+		body = "(" + body + ")"
+	}
+
+	if !start {
+		body = ".*" + body
+	}
+	if !end {
+		body = body + ".*"
+	}
+	return body, true
+}
@@ -0,0 +1,204 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"testing"
+)
+
+func TestElasticsearchQuery(test *testing.T) {
+
+	cases := []struct {
+		expression string
+		expected   string
+	}{
+		{"a > 1 && s == 'x'", `{"bool":{"filter":[{"range":{"a":{"gt":1}}},{"term":{"s":"x"}}]}}`},
+		{"a > 1 && b < 2 && flag", `{"bool":{"filter":[{"range":{"a":{"gt":1}}},{"range":{"b":{"lt":2}}},{"term":{"flag":true}}]}}`},
+		{"!(a >= 1) || 5 < b", `{"bool":{"minimum_should_match":1,"should":[{"bool":{"must_not":[{"range":{"a":{"gte":1}}}]}},{"range":{"b":{"gt":5}}}]}}`},
+		{"s in ('a', 'b')", `{"terms":{"s":["a","b"]}}`},
+		{"s =~ '^a'", `{"regexp":{"s":{"value":"a.*"}}}`},
+		{"s =~ 'c$'", `{"regexp":{"s":{"value":".*c"}}}`},
+		{"s !~ 'b'", `{"bool":{"must_not":[{"regexp":{"s":{"value":".*b.*"}}}]}}`},
+		{"s =~ 'a|b'", `{"regexp":{"s":{"value":".*(a|b).*"}}}`},
+		{"s =~ '^(a|b)$'", `{"regexp":{"s":{"value":"(a|b)"}}}`},
+		{"s =~ '^a(b|c)'", `{"regexp":{"s":{"value":"a(b|c).*"}}}`},
+		{"s =~ 'x@y~z'", `{"regexp":{"s":{"value":".*x\\@y\\~z.*"}}}`},
+		{"s =~ '\\\\.com$'", `{"regexp":{"s":{"value":".*\\.com"}}}`},
+		{"s =~ '\\\\$'", `{"regexp":{"s":{"value":".*\\$.*"}}}`},
+		{"s =~ '[]^$|]'", `{"regexp":{"s":{"value":".*[]^$|].*"}}}`},
+		{"s =~ '[^a]+'", `{"regexp":{"s":{"value":".*[^a]+.*"}}}`},
+		{"a == nil", `{"bool":{"must_not":[{"exists":{"field":"a"}}]}}`},
+		{"a != 3", `{"bool":{"must_not":[{"term":{"a":3}}]}}`},
+		{"true", `{"match_all":{}}`},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpressionWithOptions(c.expression, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		query, err := expression.TElasticsearchQuery()
+		if err != nil {
+			test.Errorf("%s: %v", c.expression, err)
+			continue
+		}
+		if encoded, _ := json.Marshal(query); string(encoded) != c.expected {
+			test.Errorf("%s: expected %s, got %s", c.expression, c.expected, encoded)
+		}
+	}
+}
+
+func TestElasticsearchQueryRejectsUntranslatable(test *testing.T) {
+
+	for _, text := range []string{
+		"a + 1 > 2", "a > b", "len(s) > 1", "a > nil",
+		"s =~ '^a|b'", "s =~ 'a|b$'", "s =~ 'a^b'", "s =~ '(a$)|b'",
+		"s =~ '\\\\d+'", "s =~ '\\\\w'", "s =~ '\\\\bword'", "s =~ '(?i)a'", "s =~ '(?:a)b'", "s =~ '[[:alpha:]]'",
+	} {
+
+		expression, err := TNewEvaluableExpressionWithOptions(text, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+		_, err = expression.TElasticsearchQuery()
+		if err == nil {
+			test.Errorf("%s: expected not to translate", text)
+		}
+	}
+}
+
+/*
+Whether [document] matches [query], as Elasticsearch would match it - for the queries TElasticsearchQuery produces.
+Fields which are nil are missing.
+*/
+func matchesElasticsearchQuery(query map[string]interface{}, document map[string]interface{}) bool {
+
+	for kind, body := range query {
+
+		var matched bool
+		switch kind {
+		case "match_all":
+			matched = true
+
+		case "bool":
+			clauses := body.(map[string]interface{})
+			matched = true
+
+			for _, clause := range asClauses(clauses["filter"]) {
+				matched = matched && matchesElasticsearchQuery(clause, document)
+			}
+			for _, clause := range asClauses(clauses["must_not"]) {
+				matched = matched && !matchesElasticsearchQuery(clause, document)
+			}
+			if should := asClauses(clauses["should"]); len(should) > 0 {
+				count := 0
+				for _, clause := range should {
+					if matchesElasticsearchQuery(clause, document) {
+						count++
+					}
+				}
+				matched = matched && count >= clauses["minimum_should_match"].(int)
+			}
+
+		case "exists":
+			matched = document[body.(map[string]interface{})["field"].(string)] != nil
+
+		default:
+			for field, condition := range body.(map[string]interface{}) {
+				matched = matchesElasticsearchField(kind, document[field], condition)
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesElasticsearchField(kind string, value interface{}, condition interface{}) bool {
+
+	if value == nil {
+		return false
+	}
+
+	switch kind {
+	case "term":
+		return value == condition
+
+	case "terms":
+		for _, element := range condition.([]interface{}) {
+			if value == element {
+				return true
+			}
+		}
+		return false
+
+	case "range":
+		for comparator, operand := range condition.(map[string]interface{}) {
+
+			order, comparable := compareFilterValues(value, operand)
+			if !comparable || !map[string]bool{"gt": order > 0, "lt": order < 0, "gte": order >= 0, "lte": order <= 0}[comparator] {
+				return false
+			}
+		}
+		return true
+
+	case "regexp":
+		// regexp queries match whole values.
+		text, isText := value.(string)
+		pattern := condition.(map[string]interface{})["value"].(string)
+		return isText && regexp.MustCompile("^(?:"+pattern+")$").MatchString(text)
+	}
+	panic(fmt.Sprintf("unexpected query %s", kind))
+}
+
+func asClauses(clauses interface{}) []map[string]interface{} {
+
+	var ret []map[string]interface{}
+	list, _ := clauses.([]interface{})
+	for _, clause := range list {
+		ret = append(ret, clause.(map[string]interface{}))
+	}
+	return ret
+}
+
+/*
+Translates generated expressions into queries, and checks that each query matches exactly the documents
+the expression is true for - wherever the expression evaluates without error.
+*/
+func TestGeneratedElasticsearchQueriesMatchEvaluation(test *testing.T) {
+
+	inputs := conformanceInputs(50)
+	random := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+
+		text := generateFilterExpression(random, 3)
+		expression, err := TNewEvaluableExpressionWithOptions(text, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+		query, err := expression.TElasticsearchQuery()
+		if err != nil {
+			test.Errorf("%s: %v", text, err)
+			continue
+		}
+
+		for _, parameters := range inputs {
+
+			result, err := expression.TEvaluate(parameters)
+			if err != nil {
+				continue
+			}
+			if matchesElasticsearchQuery(query, filterDocument(parameters)) != result {
+				encoded, _ := json.Marshal(query)
+				test.Errorf("%s: translated into %s, which does not match as it evaluates (%v) given %v", text, encoded, result, parameters)
+				break
+			}
+		}
+	}
+}
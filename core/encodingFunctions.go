@@ -0,0 +1,89 @@
+package core
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"net/url"
+)
+
+/*
+The optional hashing and encoding functions, by their unqualified names. Each takes a single string.
+*/
+var encodingFunctions = map[string]func(value string) (interface{}, error){
+	"md5": func(value string) (interface{}, error) {
+		sum := md5.Sum([]byte(value))
+		return hex.EncodeToString(sum[:]), nil
+	},
+	"sha1": func(value string) (interface{}, error) {
+		sum := sha1.Sum([]byte(value))
+		return hex.EncodeToString(sum[:]), nil
+	},
+	"sha256": func(value string) (interface{}, error) {
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:]), nil
+	},
+	"crc32": func(value string) (interface{}, error) {
+		return float64(crc32.ChecksumIEEE([]byte(value))), nil
+	},
+	"base64encode": func(value string) (interface{}, error) {
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	},
+	"base64decode": func(value string) (interface{}, error) {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(decoded), nil
+	},
+	"urlencode": func(value string) (interface{}, error) {
+		return url.QueryEscape(value), nil
+	},
+}
+
+/*
+Returns the optional hashing and encoding functions named under [namespace], such as "hash.sha256",
+or unqualified if [namespace] is empty. All are pure, and take a single string:
+md5, sha1, and sha256 return the digest in lowercase hex; crc32 returns the IEEE checksum as a number,
+so that users can be bucketed with `crc32(id) % 100 < 10`; base64encode and base64decode use standard padded base64;
+and urlencode escapes the string for use in a URL query.
+*/
+func TEncodingFunctions(namespace string) map[string]tExpressionFunction {
+
+	ret := make(map[string]tExpressionFunction, len(encodingFunctions))
+	for name, encode := range encodingFunctions {
+		qualified := qualifyName(namespace, name)
+		ret[qualified] = makeEncodingFunction(qualified, encode)
+	}
+	return ret
+}
+
+/*
+Registers the functions returned by TEncodingFunctions as pure, for every expression created after this call.
+*/
+func TRegisterEncodingFunctions(namespace string) {
+
+	for name, function := range TEncodingFunctions(namespace) {
+		TRegisterPureFunction(name, function)
+	}
+}
+
+func makeEncodingFunction(qualified string, encode func(string) (interface{}, error)) tExpressionFunction {
+
+	return func(arguments ...interface{}) (interface{}, error) {
+
+		if len(arguments) != 1 || !isString(arguments[0]) {
+			return nil, fmt.Errorf("%s expects a string", qualified)
+		}
+
+		ret, err := encode(arguments[0].(string))
+		if err != nil {
+			return nil, fmt.Errorf("%s failed: %v", qualified, err)
+		}
+		return ret, nil
+	}
+}
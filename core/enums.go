@@ -0,0 +1,102 @@
+package core
+
+import (
+	"fmt"
+)
+
+/*
+Recurses through all stages, replacing string literals which are compared to enum-typed parameters
+with the value that enum name represents.
+Returns an error if any such literal does not name a member of the enum.
+*/
+func resolveEnums(stage *evaluationStage, enums map[string]map[string]interface{}) error {
+
+	var err error
+
+	if stage == nil {
+		return nil
+	}
+
+	switch stage.symbol {
+	case tEQ:
+		fallthrough
+	case tNEQ:
+		err = resolveEnumOperand(stage.leftStage, stage.rightStage, enums)
+		if err != nil {
+			return err
+		}
+		err = resolveEnumOperand(stage.rightStage, stage.leftStage, enums)
+		if err != nil {
+			return err
+		}
+	case tIN:
+		err = resolveEnumOperand(stage.leftStage, stage.rightStage, enums)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = resolveEnums(stage.leftStage, enums)
+	if err != nil {
+		return err
+	}
+	return resolveEnums(stage.rightStage, enums)
+}
+
+/*
+If [parameter] refers to an enum, replaces every string literal in [operand] (which may be a list) with its enum value.
+*/
+func resolveEnumOperand(parameter *evaluationStage, operand *evaluationStage, enums map[string]map[string]interface{}) error {
+
+	if parameter == nil || operand == nil {
+		return nil
+	}
+	if parameter.symbol != tVALUE && parameter.symbol != tACCESS {
+		return nil
+	}
+
+	members, found := enums[parameter.name]
+	if !found {
+		return nil
+	}
+
+	return replaceEnumLiterals(parameter.name, operand, members)
+}
+
+func replaceEnumLiterals(name string, stage *evaluationStage, members map[string]interface{}) error {
+
+	switch stage.symbol {
+	case tNOOP:
+		fallthrough
+	case tSEPARATE:
+		for _, child := range []*evaluationStage{stage.leftStage, stage.rightStage} {
+
+			if child == nil {
+				continue
+			}
+
+			err := replaceEnumLiterals(name, child, members)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if stage.symbol != tLITERAL {
+		return nil
+	}
+
+	literal, err := stage.operator(nil, nil, nil)
+	if err != nil || !isString(literal) {
+		return nil
+	}
+
+	value, found := members[literal.(string)]
+	if !found {
+		return fmt.Errorf("Unknown enum value '%s' for parameter '%s'", literal, name)
+	}
+
+	stage.operator = makeLiteralStage(castToFloat64(value))
+	return nil
+}
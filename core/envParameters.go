@@ -0,0 +1,54 @@
+package core
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+tEnvParameters resolves parameters from the environment of the current process.
+*/
+type tEnvParameters struct {
+	prefix string
+	coerce bool
+}
+
+/*
+Returns tParameters which read environment variables named [prefix] followed by the parameter name.
+If no variable has that exact name, the upper-cased name is tried as well, so that `replicas` can read `APP_REPLICAS`.
+If [coerce] is set, values which look like numbers or booleans are converted to float64 or bool; otherwise all values are strings.
+*/
+func TNewEnvParameters(prefix string, coerce bool) tParameters {
+	return tEnvParameters{prefix: prefix, coerce: coerce}
+}
+
+func (p tEnvParameters) tGet(name string) (interface{}, error) {
+
+	value, found := os.LookupEnv(p.prefix + name)
+	if !found {
+		value, found = os.LookupEnv(strings.ToUpper(p.prefix + name))
+	}
+	if !found {
+		return nil, tMissingParameterError{name}
+	}
+
+	if p.coerce {
+		return coerceEnvValue(value), nil
+	}
+	return value, nil
+}
+
+func coerceEnvValue(value string) interface{} {
+
+	if value == "true" || value == "false" {
+		return value == "true"
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err == nil {
+		return number
+	}
+
+	return value
+}
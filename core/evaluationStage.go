@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 const (
@@ -24,6 +25,15 @@ type stageCombinedTypeCheck func(left interface{}, right interface{}) bool
 type evaluationStage struct {
 	symbol tOperatorSymbol
 
+	// the parameter, function, or accessor name this stage refers to, if any.
+	name string
+
+	// for accessors, the parameter name followed by each field or method name. The parameter name may contain dots.
+	path []string
+
+	// whether this is a call to a pure function, which may be evaluated at plan time if its arguments are literals.
+	pure bool
+
 	leftStage, rightStage *evaluationStage
 
 	// the operation that will be used to evaluate this stage (such as adding [left] to [right] and return the result)
@@ -40,6 +50,37 @@ type evaluationStage struct {
 
 	// regardless of which type check is used, this string format will be used as the error message for type errors
 	typeErrorFormat string
+
+	// whether this logical stage converts its operands to bools first, see TFeatureTruthiness.
+	truthy bool
+
+	// whether this stage converts fmt.Stringer operands to strings first, see TExpressionOptions.CoerceStringers.
+	stringers bool
+
+	// for function calls, whether a built-in was called with exactly one argument, which it is given as it is,
+	// and the offset of the call in runes.
+	single   bool
+	position int
+
+	// for literals folded from other stages when planned, the text of what was folded.
+	folded string
+
+	// for literals which are not written as their value renders, such as times, the literal as it is written.
+	source string
+
+	// if not zero, the result of this stage is memoized in this slot (counting from 1) of each evaluation,
+	// since the same subexpression appears elsewhere in the expression. See eliminateCommonSubexpressions.
+	memo int
+
+	// whether this stage reads a sensitive parameter, or is derived from one, so that its values are masked. See markSensitiveStages.
+	sensitive bool
+
+	// if set, evaluates this stage in place of interpreting it. See lowerStages.
+	native nativeStage
+
+	// if set, initializes ahead of time whatever [operator] would otherwise initialize on first use.
+	// Returns false if that had already been done.
+	warm func() bool
 }
 
 var (
@@ -57,11 +98,22 @@ func (t *evaluationStage) swapWith(other *evaluationStage) {
 func (t *evaluationStage) setToNonStage(other evaluationStage) {
 
 	t.symbol = other.symbol
+	t.name = other.name
+	t.path = other.path
+	t.pure = other.pure
 	t.operator = other.operator
 	t.leftTypeCheck = other.leftTypeCheck
 	t.rightTypeCheck = other.rightTypeCheck
 	t.typeCheck = other.typeCheck
 	t.typeErrorFormat = other.typeErrorFormat
+	t.truthy = other.truthy
+	t.stringers = other.stringers
+	t.single = other.single
+	t.position = other.position
+	t.folded = other.folded
+	t.source = other.source
+	t.sensitive = other.sensitive
+	t.warm = other.warm
 }
 
 func (t *evaluationStage) isShortCircuitable() bool {
@@ -88,8 +140,27 @@ func noopStageRight(left interface{}, right interface{}, parameters tParameters)
 
 func addStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
 
+	leftList := reflect.ValueOf(left)
+	rightList := reflect.ValueOf(right)
+
+	// array concat if both are arrays
+	if isList(leftList) && isList(rightList) {
+
+		ret := make([]interface{}, 0, leftList.Len()+rightList.Len())
+		for _, list := range []reflect.Value{leftList, rightList} {
+			for i := 0; i < list.Len(); i++ {
+				ret = append(ret, castToFloat64(list.Index(i).Interface()))
+			}
+		}
+		return ret, nil
+	}
+
 	// string concat if either are strings
 	if isString(left) || isString(right) {
+
+		if isList(leftList) || isList(rightList) {
+			return nil, errors.New("Cannot add an array and a string with '+', only two arrays can be concatenated")
+		}
 		return fmt.Sprintf("%v%v", left, right), nil
 	}
 
@@ -107,37 +178,70 @@ func divideStage(left interface{}, right interface{}, parameters tParameters) (i
 func exponentStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
 	return math.Pow(left.(float64), right.(float64)), nil
 }
+func floorDivideStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+	return math.Floor(left.(float64) / right.(float64)), nil
+}
 func modulusStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
 	return math.Mod(left.(float64), right.(float64)), nil
 }
+
+/*
+Like modulusStage, but the result is never negative, whatever the signs of the operands - so that `-7 % 3` is 2, not -1.
+*/
+func euclideanModulusStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+	ret := math.Mod(left.(float64), right.(float64))
+	if ret < 0 {
+		ret += math.Abs(right.(float64))
+	}
+	return ret, nil
+}
 func gteStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+	if isComparer(left) || isComparer(right) {
+		return delegateComparison(left, right, func(comparison int) bool { return comparison >= 0 })
+	}
 	if isString(left) && isString(right) {
 		return boolIface(left.(string) >= right.(string)), nil
 	}
 	return boolIface(left.(float64) >= right.(float64)), nil
 }
 func gtStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+	if isComparer(left) || isComparer(right) {
+		return delegateComparison(left, right, func(comparison int) bool { return comparison > 0 })
+	}
 	if isString(left) && isString(right) {
 		return boolIface(left.(string) > right.(string)), nil
 	}
 	return boolIface(left.(float64) > right.(float64)), nil
 }
 func lteStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+	if isComparer(left) || isComparer(right) {
+		return delegateComparison(left, right, func(comparison int) bool { return comparison <= 0 })
+	}
 	if isString(left) && isString(right) {
 		return boolIface(left.(string) <= right.(string)), nil
 	}
 	return boolIface(left.(float64) <= right.(float64)), nil
 }
 func ltStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+	if isComparer(left) || isComparer(right) {
+		return delegateComparison(left, right, func(comparison int) bool { return comparison < 0 })
+	}
 	if isString(left) && isString(right) {
 		return boolIface(left.(string) < right.(string)), nil
 	}
 	return boolIface(left.(float64) < right.(float64)), nil
 }
 func equalStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+	if equal, handled := customEqual(left, right); handled {
+		return boolIface(equal), nil
+	}
 	return boolIface(reflect.DeepEqual(left, right)), nil
 }
 func notEqualStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+	if equal, handled := customEqual(left, right); handled {
+		return boolIface(!equal), nil
+	}
 	return boolIface(!reflect.DeepEqual(left, right)), nil
 }
 func andStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
@@ -146,15 +250,15 @@ func andStage(left interface{}, right interface{}, parameters tParameters) (inte
 func orStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
 	return boolIface(left.(bool) || right.(bool)), nil
 }
+func xorStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+	return boolIface(left.(bool) != right.(bool)), nil
+}
 func negateStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
 	return -right.(float64), nil
 }
 func invertStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
 	return boolIface(!right.(bool)), nil
 }
-func bitwiseNotStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
-	return float64(^int64(right.(float64))), nil
-}
 func ternaryIfStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
 	if left.(bool) {
 		return right, nil
@@ -196,22 +300,6 @@ func notRegexStage(left interface{}, right interface{}, parameters tParameters)
 	return !(ret.(bool)), nil
 }
 
-func bitwiseOrStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
-	return float64(int64(left.(float64)) | int64(right.(float64))), nil
-}
-func bitwiseAndStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
-	return float64(int64(left.(float64)) & int64(right.(float64))), nil
-}
-func bitwiseXORStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
-	return float64(int64(left.(float64)) ^ int64(right.(float64))), nil
-}
-func leftShiftStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
-	return float64(uint64(left.(float64)) << uint64(right.(float64))), nil
-}
-func rightShiftStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
-	return float64(uint64(left.(float64)) >> uint64(right.(float64))), nil
-}
-
 func makeParameterStage(parameterName string) evaluationOperator {
 
 	return func(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
@@ -230,10 +318,18 @@ func makeLiteralStage(literal interface{}) evaluationOperator {
 	}
 }
 
-func makeFunctionStage(function tExpressionFunction) evaluationOperator {
+/*
+Calls [function] with the arguments evaluated into [right]. If [single], the built-in function was called with exactly one argument,
+which is passed as it is - even if it is nil, or an array - rather than spread into the function's arguments.
+*/
+func makeFunctionStage(function tExpressionFunction, single bool) evaluationOperator {
 
 	return func(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
 
+		if single {
+			return function(right)
+		}
+
 		if right == nil {
 			return function()
 		}
@@ -289,15 +385,37 @@ func typeConvertParams(method reflect.Value, params []reflect.Value) ([]reflect.
 	return params, nil
 }
 
-func makeAccessorStage(pair []string) evaluationOperator {
+func makeAccessorStage(pair []string, options TExpressionOptions) evaluationOperator {
+
+	access := makeExactAccessorStage(pair, options)
+
+	return func(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+
+		value, err := access(left, right, parameters)
+		if err != nil {
+			return value, err
+		}
+		return castToFloat64(value), nil
+	}
+}
+
+/*
+Like makeAccessorStage, but the value accessed is returned as-is, rather than numbers being converted to float64.
+*/
+func makeExactAccessorStage(pair []string, options TExpressionOptions) evaluationOperator {
 
 	reconstructed := strings.Join(pair, ".")
 
+	// how each step of the path resolves, by the type of struct it is applied to.
+	// Holds reflect.Type -> *accessorMember.
+	members := make([]sync.Map, len(pair))
+
 	return func(left interface{}, right interface{}, parameters tParameters) (ret interface{}, err error) {
 
 		var params []reflect.Value
 
-		value, err := parameters.tGet(pair[0])
+		// the parameter is reached into as it was given, rather than unwrapped, so that wrappers' own fields can be accessed.
+		value, err := unsanitized(parameters).tGet(pair[0])
 		if err != nil {
 			return nil, err
 		}
@@ -307,8 +425,7 @@ func makeAccessorStage(pair []string) evaluationOperator {
 		// therefore every call to an accessor sets up a defer that tries to recover from panics, converting them to errors.
 		defer func() {
 			if r := recover(); r != nil {
-				errorMsg := fmt.Sprintf("Failed to access '%s': %v", reconstructed, r.(string))
-				err = errors.New(errorMsg)
+				err = newPanicError(r, fmt.Sprintf("Failed to access '%s': %v", reconstructed, r))
 				ret = nil
 			}
 		}()
@@ -329,20 +446,24 @@ func makeAccessorStage(pair []string) evaluationOperator {
 				return nil, errors.New("Unable to access '" + pair[i] + "', '" + pair[i-1] + "' is not a struct")
 			}
 
-			field := coreValue.FieldByName(pair[i])
-			if field != (reflect.Value{}) {
+			member := resolveAccessorMember(&members[i], coreValue.Type(), options, pair[i])
+
+			if member.field != nil {
+				field := coreValue.FieldByIndex(member.field)
+				if !field.CanInterface() {
+					return nil, errors.New("Unable to access unexported field '" + pair[i] + "' on parameter '" + pair[i-1] + "'")
+				}
 				value = field.Interface()
 				continue
 			}
 
-			method := coreValue.MethodByName(pair[i])
-			if method == (reflect.Value{}) {
-				if corePtrVal.IsValid() {
-					method = corePtrVal.MethodByName(pair[i])
-				}
-				if method == (reflect.Value{}) {
-					return nil, errors.New("No method or field '" + pair[i] + "' present on parameter '" + pair[i-1] + "'")
-				}
+			var method reflect.Value
+			if member.method >= 0 {
+				method = coreValue.Method(member.method)
+			} else if member.pointerMethod >= 0 && corePtrVal.IsValid() {
+				method = corePtrVal.Method(member.pointerMethod)
+			} else {
+				return nil, errors.New("No method or field '" + pair[i] + "' present on parameter '" + pair[i-1] + "'")
 			}
 
 			switch right.(type) {
@@ -399,18 +520,137 @@ func makeAccessorStage(pair []string) evaluationOperator {
 			return nil, errors.New("Method call '" + pair[0] + "." + pair[1] + "' did not return either one value, or a value and an error. Cannot interpret meaning.")
 		}
 
-		value = castToFloat64(value)
+		value, err = unwrapValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read '%s': %w", reconstructed, err)
+		}
 		return value, nil
 	}
 }
 
+/*
+How one step of an accessor path, such as "Name" in `user.Name`, resolves against a particular struct type.
+*/
+type accessorMember struct {
+
+	// index of the field, or nil if the step is not a field.
+	field []int
+
+	// indices of the method on the struct and on a pointer to it, or -1 if there is no such method.
+	method        int
+	pointerMethod int
+}
+
+/*
+Returns how [name] resolves against [structType], from [cache] if it has been resolved against that type before.
+*/
+func resolveAccessorMember(cache *sync.Map, structType reflect.Type, options TExpressionOptions, name string) *accessorMember {
+
+	cached, found := cache.Load(structType)
+	if found {
+		return cached.(*accessorMember)
+	}
+
+	member := &accessorMember{method: -1, pointerMethod: -1}
+
+	member.field = findTaggedField(structType, options.TagName, name)
+	if member.field == nil {
+		field, found := structType.FieldByName(name)
+		if found {
+			member.field = field.Index
+		}
+	}
+
+	if member.field == nil {
+		member.method = findMethod(structType, name)
+		member.pointerMethod = findMethod(reflect.PointerTo(structType), name)
+	}
+
+	// exact names win; only if there are none is case ignored.
+	if options.CaseInsensitiveNames && member.field == nil && member.method < 0 && member.pointerMethod < 0 {
+
+		field, found := structType.FieldByNameFunc(func(candidate string) bool {
+			return strings.EqualFold(candidate, name)
+		})
+		if found {
+			member.field = field.Index
+		} else {
+			member.method = findMethodFold(structType, name)
+			member.pointerMethod = findMethodFold(reflect.PointerTo(structType), name)
+		}
+	}
+
+	// concurrent evaluations may resolve the same type at once; they produce identical results, so either may win.
+	cache.Store(structType, member)
+	return member
+}
+
+/*
+Returns the index of the method of [methodType] named [name], or -1 if there is none.
+*/
+func findMethod(methodType reflect.Type, name string) int {
+
+	method, found := methodType.MethodByName(name)
+	if !found {
+		return -1
+	}
+	return method.Index
+}
+
+/*
+Like findMethod, but ignores case.
+*/
+func findMethodFold(methodType reflect.Type, name string) int {
+
+	for i := 0; i < methodType.NumMethod(); i++ {
+		if strings.EqualFold(methodType.Method(i).Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+Returns the index of the exported field of [structType] whose [tagName] tag names it [name], or nil if there is none.
+*/
+func findTaggedField(structType reflect.Type, tagName string, name string) []int {
+
+	if tagName == "" {
+		return nil
+	}
+
+	for _, field := range reflect.VisibleFields(structType) {
+
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, found := field.Tag.Lookup(tagName)
+		if !found {
+			continue
+		}
+
+		tag, _, _ = strings.Cut(tag, ",")
+		if tag == name {
+			return field.Index
+		}
+	}
+
+	return nil
+}
+
+/*
+Appends [right] to the list built by the separators before it, on the left.
+Lists are copied as they are appended to, since the list on the left may be a literal shared by every evaluation.
+*/
 func separatorStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
 
 	var ret []interface{}
 
 	switch left.(type) {
 	case []interface{}:
-		ret = append(left.([]interface{}), right)
+		list := left.([]interface{})
+		ret = append(list[:len(list):len(list)], right)
 	default:
 		ret = []interface{}{left, right}
 	}
@@ -418,9 +658,31 @@ func separatorStage(left interface{}, right interface{}, parameters tParameters)
 	return ret, nil
 }
 
+/*
+Begins a list with [left] and [right], as the first separator in a list does - even if [left] is itself an array.
+*/
+func firstSeparatorStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+	return []interface{}{left, right}, nil
+}
+
 func inStage(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
 
 	for _, value := range right.([]interface{}) {
+
+		if equal, handled := customEqual(left, value); handled {
+			if equal {
+				return true, nil
+			}
+			continue
+		}
+
+		// values which cannot be compared with == (such as slices) would panic, so are compared as `==` compares them.
+		if !isComparable(left) || !isComparable(value) {
+			if reflect.DeepEqual(left, value) {
+				return true, nil
+			}
+			continue
+		}
 		if left == value {
 			return true, nil
 		}
@@ -467,14 +729,17 @@ func isFloat64(value interface{}) bool {
 }
 
 /*
-Addition usually means between numbers, but can also mean string concat.
-tString concat needs one (or both) of the sides to be a string.
+Addition usually means between numbers, but can also mean string concat, or array concat.
+tString concat needs one (or both) of the sides to be a string; array concat needs both sides to be arrays.
 */
 func additionTypeCheck(left interface{}, right interface{}) bool {
 
 	if isFloat64(left) && isFloat64(right) {
 		return true
 	}
+	if isList(reflect.ValueOf(left)) && isList(reflect.ValueOf(right)) {
+		return true
+	}
 	if !isString(left) && !isString(right) {
 		return false
 	}
@@ -487,6 +752,10 @@ but never between the two.
 */
 func comparatorTypeCheck(left interface{}, right interface{}) bool {
 
+	if isComparer(left) || isComparer(right) {
+		return true
+	}
+
 	if isFloat64(left) && isFloat64(right) {
 		return true
 	}
@@ -514,3 +783,41 @@ func boolIface(b bool) interface{} {
 	}
 	return _false
 }
+
+/*
+Returns a short, human-readable description of this stage alone (not its children),
+such as "&&", "[foo]", "strlen()", or a literal value.
+*/
+func (t *evaluationStage) label() string {
+
+	switch t.symbol {
+	case tLITERAL:
+		value, err := t.operator(nil, nil, nil)
+		if err != nil {
+			return "literal"
+		}
+		if isString(value) {
+			return fmt.Sprintf("'%v'", value)
+		}
+		if value == nil {
+			return "nil"
+		}
+		rendered, found := stringifyValue(value)
+		if found {
+			return rendered
+		}
+		return fmt.Sprintf("%v", value)
+	case tVALUE:
+		return "[" + t.name + "]"
+	case tFUNCTIONAL:
+		return t.name + "()"
+	case tACCESS:
+		return t.name
+	case tNOOP:
+		return "()"
+	case tSEPARATE:
+		return ","
+	}
+
+	return t.symbol.String()
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+/*
+Returns a hash of this expression's normalized form, so that duplicate rules can be found by grouping on it.
+Expressions which differ only in spacing, redundant parentheses, the order of operands to commutative operators
+(such as `a && b` and `b && a`), or the direction of comparisons (`a > b` and `b < a`) have the same fingerprint.
+*/
+func (t tEvaluableExpression) TFingerprint() string {
+
+	sum := sha256.Sum256([]byte(normalizeStage(t.evaluationStages)))
+	return hex.EncodeToString(sum[:16])
+}
+
+/*
+Returns true if this expression and [other] have the same normalized form, as described by TFingerprint.
+Unlike comparing fingerprints, this cannot be fooled by a hash collision.
+*/
+func (t tEvaluableExpression) TEquivalent(other *tEvaluableExpression) bool {
+
+	if other == nil {
+		return false
+	}
+	return normalizeStage(t.evaluationStages) == normalizeStage(other.evaluationStages)
+}
+
+/*
+Renders the given stage tree as a prefix-notation string in which equivalent trees render identically.
+*/
+func normalizeStage(stage *evaluationStage) string {
+
+	if stage == nil {
+		return "()"
+	}
+
+	switch stage.symbol {
+	case tLITERAL:
+		value, err := stage.operator(nil, nil, nil)
+		if err != nil {
+			return "nil"
+		}
+		return renderLiteral(value)
+
+	case tVALUE:
+		return renderName(stage.name)
+
+	case tACCESS:
+		if stage.rightStage == nil {
+			return renderName(stage.name)
+		}
+		return renderName(stage.name) + "(" + strings.Join(normalizeArguments(stage.rightStage), " ") + ")"
+
+	case tFUNCTIONAL:
+		return stage.name + "(" + strings.Join(normalizeArguments(stage.rightStage), " ") + ")"
+
+	case tNOOP:
+		return normalizeStage(stage.rightStage)
+
+	case tNEGATE, tINVERT, tBITWISE_NOT:
+		return "(" + stage.symbol.String() + " " + normalizeStage(stage.rightStage) + ")"
+
+	case tGT:
+		return "(< " + normalizeStage(stage.rightStage) + " " + normalizeStage(stage.leftStage) + ")"
+	case tGTE:
+		return "(<= " + normalizeStage(stage.rightStage) + " " + normalizeStage(stage.leftStage) + ")"
+
+	case tEQ, tNEQ:
+		return normalizeCommutative(stage.symbol, []string{normalizeStage(stage.leftStage), normalizeStage(stage.rightStage)})
+
+	case tAND, tOR, tXOR, tMULTIPLY, tBITWISE_AND, tBITWISE_OR, tBITWISE_XOR:
+		return normalizeCommutative(stage.symbol, normalizeChain(stage, stage.symbol, nil))
+
+	case tPLUS:
+		// `+` also concatenates strings, which does not commute.
+		if inferStageType(stage, nil) == TNumberType {
+			return normalizeCommutative(stage.symbol, normalizeChain(stage, stage.symbol, nil))
+		}
+
+	case tIN:
+		return "(in " + normalizeStage(stage.leftStage) + " " + normalizeSet(stage.rightStage) + ")"
+	}
+
+	return "(" + stage.symbol.String() + " " + normalizeStage(stage.leftStage) + " " + normalizeStage(stage.rightStage) + ")"
+}
+
+func normalizeCommutative(symbol tOperatorSymbol, operands []string) string {
+
+	sort.Strings(operands)
+	return "(" + symbol.String() + " " + strings.Join(operands, " ") + ")"
+}
+
+/*
+Flattens a chain of the same associative operator, such as `a && (b && c)`, into its operands.
+*/
+func normalizeChain(stage *evaluationStage, symbol tOperatorSymbol, operands []string) []string {
+
+	for stage.symbol == tNOOP && stage.rightStage != nil {
+		stage = stage.rightStage
+	}
+
+	if stage.symbol != symbol {
+		return append(operands, normalizeStage(stage))
+	}
+
+	operands = normalizeChain(stage.leftStage, symbol, operands)
+	return normalizeChain(stage.rightStage, symbol, operands)
+}
+
+func normalizeArguments(stage *evaluationStage) []string {
+
+	for stage != nil && stage.symbol == tNOOP {
+		stage = stage.rightStage
+	}
+
+	if stage == nil {
+		return nil
+	}
+	if stage.symbol != tSEPARATE {
+		return []string{normalizeStage(stage)}
+	}
+	return append(normalizeArguments(stage.leftStage), normalizeArguments(stage.rightStage)...)
+}
+
+/*
+Normalizes the right-hand side of `in`, whose element order does not matter if it is a literal list.
+*/
+func normalizeSet(stage *evaluationStage) string {
+
+	elements := normalizeArguments(stage)
+	if len(elements) == 1 && stage.symbol == tLITERAL {
+		value, err := stage.operator(nil, nil, nil)
+		list, isList := value.([]interface{})
+		if err == nil && isList {
+			elements = make([]string, len(list))
+			for i, element := range list {
+				elements[i] = renderLiteral(element)
+			}
+		}
+	}
+
+	sort.Strings(elements)
+	return "(" + strings.Join(elements, " ") + ")"
+}
@@ -0,0 +1,66 @@
+package core
+
+/*
+Renders this expression in canonical form, as gofmt does Go: with normalized spacing, strings quoted with single quotes,
+and parentheses only where they change how the expression is evaluated - except that `&&` is parenthesized
+within `||` and `xor`, so that its precedence can be seen. Unlike String, literals are left as written, rather than folded.
+Interpolated strings and `is null` are written as the concatenations and comparisons they stand for.
+*/
+func (t tEvaluableExpression) TFormat() string {
+
+	stage := t.planSourceStages()
+	if stage == nil {
+		return ""
+	}
+	return renderStage(normalizeParentheses(stage, nil))
+}
+
+/*
+Plans the stages of this expression as it is written, without folding literals or any other changes planning makes
+for evaluation, so that they can be rendered as the expression (or its equivalent in another language).
+*/
+func (t tEvaluableExpression) planSourceStages() *evaluationStage {
+
+	stream := newTokenStream(t.tokens)
+	stream.options = t.options
+
+	// the expression was planned once already, so cannot fail to be now.
+	stage, err := planTokens(stream)
+	if err != nil || stage == nil {
+		return nil
+	}
+
+	reorderStages(stage)
+	return stage
+}
+
+/*
+Returns [stage], a child of [parent], without the parentheses which renderStage would not need, other than those
+which call a function or contain a list, and with parentheses around `&&` within `||` and `xor`.
+*/
+func normalizeParentheses(stage *evaluationStage, parent *evaluationStage) *evaluationStage {
+
+	if stage == nil {
+		return nil
+	}
+
+	if stage.symbol == tNOOP && !isArgumentList(stage, parent) && stage.rightStage != nil && stage.rightStage.symbol != tSEPARATE {
+		return normalizeParentheses(stage.rightStage, parent)
+	}
+
+	stage.leftStage = normalizeParentheses(stage.leftStage, stage)
+	stage.rightStage = normalizeParentheses(stage.rightStage, stage)
+
+	if stage.symbol == tAND && parent != nil && (parent.symbol == tOR || parent.symbol == tXOR) {
+		return &evaluationStage{
+			symbol:     tNOOP,
+			rightStage: stage,
+			operator:   noopStageRight,
+		}
+	}
+	return stage
+}
+
+func isArgumentList(stage *evaluationStage, parent *evaluationStage) bool {
+	return parent != nil && (parent.symbol == tFUNCTIONAL || parent.symbol == tACCESS) && parent.rightStage == stage
+}
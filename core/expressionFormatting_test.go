@@ -0,0 +1,83 @@
+package core
+
+import (
+	"testing"
+)
+
+/*
+Checks that [original] formats as [formatted], and that what it formats as compiles into an expression
+which evaluates the same given [parameters].
+*/
+func assertFormatRoundTrip(test *testing.T, original string, formatted string, parameters map[string]interface{}) {
+
+	test.Helper()
+
+	expression, err := TNewEvaluableExpression(original)
+	if err != nil {
+		test.Fatalf("%s: %v", original, err)
+	}
+
+	actual := expression.TFormat()
+	if actual != formatted {
+		test.Errorf("%s: expected to format as %s, got %s", original, formatted, actual)
+	}
+
+	reparsed, err := TNewEvaluableExpression(actual)
+	if err != nil {
+		test.Errorf("%s: formatted as %s, which fails to compile: %v", original, actual, err)
+		return
+	}
+
+	expected, expectedErr := expression.TEvaluate(parameters)
+	result, err := reparsed.TEvaluate(parameters)
+	if result != expected || (err == nil) != (expectedErr == nil) {
+		test.Errorf("%s: formatted as %s, which gives %v (%v) rather than %v (%v)", original, actual, result, err, expected, expectedErr)
+	}
+}
+
+func TestFormatQuotesStrings(test *testing.T) {
+
+	parameters := map[string]interface{}{"s": `say "it's"`}
+
+	// either quote character ends a string, so both must be escaped within one.
+	assertFormatRoundTrip(test, `s == "say \"it\'s\""`, `s == 'say \"it\'s\"'`, parameters)
+	assertFormatRoundTrip(test, `s == '\"'`, `s == '\"'`, parameters)
+	assertFormatRoundTrip(test, `s + "\\"`, `s + '\\'`, parameters)
+}
+
+func TestFormatKeepsStringsAndTimesApart(test *testing.T) {
+
+	parameters := map[string]interface{}{"s": "2024-01-01", "t": 1704067200.0}
+
+	// raw strings are never times, so strings which look like times are written raw.
+	assertFormatRoundTrip(test, "s == `2024-01-01`", "s == `2024-01-01`", parameters)
+	assertFormatRoundTrip(test, "t == '2024-01-01'", "t == '2024-01-01T00:00:00Z'", parameters)
+	assertFormatRoundTrip(test, `[a\\b\[c\]] == 1`, `[a\\b\[c\]] == 1`, map[string]interface{}{`a\b[c]`: 1.0})
+}
+
+func TestFormatParenthesizesPrefixOperands(test *testing.T) {
+
+	parameters := map[string]interface{}{"a": 2.0, "flag": true}
+
+	assertFormatRoundTrip(test, "!flag", "!flag", parameters)
+	assertFormatRoundTrip(test, "-(-a)", "-(-a)", parameters)
+	assertFormatRoundTrip(test, "!('a')", "!('a')", parameters)
+	assertFormatRoundTrip(test, "flag ? a : -('2')", "flag ? a : -('2')", parameters)
+}
+
+func TestPlanningOperatorsMissingOperands(test *testing.T) {
+
+	// these are not rejected by the lexer, so must be planned, though they fail to evaluate.
+	for _, text := range []string{"(1 %) 0", "(!)"} {
+
+		expression, err := TNewEvaluableExpression(text)
+		if err != nil {
+			test.Errorf("%s: failed to compile: %v", text, err)
+			continue
+		}
+		_, err = expression.TEvaluate(nil)
+		if err == nil {
+			test.Errorf("%s: expected an error", text)
+		}
+	}
+}
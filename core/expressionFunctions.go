@@ -6,3 +6,23 @@ This method must return an error if, for any reason, it is unable to produce exa
 An error returned will halt execution of the expression.
 */
 type tExpressionFunction func(arguments ...interface{}) (interface{}, error)
+
+/*
+TExpressionFunction allows packages outside of core to build function maps.
+*/
+type TExpressionFunction = tExpressionFunction
+
+/*
+Pairs a tExpressionFunction with the name it was registered under, so that planned stages can refer to the function by name.
+*/
+type tNamedFunction struct {
+	name     string
+	function tExpressionFunction
+	pure     bool
+
+	// whether the function is the built-in of its name, rather than one which overrides it.
+	builtin bool
+
+	// the offset, in runes, of the call in the expression.
+	position int
+}
@@ -0,0 +1,226 @@
+package core
+
+/*
+TExpressionOptions changes how an expression is parsed and planned.
+The zero value gives the default behavior.
+*/
+type TExpressionOptions struct {
+
+	/*
+		If set, accessors first resolve field names using this struct tag (such as "json" or "geval"),
+		falling back to exported field names if no tagged field matches.
+		This also allows accessors to use names that begin with a lowercase letter.
+	*/
+	TagName string
+
+	/*
+		If set, parameters which cannot be found evaluate to nil instead of aborting evaluation with an error.
+		Such parameters can then be handled by the expression itself, with `??` - or with `== nil`, if NilLiteral is set.
+	*/
+	LenientParameters bool
+
+	/*
+		If set, `nil` is a literal for the nil value, so that parameters can be compared to it, as in `discount == nil`.
+		Otherwise `nil` names a parameter, as it always has; set this only where no parameter is named "nil",
+		since expressions which use one would otherwise silently compare against nil instead.
+		A parameter named "nil" can still be written as `[nil]` either way.
+	*/
+	NilLiteral bool
+
+	/*
+		If set, parameters, and the fields and methods accessed on them, are found regardless of case - so that `UserName`
+		finds a parameter given as "username" - whenever there is no exact match.
+		Only parameters given as maps (including chained ones) can be found this way.
+	*/
+	CaseInsensitiveNames bool
+
+	/*
+		If set, the names of parameters and their fields are normalized by this function when the expression is parsed,
+		and parameter names given at evaluation are normalized by it whenever there is no exact match - so that names
+		spelled with combining marks find those spelled with precomposed letters.
+		Pass norm.NFC.String, from golang.org/x/text/unicode/norm, for NFC normalization.
+		Other options which name parameters, such as Aliases and Enums, should use the normalized names.
+		Only parameters given as maps (including chained ones) are normalized at evaluation.
+	*/
+	NormalizeNames func(string) string
+
+	/*
+		Maps parameter (or accessor) names to the named values of an enum, such as {"status": {"SHIPPED": 2}}.
+		String literals compared to those parameters with `==`, `!=`, or `in` are replaced by the enum value when planned,
+		and names which are not part of the enum are rejected.
+	*/
+	Enums map[string]map[string]interface{}
+
+	/*
+		Maps display names, such as "Order Total", to the parameter or accessor they stand for, such as "order.Total".
+		Display names may be used inside brackets (`[Order Total] > 100`), and are used in place of the names they alias
+		when an expression is rendered with TDisplayString.
+	*/
+	Aliases map[string]string
+
+	/*
+		Names of parameters (or accessors) whose values are sensitive, such as personal data.
+		Wherever the engine would print the value of one of these, or of anything derived from one,
+		such as `lower(ssn)` or `ssn == '123-45-6789'`, as in traces and type errors,
+		it prints "***" instead - or a short hash of the value, if MaskWithHash is set,
+		so that equal values can still be correlated.
+	*/
+	SensitiveParameters []string
+	MaskWithHash        bool
+
+	/*
+		If set, an unoptimized reference plan is kept alongside the optimized one.
+		Should evaluating the optimized plan panic, the evaluation is transparently retried with the reference plan,
+		and the panic is reported to FailoverHandler, if set - otherwise, it is not reported at all.
+	*/
+	Failover        bool
+	FailoverHandler func(expression string, recovered interface{})
+
+	/*
+		Names of functions which are pure: their results depend only on their arguments, and calling them has no side effects.
+		Calls to pure functions whose arguments are all literals are evaluated once, when the expression is planned,
+		unless they return an array or a map - which every evaluation would otherwise share.
+		Functions registered with TRegisterPureFunction are pure unless overridden by a function given to the expression.
+	*/
+	PureFunctions []string
+
+	/*
+		If set, the built-in functions, such as `len` and `now()`, are not defined, so that expressions may only call
+		the functions registered or given to them.
+	*/
+	OmitBuiltinFunctions bool
+
+	/*
+		If set, parsing carries on past invalid tokens instead of stopping at the first,
+		and reports every one together - as a TMultiError of TParseErrors, which give the position of each.
+	*/
+	CollectParseErrors bool
+
+	/*
+		If set, a backslash in a string literal only ever passes the character after it through as-is,
+		as it did before escape sequences such as `\n` and `\u00e9` were supported.
+	*/
+	LegacyStringEscapes bool
+
+	/*
+		If set, expressions may be interpolated into double-quoted strings, as in `"hello ${user.Name}"`,
+		which is the concatenation of the literal text and what each expression gives. `\${` writes a literal `${`.
+		Single-quoted strings are never interpolated, nor is anything while LegacyStringEscapes is set.
+	*/
+	Interpolation bool
+
+	/*
+		If set, numeric literals may be written as is usual in many European locales: with a decimal comma (`1,5`),
+		and with digits grouped by thin spaces (U+2009 or U+202F, as in `1 000 000`). Periods remain decimal marks too.
+		A comma is a decimal mark only when it comes directly between two digits, so values must be separated with a space
+		after the comma - `in (1, 2, 3)` - and a literal with more than one decimal mark, such as `1,2,3`, is rejected.
+	*/
+	LocaleNumbers bool
+
+	/*
+		If set, the words `and`, `or`, and `not` (in any case) may be used in place of `&&`, `||`, and `!`,
+		and `xor` gives the exclusive or of two bools, binding as loosely as `or`.
+		The words are only operators where an operator may come next, so parameters may still be named after them.
+	*/
+	WordOperators bool
+
+	/*
+		If set, `%` gives the Euclidean modulus, which is never negative: `-7 % 3` is 2, as in mathematics,
+		rather than -1 (keeping the sign of the dividend, as math.Mod does).
+	*/
+	EuclideanModulus bool
+
+	/*
+		What `/`, `//`, and `%` give when dividing by zero: by default, +Inf, -Inf, or NaN, as float64 division does.
+		Literal divisions which fail this way are left to fail at evaluation, rather than when planned.
+	*/
+	DivisionByZero TDivisionByZero
+
+	/*
+		What integer operations give when their result does not fit in 64 bits: by default, they wrap around.
+	*/
+	Overflow TOverflow
+
+	/*
+		What `==`, `!=`, `>`, `<`, `>=`, and `<=` give when either operand is NaN: by default, what float64 comparisons give,
+		which is always false except for `!=`. NaN can be tested for directly with the built-in isNaN().
+	*/
+	NaNComparisons TNaNComparison
+
+	/*
+		If set, operands which implement fmt.Stringer, such as a uuid.UUID, are converted to strings with String()
+		wherever strings are accepted: by `+`, the comparators, `=~` and `!~`, and the left side of `in`.
+		Times, and values which are TEqualers or TComparers, are not converted.
+	*/
+	CoerceStringers bool
+
+	/*
+		If set, `==`, `!=`, and `in` compare arrays (of any slice type) and maps by their elements,
+		which are compared as parameters are - so that an []int parameter equals the literal `(1, 2)`.
+		Otherwise, arrays and maps are only equal to those of the same type.
+	*/
+	DeepEquality bool
+
+	/*
+		If set, `/` between two whole numbers gives a whole number, truncated toward zero as integer division is
+		in most languages - `7 / 2` is 3, and `-7 / 2` is -3. Division with any other operand is unchanged.
+	*/
+	IntegerMath bool
+
+	/*
+		Layouts, as given to time.Parse, which string literals are tried against before the standard ones
+		to see if they are dates - such as "02/01/2006" for day-first dates. Dates without a zone are in local time.
+	*/
+	DateFormats []string
+
+	/*
+		If set, operands are not type-checked before being given to operators, as with TEvaluationOptions.SkipTypeChecks,
+		but for every evaluation. Only use this when parameters are known to be of the right types;
+		operators will panic on the wrong ones.
+	*/
+	SkipTypeChecks bool
+
+	/*
+		Operators, functions, and other capabilities which the expression may not use, such as when it was written
+		by an untrusted user. An expression which uses any of them fails to compile.
+	*/
+	Deny TDenyList
+
+	/*
+		If set, the only parameters and functions which the expression may use. An expression which uses any other
+		fails to compile. Unlike Deny, anything not named is refused, so capabilities added later are refused too.
+	*/
+	Allow *TAllowList
+
+	/*
+		If set, conditions - `&&`, `||`, and `!` of comparisons - are compiled into Go closures specialized for comparing
+		float64s and strings, rather than interpreted stage by stage, which evaluates them faster. Results are unchanged.
+		Whenever a Tracer, profiler, or deadline is observing an evaluation, it is interpreted instead.
+		Benchmark evaluation with and without this before relying on it, since it only speeds up those conditions.
+	*/
+	NativeClosures bool
+
+	/*
+		Weights of functions, by name, for TEstimateCost, such as a high weight for a function which makes a network call.
+		Functions not named here are weighted as built-in functions are.
+	*/
+	FunctionCosts map[string]int
+
+	/*
+		The clock read by the built-in now(). If nil, the system clock is read.
+		Give a TFixedClock to test rules which depend on the time.
+	*/
+	Clock TClock
+
+	/*
+		If set, is told of every stage of every evaluation as it is entered and exited,
+		with its operands, result, and how long it took. Tracing slows evaluation, even when the tracer does nothing.
+	*/
+	Tracer TTracer
+
+	/*
+		Turns feature gates, such as TFeatureTruthiness, on or off for this expression only.
+		Features which are not named here take their global state, as set by TSetFeature.
+	*/
+	Features map[string]bool
+}
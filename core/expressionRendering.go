@@ -0,0 +1,426 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/*
+Renders this expression back into text, writing any parameter or accessor which has a display name
+(see TExpressionOptions.Aliases) as that display name instead.
+*/
+func (t tEvaluableExpression) TDisplayString() string {
+
+	displayNames := make(map[string]string, len(t.options.Aliases))
+	for display, name := range t.options.Aliases {
+		displayNames[name] = display
+	}
+
+	return renderTokens(t.tokens, func(token tExpressionToken) (string, bool) {
+
+		name, isName := tokenName(token)
+		if !isName {
+			return "", false
+		}
+
+		display, found := displayNames[name]
+		if found {
+			return "[" + display + "]", true
+		}
+		return "", false
+	})
+}
+
+/*
+Renders this expression with every string, number, time, and pattern literal replaced by a placeholder (`?` or `'?'`),
+so that expressions which embed personal data can be logged and shared. Names, operators, and structure are kept.
+*/
+func (t tEvaluableExpression) TRedact() string {
+
+	return renderTokens(t.tokens, func(token tExpressionToken) (string, bool) {
+
+		switch token.Kind {
+		case tNUMERIC:
+			return "?", true
+		case tSTRING:
+			fallthrough
+		case tTIME:
+			fallthrough
+		case tPATTERN:
+			return "'?'", true
+		}
+		return "", false
+	})
+}
+
+/*
+Renders the given [tokens] as expression text, with normalized spacing.
+[override], if not nil, may render any token differently by returning true along with that token's text.
+*/
+func renderTokens(tokens []tExpressionToken, override func(tExpressionToken) (string, bool)) string {
+
+	var buffer bytes.Buffer
+	var previous tExpressionToken
+
+	for index, token := range tokens {
+
+		if index > 0 && needsSpaceBetween(previous, token) {
+			buffer.WriteString(" ")
+		}
+
+		var text string
+		var overridden bool
+
+		if override != nil {
+			text, overridden = override(token)
+		}
+		if !overridden {
+			text = renderToken(token)
+		}
+
+		buffer.WriteString(text)
+		previous = token
+	}
+
+	return buffer.String()
+}
+
+func renderToken(token tExpressionToken) string {
+
+	switch token.Kind {
+	case tVARIABLE:
+		return renderName(token.Value.(string))
+	case tACCESSOR:
+		return renderPath(token.Value.([]string))
+	case tFUNCTION:
+		return token.Value.(tNamedFunction).name
+	case tNUMERIC:
+		return renderLiteral(token.Value)
+	case tBOOLEAN:
+		return strconv.FormatBool(token.Value.(bool))
+	case tNIL:
+		return "nil"
+	case tSTRING:
+		return quoteString(token.Value.(string))
+	case tPATTERN:
+		return quoteString(token.Value.(*regexp.Regexp).String())
+	case tTIME:
+		return quoteTime(token.Value.(time.Time))
+	case tCLAUSE:
+		return "("
+	case tCLAUSE_CLOSE:
+		return ")"
+	}
+
+	return fmt.Sprintf("%v", token.Value)
+}
+
+/*
+Returns the parameter or accessor name referred to by [token], if it refers to one.
+*/
+func tokenName(token tExpressionToken) (string, bool) {
+
+	switch token.Kind {
+	case tVARIABLE:
+		return token.Value.(string), true
+	case tACCESSOR:
+		return strings.Join(token.Value.([]string), "."), true
+	}
+	return "", false
+}
+
+func needsSpaceBetween(previous tExpressionToken, next tExpressionToken) bool {
+
+	switch previous.Kind {
+	case tCLAUSE:
+		fallthrough
+	case tPREFIX:
+		fallthrough
+	case tFUNCTION:
+		return false
+	}
+
+	switch next.Kind {
+	case tCLAUSE_CLOSE:
+		fallthrough
+	case tSEPARATOR:
+		return false
+	case tCLAUSE:
+		// calls hug their arguments
+		return previous.Kind != tACCESSOR
+	}
+
+	return true
+}
+
+/*
+Returns the given parameter name as it must be written in an expression - bare if it lexes as a single variable, bracketed otherwise.
+*/
+func renderName(name string) string {
+
+	// a bare name with dots would be lexed as an accessor.
+	if isBareName(name) && !strings.Contains(name, ".") {
+		return name
+	}
+	return bracketName(name)
+}
+
+/*
+Brackets [name], escaping whatever would otherwise end the brackets, or nest others within them.
+*/
+func bracketName(name string) string {
+	return "[" + strings.NewReplacer("\\", "\\\\", "[", "\\[", "]", "\\]").Replace(name) + "]"
+}
+
+/*
+Returns the given accessor path as it must be written in an expression, bracketing the parameter name if need be,
+such as `[user profile].Address`.
+*/
+func renderPath(path []string) string {
+
+	if isBareName(path[0]) && !strings.Contains(path[0], ".") {
+		return strings.Join(path, ".")
+	}
+	return bracketName(path[0]) + "." + strings.Join(path[1:], ".")
+}
+
+func isBareName(name string) bool {
+
+	if name == "" || !unicode.IsLetter(getFirstRune(name)) {
+		return false
+	}
+
+	for _, character := range name {
+		if !isVariableName(character) {
+			return false
+		}
+	}
+
+	switch name {
+	case "true", "false", "nil", "in", "tIN":
+		return false
+	}
+	return true
+}
+
+/*
+Quotes [value] as a string literal. Strings which would be read as times, such as '2024-01-01', are written as raw strings,
+which never are.
+*/
+func quoteString(value string) string {
+
+	if _, isTime := tryParseTime(value); isTime && !strings.Contains(value, "`") {
+		return "`" + value + "`"
+	}
+
+	// either quote character ends a string, so both are escaped.
+	return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'", "\"", "\\\"").Replace(value) + "'"
+}
+
+/*
+Quotes [value] as a time literal.
+*/
+func quoteTime(value time.Time) string {
+	return "'" + value.Format(isoDateFormat) + "'"
+}
+
+/*
+Renders the planned form of this expression - after any folding of literals - as expression text,
+with parentheses wherever precedence requires them.
+*/
+func (t tEvaluableExpression) String() string {
+	return renderStage(t.evaluationStages)
+}
+
+/*
+Renders the given stage tree as expression text.
+*/
+func renderStage(stage *evaluationStage) string {
+
+	if stage == nil {
+		return ""
+	}
+
+	switch stage.symbol {
+	case tLITERAL:
+		if stage.source != "" {
+			return stage.source
+		}
+		value, err := stage.operator(nil, nil, nil)
+		if err != nil {
+			return "nil"
+		}
+		return renderLiteral(value)
+
+	case tVALUE:
+		return renderName(stage.name)
+
+	case tACCESS:
+		if stage.rightStage == nil {
+			return renderPath(stage.path)
+		}
+		return renderPath(stage.path) + renderArguments(stage.rightStage)
+
+	case tFUNCTIONAL:
+		return stage.name + renderArguments(stage.rightStage)
+
+	case tNOOP:
+		return "(" + renderStage(stage.rightStage) + ")"
+
+	case tNEGATE, tINVERT, tBITWISE_NOT:
+		// adjacent prefixes would be lexed as a single symbol, such as "--", and only some literals may follow a prefix.
+		if stage.rightStage != nil && (bindingPower(stage.rightStage.symbol) == bindingPower(stage.symbol) || !mayFollowPrefix(stage.rightStage)) {
+			return stage.symbol.String() + "(" + renderStage(stage.rightStage) + ")"
+		}
+		return stage.symbol.String() + renderOperand(stage.rightStage, stage, true)
+
+	case tSEPARATE:
+		return renderStage(stage.leftStage) + ", " + renderStage(stage.rightStage)
+	}
+
+	return renderOperand(stage.leftStage, stage, false) + " " + stage.symbol.String() + " " + renderOperand(stage.rightStage, stage, true)
+}
+
+/*
+Whether [stage] may directly follow a prefix operator, as any stage but a string, pattern, or time literal may.
+*/
+func mayFollowPrefix(stage *evaluationStage) bool {
+
+	if stage.symbol != tLITERAL {
+		return true
+	}
+	if stage.source != "" {
+		return false
+	}
+
+	value, err := stage.operator(nil, nil, nil)
+	if err != nil {
+		return false
+	}
+	switch value.(type) {
+	case float64, bool, nil:
+		return true
+	}
+	return false
+}
+
+func renderArguments(stage *evaluationStage) string {
+
+	if stage == nil {
+		return "()"
+	}
+	if stage.symbol == tNOOP {
+		return renderStage(stage)
+	}
+	return "(" + renderStage(stage) + ")"
+}
+
+/*
+Renders [operand], a child of [parent], in parentheses if it would otherwise be parsed as something else.
+Same-precedence operators are evaluated left to right, so only right-hand operands need parentheses for them.
+*/
+func renderOperand(operand *evaluationStage, parent *evaluationStage, isRight bool) string {
+
+	// operators missing an operand, as in `(1 %)`, may be planned, though they can only fail to evaluate.
+	if operand == nil {
+		return ""
+	}
+
+	rendered := renderStage(operand)
+
+	operandPower := bindingPower(operand.symbol)
+	parentPower := bindingPower(parent.symbol)
+
+	if operandPower < parentPower || (isRight && operandPower == parentPower && operand.leftStage != nil) {
+		return "(" + rendered + ")"
+	}
+
+	// ternaries and coalescing share a precedence, but only a ternary's condition may go unparenthesized among them.
+	if operandPower == parentPower && operandPower == bindingPower(tTERNARY_TRUE) &&
+		operand.symbol != parent.symbol && !(parent.symbol == tTERNARY_FALSE && operand.symbol == tTERNARY_TRUE) {
+		return "(" + rendered + ")"
+	}
+	return rendered
+}
+
+/*
+Returns how tightly the given operator binds to its operands; higher binds tighter.
+*/
+func bindingPower(symbol tOperatorSymbol) int {
+
+	switch symbol {
+	case tSEPARATE:
+		return 1
+	case tTERNARY_TRUE, tTERNARY_FALSE, tCOALESCE:
+		return 2
+	case tOR, tXOR:
+		return 3
+	case tAND:
+		return 4
+	case tEQ, tNEQ, tGT, tLT, tGTE, tLTE, tREQ, tNREQ, tIN:
+		return 5
+	case tBITWISE_AND, tBITWISE_OR, tBITWISE_XOR:
+		return 6
+	case tBITWISE_LSHIFT, tBITWISE_RSHIFT:
+		return 7
+	case tPLUS, tMINUS:
+		return 8
+	case tMULTIPLY, tDIVIDE, tFLOOR_DIVIDE, tMODULUS:
+		return 9
+	case tEXPONENT:
+		return 10
+	case tNEGATE, tINVERT, tBITWISE_NOT:
+		return 11
+	}
+	return 12
+}
+
+func renderLiteral(value interface{}) string {
+
+	switch typed := value.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		// as produced by folding literals such as `0 / 0`, which have no literal form of their own.
+		switch {
+		case math.IsNaN(typed):
+			return "nan()"
+		case math.IsInf(typed, 1):
+			return "inf()"
+		case math.IsInf(typed, -1):
+			return "inf(-1)"
+		}
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case uint64:
+		// only too large to be a float64, as a hex literal or the result of a bitwise operator.
+		return "0x" + strconv.FormatUint(typed, 16)
+	case int64:
+		return strconv.FormatInt(typed, 10)
+	case bool:
+		return strconv.FormatBool(typed)
+	case string:
+		return quoteString(typed)
+	case *regexp.Regexp:
+		return quoteString(typed.String())
+	case time.Time:
+		return quoteTime(typed)
+	case []interface{}:
+		elements := make([]string, len(typed))
+		for i, element := range typed {
+			elements[i] = renderLiteral(element)
+		}
+		return "(" + strings.Join(elements, ", ") + ")"
+	}
+
+	rendered, found := stringifyValue(value)
+	if found {
+		return quoteString(rendered)
+	}
+	return quoteString(fmt.Sprintf("%v", value))
+}
@@ -0,0 +1,28 @@
+package core
+
+/*
+Evaluates the optimized plan, falling back to the reference plan if the optimized one panics.
+*/
+func (t tEvaluableExpression) evaluateWithFailover(parameters tParameters) (interface{}, error) {
+
+	result, err, recovered := t.evaluateRecovering(t.evaluationStages, parameters)
+	if recovered == nil {
+		return result, err
+	}
+
+	if t.options.FailoverHandler != nil {
+		t.options.FailoverHandler(t.inputExpression, recovered)
+	}
+
+	return t.evaluateStage(t.referenceStages, parameters)
+}
+
+func (t tEvaluableExpression) evaluateRecovering(stage *evaluationStage, parameters tParameters) (result interface{}, err error, recovered interface{}) {
+
+	defer func() {
+		recovered = recover()
+	}()
+
+	result, err = t.evaluateStage(stage, parameters)
+	return result, err, nil
+}
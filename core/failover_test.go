@@ -0,0 +1,121 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"testing"
+)
+
+/*
+Compiles [text] with Failover, recording what is reported to the FailoverHandler in [reports],
+and replaces its optimized plan with one which runs [operator].
+*/
+func compileFailingOver(test *testing.T, text string, operator evaluationOperator, reports *[]interface{}) *tEvaluableExpression {
+
+	test.Helper()
+
+	options := TExpressionOptions{
+		Failover: true,
+		FailoverHandler: func(expression string, recovered interface{}) {
+			if expression != text {
+				test.Errorf("expected the failing expression to be given as %s, got %s", text, expression)
+			}
+			*reports = append(*reports, recovered)
+		},
+	}
+
+	expression, err := TNewEvaluableExpressionWithOptions(text, options)
+	if err != nil {
+		test.Fatal(err)
+	}
+	expression.evaluationStages = &evaluationStage{symbol: tLITERAL, operator: operator}
+	return expression
+}
+
+func TestFailoverRetriesPanics(test *testing.T) {
+
+	var reports []interface{}
+	expression := compileFailingOver(test, "a * 2 > b", func(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+		panic("optimized plan failed")
+	}, &reports)
+
+	for _, c := range []struct {
+		a, b     float64
+		expected bool
+	}{{2, 3, true}, {1, 3, false}} {
+
+		result, err := expression.TEvaluate(map[string]interface{}{"a": c.a, "b": c.b})
+		if err != nil || result != c.expected {
+			test.Errorf("expected the reference plan to give %v, got %v (%v)", c.expected, result, err)
+		}
+	}
+
+	if len(reports) != 2 || reports[0] != "optimized plan failed" {
+		test.Errorf("expected each panic to be reported, got %v", reports)
+	}
+}
+
+func TestFailoverWithoutHandlerIsSilent(test *testing.T) {
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	expression, err := TNewEvaluableExpressionWithOptions("a * 2 > b", TExpressionOptions{Failover: true})
+	if err != nil {
+		test.Fatal(err)
+	}
+	expression.evaluationStages = &evaluationStage{symbol: tLITERAL, operator: func(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+		panic("optimized plan failed")
+	}}
+
+	result, err := expression.TEvaluate(map[string]interface{}{"a": 2.0, "b": 3.0})
+	if err != nil || result != true {
+		test.Errorf("expected the reference plan to give true, got %v (%v)", result, err)
+	}
+	if logged.Len() != 0 {
+		test.Errorf("expected nothing to be logged, got %s", logged.String())
+	}
+}
+
+func TestFailoverDoesNotRetryErrors(test *testing.T) {
+
+	var reports []interface{}
+	expression := compileFailingOver(test, "a > b", func(left interface{}, right interface{}, parameters tParameters) (interface{}, error) {
+		return nil, errors.New("optimized plan failed")
+	}, &reports)
+
+	_, err := expression.TEvaluate(map[string]interface{}{"a": 1.0, "b": 2.0})
+	if err == nil || err.Error() != "optimized plan failed" {
+		test.Errorf("expected the optimized plan's error, got %v", err)
+	}
+	if len(reports) != 0 {
+		test.Errorf("expected errors not to be reported as failures, got %v", reports)
+	}
+}
+
+func TestPartialEvaluationKeepsReferencePlan(test *testing.T) {
+
+	expression, err := TNewEvaluableExpressionWithOptions("a * 2 > b", TExpressionOptions{Failover: true})
+	if err != nil {
+		test.Fatal(err)
+	}
+	partial, err := expression.TPartialEvaluate(map[string]interface{}{"a": 2.0})
+	if err != nil {
+		test.Fatal(err)
+	}
+	if partial.referenceStages == nil {
+		test.Fatalf("expected the partially evaluated expression to keep a reference plan")
+	}
+
+	reference := *partial
+	reference.evaluationStages = partial.referenceStages
+	reference.referenceStages = nil
+
+	result, err := reference.TEvaluate(map[string]interface{}{"b": 3.0})
+	if err != nil || result != true {
+		test.Errorf("expected the reference plan to have a substituted, got %v (%v)", result, err)
+	}
+}
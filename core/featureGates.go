@@ -0,0 +1,152 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Makes `&&`, `||`, `!`, and the condition of `?` accept any value, rather than only bools.
+nil, false, zero, the empty string, empty arrays, and the zero time are false; everything else is true.
+*/
+const TFeatureTruthiness = "truthiness"
+
+/*
+TFeatureGate describes a behavior-changing feature, which can be turned on or off globally with TSetFeature,
+or for a single expression with TExpressionOptions.Features - so that large rule corpora can be migrated
+one rule at a time, ahead of a release which changes the default.
+*/
+type TFeatureGate struct {
+	Name        string
+	Description string
+
+	// whether the feature is on for expressions which do not say otherwise.
+	Enabled bool
+}
+
+var featureGatesLock sync.RWMutex
+var featureGates = map[string]*TFeatureGate{
+	TFeatureTruthiness: {
+		Name:        TFeatureTruthiness,
+		Description: "Logical operators accept any value, treating nil, false, zero, and empty values as false",
+	},
+}
+
+/*
+Turns the named feature on or off for every expression compiled after this call,
+except those which set it themselves in TExpressionOptions.Features.
+*/
+func TSetFeature(name string, enabled bool) error {
+
+	featureGatesLock.Lock()
+	defer featureGatesLock.Unlock()
+
+	gate, found := featureGates[name]
+	if !found {
+		return fmt.Errorf("Unknown feature '%s'", name)
+	}
+	gate.Enabled = enabled
+	return nil
+}
+
+/*
+Returns every feature gate, with whether it is globally enabled, in order of name.
+*/
+func TFeatureGates() []TFeatureGate {
+
+	featureGatesLock.RLock()
+	defer featureGatesLock.RUnlock()
+
+	ret := make([]TFeatureGate, 0, len(featureGates))
+	for _, gate := range featureGates {
+		ret = append(ret, *gate)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+/*
+Returns whether each feature is enabled for this expression, as decided when it was compiled.
+*/
+func (t tEvaluableExpression) TFeatures() map[string]bool {
+
+	ret := make(map[string]bool, len(t.options.Features))
+	for name, enabled := range t.options.Features {
+		ret[name] = enabled
+	}
+	return ret
+}
+
+/*
+Returns the global state of every feature, overridden by the given per-expression [features].
+*/
+func resolveFeatures(features map[string]bool) (map[string]bool, error) {
+
+	featureGatesLock.RLock()
+	defer featureGatesLock.RUnlock()
+
+	ret := make(map[string]bool, len(featureGates))
+	for name, gate := range featureGates {
+		ret[name] = gate.Enabled
+	}
+
+	for name, enabled := range features {
+
+		_, found := featureGates[name]
+		if !found {
+			return nil, fmt.Errorf("Unknown feature '%s'", name)
+		}
+		ret[name] = enabled
+	}
+	return ret, nil
+}
+
+/*
+Recurses through all stages, marking the logical ones to convert their operands to bools.
+*/
+func applyTruthiness(stage *evaluationStage) {
+
+	if stage == nil {
+		return
+	}
+
+	switch stage.symbol {
+	case tAND, tOR, tXOR, tINVERT, tTERNARY_TRUE:
+		stage.truthy = true
+	}
+
+	applyTruthiness(stage.leftStage)
+	applyTruthiness(stage.rightStage)
+}
+
+func isTruthy(value interface{}) bool {
+
+	switch typed := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return typed
+	case float64:
+		return typed != 0
+	case string:
+		return typed != ""
+	case []interface{}:
+		return len(typed) > 0
+	case time.Time:
+		return !typed.IsZero()
+	}
+
+	// any other type, such as a struct parameter, is true unless it is a nil pointer, map, or the like.
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return !reflected.IsNil()
+	}
+	return true
+}
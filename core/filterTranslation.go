@@ -0,0 +1,156 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// the comparator which means the same with its operands swapped, so that `5 < x` is written as `x > 5`.
+var mirroredComparators = map[tOperatorSymbol]tOperatorSymbol{
+	tEQ:  tEQ,
+	tNEQ: tNEQ,
+	tGT:  tLT,
+	tLT:  tGT,
+	tGTE: tLTE,
+	tLTE: tGTE,
+}
+
+/*
+Plans the stages of this expression to be translated into a query for some other system, as written:
+with literals folded, but without the optimizations which make them harder to read.
+*/
+func (t tEvaluableExpression) planFilterStages() (*evaluationStage, error) {
+
+	stage, err := planReferenceStages(t.tokens, t.options)
+	if err != nil || stage == nil {
+		return nil, err
+	}
+	return elideLiterals(stage), nil
+}
+
+/*
+Returns the field, value, and comparator of a comparison between a field and a literal, in that order.
+*/
+func filterComparison(stage *evaluationStage) (string, interface{}, tOperatorSymbol, bool) {
+
+	field, isField := filterField(stage.leftStage)
+	value, isValue := filterValue(stage.rightStage)
+	if isField && isValue {
+		return field, value, stage.symbol, true
+	}
+
+	field, isField = filterField(stage.rightStage)
+	value, isValue = filterValue(stage.leftStage)
+	if isField && isValue {
+		return field, value, mirroredComparators[stage.symbol], true
+	}
+	return "", nil, stage.symbol, false
+}
+
+/*
+Returns the field named by [stage], if it is a parameter, or an accessor of fields (as a dotted path).
+*/
+func filterField(stage *evaluationStage) (string, bool) {
+
+	stage = unparenthesized(stage)
+	switch {
+	case stage == nil:
+		return "", false
+	case stage.symbol == tVALUE:
+		return stage.name, true
+	case stage.symbol == tACCESS && stage.rightStage == nil:
+		return strings.Join(stage.path, "."), true
+	}
+	return "", false
+}
+
+func filterValue(stage *evaluationStage) (interface{}, bool) {
+
+	stage = unparenthesized(stage)
+	if stage == nil || stage.symbol != tLITERAL {
+		return nil, false
+	}
+
+	value, err := stage.operator(nil, nil, nil)
+	if err != nil {
+		return nil, false
+	}
+
+	switch typed := value.(type) {
+	case float64:
+		// time literals are planned as seconds since the epoch, but are stored as dates.
+		if stage.source != "" {
+			return time.Unix(int64(typed), 0).UTC(), true
+		}
+	case *regexp.Regexp, []interface{}:
+		return nil, false
+	}
+	return value, true
+}
+
+func filterList(stage *evaluationStage) ([]interface{}, bool) {
+
+	stage = unparenthesized(stage)
+	if stage == nil {
+		return []interface{}{}, true
+	}
+
+	if stage.symbol == tSEPARATE {
+
+		left, isList := filterList(stage.leftStage)
+		if !isList {
+			return nil, false
+		}
+		right, isList := filterList(stage.rightStage)
+		if !isList {
+			return nil, false
+		}
+		return append(left, right...), true
+	}
+
+	// a list folded into a single literal.
+	if stage.symbol == tLITERAL {
+		values, _ := literalArguments(stage)
+		if len(values) == 1 {
+			if list, isList := values[0].([]interface{}); isList {
+				return list, true
+			}
+		}
+	}
+
+	value, isValue := filterValue(stage)
+	if !isValue {
+		return nil, false
+	}
+	return []interface{}{value}, true
+}
+
+func filterPattern(stage *evaluationStage) (string, bool) {
+
+	stage = unparenthesized(stage)
+	if stage == nil || stage.symbol != tLITERAL {
+		return "", false
+	}
+
+	value, err := stage.operator(nil, nil, nil)
+	if err != nil {
+		return "", false
+	}
+
+	switch typed := value.(type) {
+	case string:
+		return typed, true
+	case *regexp.Regexp:
+		return typed.String(), true
+	}
+	return "", false
+}
+
+func unparenthesized(stage *evaluationStage) *evaluationStage {
+
+	for stage != nil && stage.symbol == tNOOP {
+		stage = stage.rightStage
+	}
+	return stage
+}
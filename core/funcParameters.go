@@ -0,0 +1,45 @@
+package core
+
+import (
+	"sync"
+)
+
+/*
+tFuncParameters resolves each parameter by calling a function, only once the expression asks for it.
+*/
+type tFuncParameters map[string]*lazyParameter
+
+type lazyParameter struct {
+	once     sync.Once
+	function func() (interface{}, error)
+	value    interface{}
+	err      error
+}
+
+/*
+Returns tParameters which compute each value by calling its function from [functions], such as an expensive geo-IP lookup,
+only if the expression being evaluated actually refers to it. Each function is called at most once, and its result
+(or error) is reused for later references - so make a new source for each evaluation which should see fresh values.
+Safe for concurrent use.
+*/
+func TNewFuncParameters(functions map[string]func() (interface{}, error)) tParameters {
+
+	ret := make(tFuncParameters, len(functions))
+	for name, function := range functions {
+		ret[name] = &lazyParameter{function: function}
+	}
+	return ret
+}
+
+func (p tFuncParameters) tGet(name string) (interface{}, error) {
+
+	parameter, found := p[name]
+	if !found {
+		return nil, tMissingParameterError{name}
+	}
+
+	parameter.once.Do(func() {
+		parameter.value, parameter.err = parameter.function()
+	})
+	return parameter.value, parameter.err
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+TFunctionError is returned by evaluations in which a function call returned an error, saying which call it was.
+The error the function returned can be found with errors.Is and errors.As, as if it had been returned as-is.
+*/
+type TFunctionError struct {
+
+	// the name the function was called by.
+	Function string
+
+	// the values the function was called with, masked as SensitiveParameters are wherever values are printed.
+	Arguments []interface{}
+
+	// the offset, in runes, of the start of the call in the expression.
+	// Calls inside interpolated strings are given relative to the start of the interpolated expression.
+	Position int
+
+	Err error
+}
+
+func (e TFunctionError) Error() string {
+
+	arguments := make([]string, len(e.Arguments))
+	for i, argument := range e.Arguments {
+		arguments[i] = renderLiteral(argument)
+	}
+	return fmt.Sprintf("%s(%s) failed (at %d): %v", e.Function, strings.Join(arguments, ", "), e.Position, e.Err)
+}
+
+func (e TFunctionError) Unwrap() error {
+	return e.Err
+}
+
+/*
+Wraps [err], as returned by the function called by [stage] with the arguments evaluated into [right], in a TFunctionError.
+*/
+func (t tEvaluableExpression) functionError(stage *evaluationStage, right interface{}, err error) error {
+
+	var values []interface{}
+	switch {
+	case stage.single:
+		values = []interface{}{right}
+	case right == nil:
+	default:
+		list, isList := right.([]interface{})
+		if !isList {
+			list = []interface{}{right}
+		}
+		values = list
+	}
+
+	// each argument is masked by the stage it came from. Arguments which were folded into one literal have no stage of
+	// their own, but can only be those at the start of the list, so stages are matched to arguments from the end.
+	stages := argumentStages(stage.rightStage)
+	offset := len(values) - len(stages)
+
+	arguments := make([]interface{}, len(values))
+	for i, value := range values {
+
+		var argumentStage *evaluationStage
+		if i-offset >= 0 {
+			argumentStage = stages[i-offset]
+		}
+		arguments[i] = t.displayValue(argumentStage, value)
+	}
+
+	return TFunctionError{
+		Function:  stage.name,
+		Arguments: arguments,
+		Position:  stage.position,
+		Err:       err,
+	}
+}
+
+/*
+Returns the stages of each argument in the parenthesized argument list [stage].
+*/
+func argumentStages(stage *evaluationStage) []*evaluationStage {
+
+	if stage == nil || stage.rightStage == nil {
+		return nil
+	}
+
+	list := stage.rightStage
+	if list.symbol != tSEPARATE {
+		return []*evaluationStage{list}
+	}
+
+	var ret []*evaluationStage
+	for list.symbol == tSEPARATE {
+		ret = append(ret, list.rightStage)
+		list = list.leftStage
+	}
+	ret = append(ret, list)
+
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	return ret
+}
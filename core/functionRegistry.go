@@ -0,0 +1,146 @@
+package core
+
+import (
+	"reflect"
+	"sync"
+)
+
+var globalFunctionsLock sync.RWMutex
+var globalFunctions = make(map[string]tExpressionFunction)
+var globalPureFunctions = make(map[string]bool)
+
+// counts registrations, so that compiled expressions which may refer to replaced functions can be recognized.
+var globalFunctionsGeneration uint64
+
+/*
+Registers [function] under [name] for every expression created after this call.
+Functions given to a specific expression take precedence over registered ones of the same name.
+Registering a nil function removes any function registered under that name.
+*/
+func TRegisterFunction(name string, function tExpressionFunction) {
+	registerFunction(name, function, false)
+}
+
+/*
+Like TRegisterFunction, but also declares that [function] is pure: its result depends only on its arguments,
+and calling it has no side effects. Calls to pure functions whose arguments are all literals are evaluated once,
+when the expression is planned, rather than every time the expression is evaluated.
+*/
+func TRegisterPureFunction(name string, function tExpressionFunction) {
+	registerFunction(name, function, true)
+}
+
+/*
+Registers [function], and whether it is [pure], at once, so that no expression is planned with one but not the other.
+*/
+func registerFunction(name string, function tExpressionFunction, pure bool) {
+
+	globalFunctionsLock.Lock()
+	defer globalFunctionsLock.Unlock()
+
+	globalFunctionsGeneration++
+	delete(globalPureFunctions, name)
+
+	if function == nil {
+		delete(globalFunctions, name)
+		return
+	}
+
+	globalFunctions[name] = function
+	if pure {
+		globalPureFunctions[name] = true
+	}
+}
+
+/*
+Returns a number which changes whenever a function is registered (or removed), so that anything which keeps
+compiled expressions, such as a cache, can tell whether they were compiled with the functions now registered.
+*/
+func TRegisteredFunctionsGeneration() uint64 {
+
+	globalFunctionsLock.RLock()
+	defer globalFunctionsLock.RUnlock()
+
+	return globalFunctionsGeneration
+}
+
+/*
+Returns the names of all built-in and registered pure functions which are not overridden by the given expression-specific [functions]
+(or, for built-ins, by registered ones), followed by the given [pure] names.
+*/
+func mergeRegisteredPureFunctions(functions map[string]tExpressionFunction, pure []string) []string {
+
+	globalFunctionsLock.RLock()
+	defer globalFunctionsLock.RUnlock()
+
+	var ret []string
+
+	for name := range builtinFunctions {
+
+		_, overridden := functions[name]
+		if !overridden {
+			_, overridden = globalFunctions[name]
+		}
+		if !overridden {
+			ret = append(ret, name)
+		}
+	}
+
+	for name := range globalPureFunctions {
+
+		_, overridden := functions[name]
+		if !overridden {
+			ret = append(ret, name)
+		}
+	}
+
+	return append(ret, pure...)
+}
+
+/*
+Whether [name] is the name of a built-in function, whether or not a registered or expression-specific function overrides it.
+*/
+func isBuiltinFunction(name string) bool {
+
+	_, found := builtinFunctions[name]
+	return found || name == "now"
+}
+
+/*
+Whether [function], found under [name], is the built-in of that name, rather than a function which overrides it.
+*/
+func isBuiltinImplementation(name string, function tExpressionFunction) bool {
+
+	builtin, found := builtinFunctions[name]
+	if name == "now" {
+		builtin, found = makeNowFunction(nil), true
+	}
+
+	// built-ins are either declared functions or closures made by one function each, so share code with no other function.
+	return found && reflect.ValueOf(function).Pointer() == reflect.ValueOf(builtin).Pointer()
+}
+
+/*
+Returns the built-in functions (including those which read the clock given by [options]) unless options.OmitBuiltinFunctions,
+overridden by the registered functions, overridden in turn by the given expression-specific [functions].
+*/
+func mergeRegisteredFunctions(functions map[string]tExpressionFunction, options TExpressionOptions) map[string]tExpressionFunction {
+
+	globalFunctionsLock.RLock()
+	defer globalFunctionsLock.RUnlock()
+
+	ret := make(map[string]tExpressionFunction, len(builtinFunctions)+len(globalFunctions)+len(functions)+1)
+	if !options.OmitBuiltinFunctions {
+		for name, function := range builtinFunctions {
+			ret[name] = function
+		}
+		ret["now"] = makeNowFunction(options.Clock)
+	}
+	for name, function := range globalFunctions {
+		ret[name] = function
+	}
+	for name, function := range functions {
+		ret[name] = function
+	}
+	return ret
+}
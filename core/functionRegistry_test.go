@@ -0,0 +1,180 @@
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRegisteredPureFunctionsAreFolded(test *testing.T) {
+
+	var calls int64
+	TRegisterPureFunction("registryTestTwice", func(arguments ...interface{}) (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		return arguments[0].(float64) * 2, nil
+	})
+	defer TRegisterFunction("registryTestTwice", nil)
+
+	expression, err := TNewEvaluableExpression("registryTestTwice(2) + a")
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		result, err := expression.TEvaluate(map[string]interface{}{"a": 1})
+		if err != nil || result != 5.0 {
+			test.Fatalf("expected 5, got %v (%v)", result, err)
+		}
+	}
+
+	if calls != 1 {
+		test.Errorf("expected the call to be folded when planned, but it was called %d times", calls)
+	}
+}
+
+func TestPureFunctionsReturningArraysAreNotShared(test *testing.T) {
+
+	TRegisterPureFunction("registryTestMap", func(arguments ...interface{}) (interface{}, error) {
+		return map[string]interface{}{"key": arguments[0]}, nil
+	})
+	defer TRegisterFunction("registryTestMap", nil)
+
+	union, err := TNewEvaluableExpression("union((1, 2), (3))")
+	if err != nil {
+		test.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+
+		result, err := union.TEvaluate(nil)
+		if err != nil {
+			test.Fatal(err)
+		}
+		list := result.([]interface{})
+		if list[0] != 1.0 {
+			test.Fatalf("expected an earlier caller's changes not to be seen, got %v", list)
+		}
+		list[0] = "modified"
+	}
+
+	mapped, err := TNewEvaluableExpression("registryTestMap(1)")
+	if err != nil {
+		test.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+
+		result, err := mapped.TEvaluate(nil)
+		if err != nil {
+			test.Fatal(err)
+		}
+		values := result.(map[string]interface{})
+		if values["key"] != 1.0 {
+			test.Fatalf("expected an earlier caller's changes not to be seen, got %v", values)
+		}
+		values["key"] = "modified"
+	}
+}
+
+func TestRegisteringPureFunctionsIsAtomic(test *testing.T) {
+
+	function := func(arguments ...interface{}) (interface{}, error) {
+		return 1.0, nil
+	}
+	TRegisterPureFunction("registryTestOne", function)
+	defer TRegisterFunction("registryTestOne", nil)
+
+	var group sync.WaitGroup
+	done := make(chan struct{})
+
+	group.Add(1)
+	go func() {
+		defer group.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				TRegisterPureFunction("registryTestOne", function)
+			}
+		}
+	}()
+
+	// were the function ever registered without being pure, some compilations would not fold it.
+	for i := 0; i < 2000; i++ {
+
+		expression, err := TNewEvaluableExpression("registryTestOne()")
+		if err != nil {
+			test.Fatal(err)
+		}
+		if expression.String() != "1" {
+			test.Errorf("expected the call to be folded, but it was planned as %s", expression.String())
+			break
+		}
+	}
+
+	close(done)
+	group.Wait()
+}
+
+func TestRegisteredFunctionsGeneration(test *testing.T) {
+
+	before := TRegisteredFunctionsGeneration()
+	TRegisterFunction("registryTestGeneration", nil)
+
+	if TRegisteredFunctionsGeneration() == before {
+		test.Errorf("expected registering a function to change the generation")
+	}
+}
+
+func TestOnlyBuiltinsAreGivenSingleArgumentsAsTheyAre(test *testing.T) {
+
+	counting := func(arguments ...interface{}) (interface{}, error) {
+		return float64(len(arguments)), nil
+	}
+	functions := map[string]tExpressionFunction{"count": counting, "isString": counting}
+	parameters := map[string]interface{}{"list": []interface{}{1.0, 2.0, 3.0}}
+
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"count(list)", 3.0},
+		{"count(nil)", 0.0},
+		{"count(list, 1)", 2.0},
+		{"isString(list)", 3.0}, // overrides the built-in
+		{"typeof(list)", "array"},
+		{"typeof(nil)", "nil"},
+		{"isArray(list)", true},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpressionWithFunctionsAndOptions(c.expression, functions, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		result, err := expression.TEvaluate(parameters)
+		if err != nil || result != c.expected {
+			test.Errorf("%s: expected %v, got %v (%v)", c.expression, c.expected, result, err)
+		}
+	}
+}
+
+func TestOmitBuiltinFunctions(test *testing.T) {
+
+	options := TExpressionOptions{OmitBuiltinFunctions: true}
+
+	for _, text := range []string{"len('abc')", "now()"} {
+		if _, err := TNewEvaluableExpressionWithOptions(text, options); err == nil {
+			test.Errorf("%s: expected no built-in to be defined", text)
+		}
+	}
+
+	expression, err := TNewEvaluableExpressionWithOptions("len > 1", options)
+	if err != nil {
+		test.Fatal(err)
+	}
+	result, err := expression.TEvaluate(map[string]interface{}{"len": 2.0})
+	if err != nil || result != true {
+		test.Errorf("expected len to be a parameter, got %v (%v)", result, err)
+	}
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+tInterpolation is a double-quoted string literal, such as `"hello ${user.Name}"`, split around the expressions interpolated into it.
+There is always one more literal than there are expressions; literals[i] comes before expressions[i].
+*/
+type tInterpolation struct {
+	literals    []string
+	expressions []string
+}
+
+/*
+Reads the source of an interpolated expression, whose `${` has already been read, up to and including the closing `}`.
+Quoted strings within the expression may themselves contain `}`.
+*/
+func readInterpolatedSource(stream *lexerStream) (string, error) {
+
+	var source strings.Builder
+	var quote rune
+
+	for stream.canRead() {
+
+		character := stream.readCharacter()
+
+		switch {
+		case quote == 0 && character == '}':
+			if strings.TrimSpace(source.String()) == "" {
+				return "", errors.New("Empty interpolation in string literal")
+			}
+			return source.String(), nil
+
+		case quote == 0 && (character == '\'' || character == '"' || character == '`'):
+			quote = character
+
+		case quote == character:
+			quote = 0
+
+		case quote != 0 && quote != '`' && character == '\\' && stream.canRead():
+			source.WriteRune(character)
+			character = stream.readCharacter()
+		}
+
+		source.WriteRune(character)
+	}
+
+	return "", errors.New("Unclosed interpolation in string literal")
+}
+
+/*
+Returns the tokens which [token] stands for. That is only ever [token] itself, except for:
+interpolated strings, which stand for the concatenation of their literals and the expressions between them - so that
+`"a ${b} c"` is lexed as `('a ' + (b) + ' c')`; and null predicates, so that `a is not null` is lexed as `a != nil`.
+*/
+func expandToken(token tExpressionToken, functions map[string]tExpressionFunction, options TExpressionOptions) ([]tExpressionToken, error) {
+
+	switch value := token.Value.(type) {
+	case tInterpolation:
+		return expandInterpolation(value, functions, options)
+
+	case tNullPredicate:
+		comparator := "=="
+		if value.negated {
+			comparator = "!="
+		}
+		return []tExpressionToken{{Kind: tCOMPARATOR, Value: comparator}, {Kind: tNIL, Value: nil}}, nil
+	}
+
+	return []tExpressionToken{token}, nil
+}
+
+func expandInterpolation(interpolation tInterpolation, functions map[string]tExpressionFunction, options TExpressionOptions) ([]tExpressionToken, error) {
+
+	// the leading literal is kept even if empty, so that the whole is concatenated as a string.
+	ret := []tExpressionToken{
+		{Kind: tCLAUSE, Value: '('},
+		{Kind: tSTRING, Value: interpolation.literals[0]},
+	}
+
+	for i, source := range interpolation.expressions {
+
+		tokens, err := parseTokens(source, functions, options)
+		if err == nil {
+			err = checkExpressionSyntax(tokens)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid interpolation '${%s}': %v", source, err)
+		}
+
+		ret = append(ret, tExpressionToken{Kind: tMODIFIER, Value: "+"}, tExpressionToken{Kind: tCLAUSE, Value: '('})
+		ret = append(ret, tokens...)
+		ret = append(ret, tExpressionToken{Kind: tCLAUSE_CLOSE, Value: ')'})
+
+		literal := interpolation.literals[i+1]
+		if literal != "" {
+			ret = append(ret, tExpressionToken{Kind: tMODIFIER, Value: "+"}, tExpressionToken{Kind: tSTRING, Value: literal})
+		}
+	}
+
+	return append(ret, tExpressionToken{Kind: tCLAUSE_CLOSE, Value: ')'}), nil
+}
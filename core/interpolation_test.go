@@ -0,0 +1,46 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestInterpolation(test *testing.T) {
+
+	parameters := map[string]interface{}{"name": "Ada", "count": 3.0}
+	interpolating := TExpressionOptions{Interpolation: true}
+
+	cases := []struct {
+		expression string
+		options    TExpressionOptions
+		expected   interface{}
+	}{
+		{`"hello ${name}"`, interpolating, "hello Ada"},
+		{`"${count + 1} items for ${name}"`, interpolating, "4 items for Ada"},
+		{`"${name}"`, interpolating, "Ada"},
+		{`"\${name}"`, interpolating, "${name}"},
+		{`'hello ${name}'`, interpolating, "hello ${name}"},
+		{`"hello ${name}"`, TExpressionOptions{}, "hello ${name}"},
+		{`"a" + "${"`, TExpressionOptions{}, "a${"},
+		{`"hello ${name}"`, TExpressionOptions{Interpolation: true, LegacyStringEscapes: true}, "hello ${name}"},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpressionWithOptions(c.expression, c.options)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		result, err := expression.TEvaluate(parameters)
+		if err != nil || result != c.expected {
+			test.Errorf("%s: expected %v, got %v (%v)", c.expression, c.expected, result, err)
+		}
+	}
+}
+
+func TestUnclosedInterpolation(test *testing.T) {
+
+	_, err := TNewEvaluableExpressionWithOptions(`"a" + "${"`, TExpressionOptions{Interpolation: true})
+	if err == nil {
+		test.Errorf("expected an unclosed interpolation not to parse")
+	}
+}
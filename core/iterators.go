@@ -0,0 +1,98 @@
+//go:build go1.23
+
+package core
+
+import (
+	"iter"
+)
+
+/*
+TNode describes a single stage of a planned expression, as visited by TWalk.
+*/
+type TNode struct {
+
+	// a short description of the stage, such as "&&", "[foo]", or "strlen()"
+	Label string
+
+	// the parameter, function, or accessor name the stage refers to, if any
+	Name string
+
+	// zero for the root stage, one for its children, and so on
+	Depth int
+
+	// whether the stage is a literal value, possibly folded from other literals when planned
+	IsLiteral bool
+}
+
+/*
+TRuleResult is the outcome of evaluating one rule of a rule set.
+*/
+type TRuleResult struct {
+	Name   string
+	Result interface{}
+	Err    error
+}
+
+/*
+Iterates over the tokens this expression was parsed into.
+*/
+func (t tEvaluableExpression) TTokens() iter.Seq[TExpressionToken] {
+
+	return func(yield func(TExpressionToken) bool) {
+		for _, token := range t.tokens {
+			if !yield(token) {
+				return
+			}
+		}
+	}
+}
+
+/*
+Iterates over the planned stages of this expression, parents before children, left before right.
+*/
+func (t tEvaluableExpression) TWalk() iter.Seq[TNode] {
+
+	return func(yield func(TNode) bool) {
+		walkStages(t.evaluationStages, 0, yield)
+	}
+}
+
+func walkStages(stage *evaluationStage, depth int, yield func(TNode) bool) bool {
+
+	if stage == nil {
+		return true
+	}
+
+	node := TNode{
+		Label:     stage.label(),
+		Name:      stage.name,
+		Depth:     depth,
+		IsLiteral: stage.symbol == tLITERAL,
+	}
+
+	return yield(node) &&
+		walkStages(stage.leftStage, depth+1, yield) &&
+		walkStages(stage.rightStage, depth+1, yield)
+}
+
+/*
+Evaluates each rule in order, yielding those which evaluate to true, along with those which fail to evaluate.
+Rules are only evaluated as the iteration reaches them, so stopping early skips the remaining rules.
+*/
+func (r *tRuleSet) TMatches(parameters map[string]interface{}) iter.Seq[TRuleResult] {
+
+	return func(yield func(TRuleResult) bool) {
+
+		for _, name := range r.TNames() {
+
+			result, err := r.TEvaluate(name, parameters)
+			if err == nil && result != true {
+				continue
+			}
+
+			if !yield(TRuleResult{Name: name, Result: result, Err: err}) {
+				return
+			}
+		}
+	}
+}
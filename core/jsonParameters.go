@@ -0,0 +1,208 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+tJSONParameters resolves parameters from a JSON document.
+Parameter names are paths into the document, such as `[order.total]`, `[items[0].sku]`, `[items.0.sku]`, or `[items[0]['sku']]`.
+Paths must be bracketed, since an unbracketed name such as `order.total` is an accessor of the parameter `order`.
+The document is only decoded as far as is needed to find each requested value.
+*/
+type tJSONParameters struct {
+	document []byte
+}
+
+/*
+Returns tParameters which read values out of the given JSON [document].
+*/
+func TNewJSONParameters(document []byte) tParameters {
+	return tJSONParameters{document: document}
+}
+
+func (p tJSONParameters) tGet(name string) (interface{}, error) {
+
+	path, err := parseJSONPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	value, found, err := readJSONPath(p.document, path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read parameter '%s' from JSON: %v", name, err)
+	}
+	if !found {
+		return nil, tMissingParameterError{name}
+	}
+	return value, nil
+}
+
+/*
+Decodes the value found by following [path] through the JSON [document], or returns false if there is none.
+*/
+func readJSONPath(document []byte, path []string) (interface{}, bool, error) {
+
+	decoder := json.NewDecoder(bytes.NewReader(document))
+
+	for _, segment := range path {
+
+		found, err := seekJSONSegment(decoder, segment)
+		if err != nil || !found {
+			return nil, false, err
+		}
+	}
+
+	var value interface{}
+
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+/*
+Splits a path like `items[0].sku` into the segments "items", "0", "sku".
+Members may also be bracketed and quoted, as in `items[0]['sku']`, so that their names may contain dots or brackets.
+*/
+func parseJSONPath(name string) ([]string, error) {
+
+	var ret []string
+
+	for i := 0; i < len(name); {
+
+		switch name[i] {
+		case '.':
+			i++
+
+		case '[':
+			segment, length, err := readJSONIndex(name[i:])
+			if err != nil {
+				return nil, errors.New(err.Error() + " in JSON path '" + name + "'")
+			}
+			ret = append(ret, segment)
+			i += length
+
+		default:
+			end := strings.IndexAny(name[i:], ".[")
+			if end < 0 {
+				end = len(name) - i
+			}
+			ret = append(ret, name[i:i+end])
+			i += end
+		}
+	}
+
+	return ret, nil
+}
+
+/*
+Reads the bracketed segment at the start of [path], such as `[0]` or `['sku']`, returning it and how much of [path] it spans.
+*/
+func readJSONIndex(path string) (string, int, error) {
+
+	if len(path) > 1 && (path[1] == '\'' || path[1] == '"') {
+
+		closing := strings.IndexByte(path[2:], path[1])
+		if closing < 0 || 2+closing+1 >= len(path) || path[2+closing+1] != ']' {
+			return "", 0, errors.New("Unclosed quoted index")
+		}
+		return path[2 : 2+closing], 2 + closing + 2, nil
+	}
+
+	closing := strings.IndexByte(path, ']')
+	if closing < 0 {
+		return "", 0, errors.New("Unclosed index")
+	}
+	return path[1:closing], closing + 1, nil
+}
+
+/*
+Advances [decoder] so that the next value it decodes is the member (or element) named by [segment] of the current value.
+Returns false if the current value has no such member.
+*/
+func seekJSONSegment(decoder *json.Decoder, segment string) (bool, error) {
+
+	token, err := decoder.Token()
+	if err != nil {
+		return false, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+
+		for decoder.More() {
+
+			key, err := decoder.Token()
+			if err != nil {
+				return false, err
+			}
+
+			if key == segment {
+				return true, nil
+			}
+
+			err = skipJSONValue(decoder)
+			if err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+
+	case json.Delim('['):
+
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 {
+			return false, nil
+		}
+
+		for i := 0; decoder.More(); i++ {
+
+			if i == index {
+				return true, nil
+			}
+
+			err = skipJSONValue(decoder)
+			if err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	// scalars have no members.
+	return false, nil
+}
+
+func skipJSONValue(decoder *json.Decoder) error {
+
+	depth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONParameterPaths(test *testing.T) {
+
+	document := []byte(`{
+		"order": {"total": 120.5},
+		"items": [{"sku": "A-1"}, {"sku": "B-2"}],
+		"meta": {"a]b": "bracketed", "c.d": "dotted"}
+	}`)
+
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"[order.total]", 120.5},
+		{"[order.total] > 100", true},
+		{"[items[0].sku]", "A-1"},
+		{"[items[1].sku] == 'B-2'", true},
+		{"[items.0.sku]", "A-1"},
+		{`[items[0\].sku]`, "A-1"},
+		{"[items[0]['sku']]", "A-1"},
+		{"[meta['a]b']]", "bracketed"},
+		{`[meta["c.d"]]`, "dotted"},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpression(c.expression)
+		if err != nil {
+			test.Errorf("%s: failed to compile: %v", c.expression, err)
+			continue
+		}
+
+		result, err := expression.TEvaluateParameters(TNewJSONParameters(document))
+		if err != nil {
+			test.Errorf("%s: failed to evaluate: %v", c.expression, err)
+			continue
+		}
+		if result != c.expected {
+			test.Errorf("%s: expected %v, got %v", c.expression, c.expected, result)
+		}
+	}
+}
+
+func TestJSONParameterMissingPath(test *testing.T) {
+
+	expression, err := TNewEvaluableExpression("[items[5].sku]")
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	_, err = expression.TEvaluateParameters(TNewJSONParameters([]byte(`{"items": []}`)))
+	if err == nil || !strings.Contains(err.Error(), "items[5].sku") {
+		test.Errorf("expected a missing parameter error, got %v", err)
+	}
+}
+
+func TestBracketedNamesRoundTrip(test *testing.T) {
+
+	for _, text := range []string{"[items[0].sku]", `[items[0\].sku]`, "[a\\]b]"} {
+
+		expression, err := TNewEvaluableExpression(text)
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+
+		formatted := expression.TFormat()
+		reparsed, err := TNewEvaluableExpression(formatted)
+		if err != nil {
+			test.Fatalf("%s: formatted as %s, which fails to compile: %v", text, formatted, err)
+		}
+		if reparsed.TVars()[0] != expression.TVars()[0] {
+			test.Errorf("%s: formatted as %s, which names %q rather than %q", text, formatted, reparsed.TVars()[0], expression.TVars()[0])
+		}
+	}
+}
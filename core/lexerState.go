@@ -25,6 +25,7 @@ var validLexerStates = []lexerState{
 			tPREFIX,
 			tNUMERIC,
 			tBOOLEAN,
+			tNIL,
 			tVARIABLE,
 			tPATTERN,
 			tFUNCTION,
@@ -45,6 +46,7 @@ var validLexerStates = []lexerState{
 			tPREFIX,
 			tNUMERIC,
 			tBOOLEAN,
+			tNIL,
 			tVARIABLE,
 			tPATTERN,
 			tFUNCTION,
@@ -67,6 +69,7 @@ var validLexerStates = []lexerState{
 			tMODIFIER,
 			tNUMERIC,
 			tBOOLEAN,
+			tNIL,
 			tVARIABLE,
 			tSTRING,
 			tPATTERN,
@@ -109,6 +112,21 @@ var validLexerStates = []lexerState{
 			tSEPARATOR,
 		},
 	},
+	lexerState{
+
+		kind:       tNIL,
+		isEOF:      true,
+		isNullable: true,
+		validNextKinds: []tTokenKind{
+
+			tMODIFIER,
+			tCOMPARATOR,
+			tLOGICALOP,
+			tCLAUSE_CLOSE,
+			tTERNARY,
+			tSEPARATOR,
+		},
+	},
 	lexerState{
 
 		kind:       tSTRING,
@@ -180,7 +198,9 @@ var validLexerStates = []lexerState{
 			tFUNCTION,
 			tACCESSOR,
 			tSTRING,
+			tTIME,
 			tBOOLEAN,
+			tNIL,
 			tCLAUSE,
 			tCLAUSE_CLOSE,
 		},
@@ -195,6 +215,7 @@ var validLexerStates = []lexerState{
 			tPREFIX,
 			tNUMERIC,
 			tBOOLEAN,
+			tNIL,
 			tVARIABLE,
 			tFUNCTION,
 			tACCESSOR,
@@ -215,6 +236,7 @@ var validLexerStates = []lexerState{
 			tPREFIX,
 			tNUMERIC,
 			tBOOLEAN,
+			tNIL,
 			tVARIABLE,
 			tFUNCTION,
 			tACCESSOR,
@@ -233,6 +255,7 @@ var validLexerStates = []lexerState{
 
 			tNUMERIC,
 			tBOOLEAN,
+			tNIL,
 			tVARIABLE,
 			tFUNCTION,
 			tACCESSOR,
@@ -251,6 +274,7 @@ var validLexerStates = []lexerState{
 			tPREFIX,
 			tNUMERIC,
 			tBOOLEAN,
+			tNIL,
 			tSTRING,
 			tTIME,
 			tVARIABLE,
@@ -294,7 +318,9 @@ var validLexerStates = []lexerState{
 			tPREFIX,
 			tNUMERIC,
 			tBOOLEAN,
+			tNIL,
 			tSTRING,
+			tPATTERN,
 			tTIME,
 			tVARIABLE,
 			tFUNCTION,
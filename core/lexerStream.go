@@ -1,5 +1,9 @@
 package core
 
+import (
+	"unicode"
+)
+
 type lexerStream struct {
 	source   []rune
 	position int
@@ -30,6 +34,19 @@ func (this *lexerStream) readCharacter() rune {
 	return character
 }
 
+/*
+Returns the next character which is not whitespace, without reading it - or 0, if there is none.
+*/
+func (this lexerStream) peekPastSpace() rune {
+
+	for position := this.position; position < this.length; position++ {
+		if !unicode.IsSpace(this.source[position]) {
+			return this.source[position]
+		}
+	}
+	return 0
+}
+
 func (this *lexerStream) rewind(amount int) {
 	this.position -= amount
 }
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+/*
+TLintWarning describes something in an expression which is legal, but probably not what its author meant.
+*/
+type TLintWarning struct {
+
+	// byte offset of the problem in the expression text, or -1 if it cannot be pinned to one place.
+	Position int
+	Message  string
+}
+
+func (w TLintWarning) String() string {
+
+	if w.Position < 0 {
+		return w.Message
+	}
+	return fmt.Sprintf("%s (at %d)", w.Message, w.Position)
+}
+
+/*
+Returns warnings for suspicious constructs in this expression: comparisons of literals which always
+have the same result (such as `1 > 2`), comparisons of a parameter with itself, and regular expressions
+which are anchored at neither end, and so match anywhere in a string.
+
+Literal comparisons are found before they are folded away by planning, so they are reported even though
+they cost nothing at evaluation time. An expression which does not compile cannot be linted this way;
+see TLintSource for the checks which only need its text.
+*/
+func (t tEvaluableExpression) TLint() []TLintWarning {
+
+	stages, err := planReferenceStages(t.tokens, t.options)
+	if err != nil {
+		return nil
+	}
+
+	var ret []TLintWarning
+	lintStage(stages, &ret)
+	return ret
+}
+
+/*
+Returns a warning for every `=` in [expression] which is not part of a longer operator, where `==` was probably meant.
+Only needs the text of the expression, so it can explain why an expression failed to compile.
+*/
+func TLintSource(expression string) []TLintWarning {
+
+	var ret []TLintWarning
+	var quote rune
+	var bracketed, escaped bool
+
+	runes := []rune(expression)
+	position := 0
+
+	for index, character := range runes {
+
+		switch {
+		case escaped:
+			escaped = false
+
+		case quote == '`':
+			if character == quote {
+				quote = 0
+			}
+
+		case character == '\\' && (quote != 0 || bracketed):
+			escaped = true
+
+		case quote != 0:
+			if character == quote {
+				quote = 0
+			}
+
+		case bracketed:
+			if character == ']' {
+				bracketed = false
+			}
+
+		case character == '\'' || character == '"' || character == '`':
+			quote = character
+
+		case character == '[':
+			bracketed = true
+
+		case character == '=':
+			if !isOperatorRune(runeAt(runes, index-1)) && !isOperatorRune(runeAt(runes, index+1)) && runeAt(runes, index+1) != '~' {
+				ret = append(ret, TLintWarning{Position: position, Message: "'=' is not an operator, '==' was probably meant"})
+			}
+		}
+
+		position += len(string(character))
+	}
+
+	return ret
+}
+
+func runeAt(runes []rune, index int) rune {
+
+	if index < 0 || index >= len(runes) {
+		return 0
+	}
+	return runes[index]
+}
+
+func isOperatorRune(character rune) bool {
+	return strings.ContainsRune("=!<>", character)
+}
+
+func lintStage(stage *evaluationStage, warnings *[]TLintWarning) {
+
+	if stage == nil {
+		return
+	}
+
+	switch stage.symbol {
+	case tEQ, tNEQ, tGT, tLT, tGTE, tLTE, tREQ, tNREQ, tIN:
+		lintComparison(stage, warnings)
+	}
+
+	lintStage(stage.leftStage, warnings)
+	lintStage(stage.rightStage, warnings)
+}
+
+func lintComparison(stage *evaluationStage, warnings *[]TLintWarning) {
+
+	_, leftLiteral := literalArguments(stage.leftStage)
+	_, rightLiteral := literalArguments(stage.rightStage)
+
+	if leftLiteral && rightLiteral {
+
+		result, err := tEvaluableExpression{checksTypes: true}.evaluateStage(stage, tDUMMY_PARAMETERS)
+		if err == nil {
+			*warnings = append(*warnings, TLintWarning{
+				Position: -1,
+				Message:  fmt.Sprintf("'%s' compares only literals, and is always %v", renderStage(stage), result),
+			})
+		}
+	}
+
+	left := unwrapStage(stage.leftStage)
+	right := unwrapStage(stage.rightStage)
+
+	if isPlainReference(left) && isPlainReference(right) && left.name == right.name {
+		*warnings = append(*warnings, TLintWarning{
+			Position: -1,
+			Message:  fmt.Sprintf("'%s' compares '%s' with itself", renderStage(stage), left.name),
+		})
+	}
+
+	if (stage.symbol == tREQ || stage.symbol == tNREQ) && rightLiteral {
+
+		pattern, err := tEvaluableExpression{}.evaluateStage(stage.rightStage, tDUMMY_PARAMETERS)
+		if err == nil && !isAnchored(pattern) {
+			*warnings = append(*warnings, TLintWarning{
+				Position: -1,
+				Message:  fmt.Sprintf("Pattern in '%s' is not anchored with '^' or '$', so it matches anywhere in the string", renderStage(stage)),
+			})
+		}
+	}
+}
+
+/*
+Looks through any parentheses around [stage].
+*/
+func unwrapStage(stage *evaluationStage) *evaluationStage {
+
+	for stage != nil && stage.symbol == tNOOP && stage.rightStage != nil {
+		stage = stage.rightStage
+	}
+	return stage
+}
+
+/*
+Returns true if [stage] reads a parameter or field, rather than calling anything which may return a different value each time.
+*/
+func isPlainReference(stage *evaluationStage) bool {
+	return stage != nil && (stage.symbol == tVALUE || (stage.symbol == tACCESS && stage.rightStage == nil))
+}
+
+func isAnchored(pattern interface{}) bool {
+
+	var source string
+
+	switch typed := pattern.(type) {
+	case string:
+		source = typed
+	case *regexp.Regexp:
+		source = typed.String()
+	default:
+		return true
+	}
+
+	return strings.HasPrefix(source, "^") || strings.HasPrefix(source, `\A`) ||
+		strings.HasSuffix(source, "$") || strings.HasSuffix(source, `\z`)
+}
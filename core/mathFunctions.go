@@ -0,0 +1,105 @@
+package core
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+The optional math functions, by their unqualified names, with the numbers of arguments each accepts.
+*/
+var mathFunctions = map[string]struct {
+	min, max  int
+	calculate func(arguments []float64) float64
+}{
+	"sqrt":  {1, 1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"log":   {1, 2, logarithm},
+	"log2":  {1, 1, func(a []float64) float64 { return math.Log2(a[0]) }},
+	"exp":   {1, 1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"sin":   {1, 1, func(a []float64) float64 { return math.Sin(a[0]) }},
+	"cos":   {1, 1, func(a []float64) float64 { return math.Cos(a[0]) }},
+	"pow":   {2, 2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"clamp": {3, 3, func(a []float64) float64 { return math.Max(a[1], math.Min(a[2], a[0])) }},
+	"sign":  {1, 1, sign},
+}
+
+/*
+Returns the optional math functions - sqrt, log, log2, exp, sin, cos, pow, clamp, and sign - named under [namespace],
+such as "math.sqrt", or unqualified if [namespace] is empty. All are pure, and take and return numbers:
+`log(x)` is the natural logarithm, and `log(x, base)` that in the given base; `clamp(x, min, max)` limits [x] to the range;
+`sign(x)` is -1, 0, or 1.
+*/
+func TMathFunctions(namespace string) map[string]tExpressionFunction {
+
+	ret := make(map[string]tExpressionFunction, len(mathFunctions))
+	for name := range mathFunctions {
+		qualified := qualifyName(namespace, name)
+		ret[qualified] = makeMathFunction(qualified, name)
+	}
+	return ret
+}
+
+/*
+Registers the functions returned by TMathFunctions as pure, for every expression created after this call.
+*/
+func TRegisterMathFunctions(namespace string) {
+
+	for name, function := range TMathFunctions(namespace) {
+		TRegisterPureFunction(name, function)
+	}
+}
+
+func makeMathFunction(qualified string, name string) tExpressionFunction {
+
+	definition := mathFunctions[name]
+
+	return func(arguments ...interface{}) (interface{}, error) {
+
+		if len(arguments) < definition.min || len(arguments) > definition.max {
+			if definition.min == definition.max {
+				return nil, fmt.Errorf("%s expects %d arguments, got %d", qualified, definition.min, len(arguments))
+			}
+			return nil, fmt.Errorf("%s expects %d to %d arguments, got %d", qualified, definition.min, definition.max, len(arguments))
+		}
+
+		numbers := make([]float64, len(arguments))
+		for i, argument := range arguments {
+
+			number, ok := argument.(float64)
+			if !ok {
+				return nil, fmt.Errorf("%s expects numbers, got '%v'", qualified, argument)
+			}
+			numbers[i] = number
+		}
+
+		return definition.calculate(numbers), nil
+	}
+}
+
+func qualifyName(namespace string, name string) string {
+
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func logarithm(arguments []float64) float64 {
+
+	if len(arguments) == 1 {
+		return math.Log(arguments[0])
+	}
+	return math.Log(arguments[0]) / math.Log(arguments[1])
+}
+
+func sign(arguments []float64) float64 {
+
+	switch {
+	case arguments[0] > 0:
+		return 1
+	case arguments[0] < 0:
+		return -1
+	}
+	// zero, or NaN.
+	return arguments[0]
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"fmt"
+)
+
+var mongoComparators = map[tOperatorSymbol]string{
+	tEQ:  "$eq",
+	tNEQ: "$ne",
+	tGT:  "$gt",
+	tLT:  "$lt",
+	tGTE: "$gte",
+	tLTE: "$lte",
+}
+
+/*
+Translates this expression into a MongoDB filter document, which can be given to a collection's Find as it is,
+or converted to a bson.M - so that the same rule can filter both parameters in memory and documents in a collection.
+Parameters are taken to be fields of the document; accessors, such as `user.Address.City`, to be paths of fields.
+
+Only `&&`, `||`, `!`, the comparators, `in`, `=~`, and `!~` translate, and only between a field and a literal,
+or a list of literals for `in`. Time literals translate to time.Times. Anything else, such as arithmetic,
+function calls, or comparisons between fields, fails to translate with an error naming it.
+*/
+func (t tEvaluableExpression) TMongoFilter() (map[string]interface{}, error) {
+
+	stage, err := t.planFilterStages()
+	if err != nil {
+		return nil, err
+	}
+	if stage == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	return mongoFilter(stage)
+}
+
+func mongoFilter(stage *evaluationStage) (map[string]interface{}, error) {
+
+	switch stage.symbol {
+	case tNOOP:
+		if stage.rightStage != nil {
+			return mongoFilter(stage.rightStage)
+		}
+
+	case tAND, tOR:
+		operator := "$and"
+		if stage.symbol == tOR {
+			operator = "$or"
+		}
+
+		var clauses []interface{}
+		for _, operand := range []*evaluationStage{stage.leftStage, stage.rightStage} {
+
+			clause, err := mongoFilter(operand)
+			if err != nil {
+				return nil, err
+			}
+
+			// nested operators of the same kind are flattened into one, as `a && b && c` is written.
+			nested, isNested := clause[operator].([]interface{})
+			if isNested && len(clause) == 1 {
+				clauses = append(clauses, nested...)
+			} else {
+				clauses = append(clauses, clause)
+			}
+		}
+		return map[string]interface{}{operator: clauses}, nil
+
+	case tINVERT:
+		clause, err := mongoFilter(stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$nor": []interface{}{clause}}, nil
+
+	case tVALUE, tACCESS:
+		field, isField := filterField(stage)
+		if isField {
+			return map[string]interface{}{field: true}, nil
+		}
+
+	case tLITERAL:
+		value, _ := filterValue(stage)
+		if value == true {
+			return map[string]interface{}{}, nil
+		}
+
+	case tEQ, tNEQ, tGT, tLT, tGTE, tLTE:
+		field, value, symbol, ok := filterComparison(stage)
+		if ok {
+			return map[string]interface{}{field: map[string]interface{}{mongoComparators[symbol]: value}}, nil
+		}
+
+	case tIN:
+		field, isField := filterField(stage.leftStage)
+		values, isList := filterList(stage.rightStage)
+		if isField && isList {
+			return map[string]interface{}{field: map[string]interface{}{"$in": values}}, nil
+		}
+
+	case tREQ, tNREQ:
+		field, isField := filterField(stage.leftStage)
+		pattern, isPattern := filterPattern(stage.rightStage)
+		if isField && isPattern {
+
+			condition := map[string]interface{}{"$regex": pattern}
+			if stage.symbol == tNREQ {
+				condition = map[string]interface{}{"$not": condition}
+			}
+			return map[string]interface{}{field: condition}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Cannot translate '%s' into a MongoDB filter", renderStage(stage))
+}
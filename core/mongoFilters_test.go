@@ -0,0 +1,262 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"testing"
+)
+
+func TestMongoFilter(test *testing.T) {
+
+	cases := []struct {
+		expression string
+		expected   string
+	}{
+		{"a > 1 && s == 'x'", `{"$and":[{"a":{"$gt":1}},{"s":{"$eq":"x"}}]}`},
+		{"a > 1 && b < 2 && flag", `{"$and":[{"a":{"$gt":1}},{"b":{"$lt":2}},{"flag":true}]}`},
+		{"!(a >= 1) || 5 < b", `{"$or":[{"$nor":[{"a":{"$gte":1}}]},{"b":{"$gt":5}}]}`},
+		{"s in ('a', 'b')", `{"s":{"$in":["a","b"]}}`},
+		{"s !~ 'b'", `{"s":{"$not":{"$regex":"b"}}}`},
+		{"user.Age <= 18", `{"user.Age":{"$lte":18}}`},
+		{"a == nil", `{"a":{"$eq":null}}`},
+		{"t == '2024-01-02'", `{"t":{"$eq":"2024-01-02T00:00:00Z"}}`},
+		{"s =~ 'a|b'", `{"s":{"$regex":"a|b"}}`},
+		{"s in ('a') && t !~ '^x'", `{"$and":[{"s":{"$in":["a"]}},{"t":{"$not":{"$regex":"^x"}}}]}`},
+		{"!(a != 1)", `{"$nor":[{"a":{"$ne":1}}]}`},
+		{"!(a > 1 && b < 2)", `{"$nor":[{"$and":[{"a":{"$gt":1}},{"b":{"$lt":2}}]}]}`},
+		{"a != 1 || b == 2", `{"$or":[{"a":{"$ne":1}},{"b":{"$eq":2}}]}`},
+		{"user.Name != nil", `{"user.Name":{"$ne":null}}`},
+		{"flag == false", `{"flag":{"$eq":false}}`},
+		{"a >= 1 && a <= 5", `{"$and":[{"a":{"$gte":1}},{"a":{"$lte":5}}]}`},
+		{"true", `{}`},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpressionWithOptions(c.expression, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		filter, err := expression.TMongoFilter()
+		if err != nil {
+			test.Errorf("%s: %v", c.expression, err)
+			continue
+		}
+		if encoded, _ := json.Marshal(filter); string(encoded) != c.expected {
+			test.Errorf("%s: expected %s, got %s", c.expression, c.expected, encoded)
+		}
+	}
+}
+
+func TestMongoFilterRejectsUntranslatable(test *testing.T) {
+
+	for _, text := range []string{"a + 1 > 2", "a > b", "len(s) > 1", "false", "a > 1 ? s : t"} {
+
+		expression, err := TNewEvaluableExpressionWithOptions(text, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+		_, err = expression.TMongoFilter()
+		if err == nil {
+			test.Errorf("%s: expected not to translate", text)
+		}
+	}
+}
+
+/*
+Generates an expression out of the conditions which filters translate: comparisons between the parameters
+of conformanceInputs and literals of the same type, joined by `&&`, `||`, and `!`.
+*/
+func generateFilterExpression(random *rand.Rand, depth int) string {
+
+	if depth > 0 {
+		switch random.Intn(5) {
+		case 0:
+			return "(" + generateFilterExpression(random, depth-1) + " && " + generateFilterExpression(random, depth-1) + ")"
+		case 1:
+			return "(" + generateFilterExpression(random, depth-1) + " || " + generateFilterExpression(random, depth-1) + ")"
+		case 2:
+			return "!(" + generateFilterExpression(random, depth-1) + ")"
+		}
+	}
+
+	pick := func(choices ...string) string {
+		return choices[random.Intn(len(choices))]
+	}
+	comparator := pick("==", "!=", ">", "<", ">=", "<=")
+
+	switch random.Intn(6) {
+	case 0:
+		if random.Intn(2) == 0 {
+			return pick("a", "b", "user.Age") + " " + comparator + " " + pick("0", "2", "2.5", "18")
+		}
+		return pick("0", "2", "2.5", "18") + " " + comparator + " " + pick("a", "b", "user.Age")
+	case 1:
+		return pick("s", "t", "user.Name") + " " + comparator + " " + pick("''", "'a'", "'abc'", "'B'")
+	case 2:
+		return pick("a", "b") + " in (" + pick("0", "2") + ", " + pick("3", "7", "40") + ")"
+	case 3:
+		return pick("s", "t") + " " + pick("=~", "!~") + " " + pick("'^a'", "'c$'", "'b+'", "'a'", "'a|c'", "'^(a|b)$'", "'[^a]b'")
+	case 4:
+		return pick("flag", "flag == false")
+	}
+	return "s " + pick("==", "!=") + " nil"
+}
+
+/*
+The fields of [parameters], as a document whose fields are found by their dotted paths.
+*/
+func filterDocument(parameters map[string]interface{}) map[string]interface{} {
+
+	document := make(map[string]interface{})
+	for name, value := range parameters {
+
+		user, isUser := value.(conformanceUser)
+		if isUser {
+			document[name+".Age"] = user.Age
+			document[name+".Name"] = user.Name
+			continue
+		}
+		document[name] = value
+	}
+	return document
+}
+
+/*
+Whether [document] matches [filter], as MongoDB would match it - for the operators TMongoFilter produces.
+*/
+func matchesMongoFilter(filter map[string]interface{}, document map[string]interface{}) bool {
+
+	for key, condition := range filter {
+
+		var matched bool
+		switch key {
+		case "$and", "$or", "$nor":
+			count := 0
+			for _, clause := range condition.([]interface{}) {
+				if matchesMongoFilter(clause.(map[string]interface{}), document) {
+					count++
+				}
+			}
+			clauses := len(condition.([]interface{}))
+			matched = (key == "$and" && count == clauses) || (key == "$or" && count > 0) || (key == "$nor" && count == 0)
+
+		default:
+			operators, isOperators := condition.(map[string]interface{})
+			if isOperators {
+				matched = matchesMongoOperators(document[key], operators)
+			} else {
+				matched = document[key] == condition
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesMongoOperators(value interface{}, operators map[string]interface{}) bool {
+
+	for operator, operand := range operators {
+
+		var matched bool
+		switch operator {
+		case "$eq":
+			matched = value == operand
+		case "$ne":
+			matched = value != operand
+		case "$gt", "$lt", "$gte", "$lte":
+			order, comparable := compareFilterValues(value, operand)
+			matched = comparable && map[string]bool{"$gt": order > 0, "$lt": order < 0, "$gte": order >= 0, "$lte": order <= 0}[operator]
+		case "$in":
+			for _, element := range operand.([]interface{}) {
+				matched = matched || value == element
+			}
+		case "$regex":
+			text, isText := value.(string)
+			matched = isText && regexp.MustCompile(operand.(string)).MatchString(text)
+		case "$not":
+			matched = !matchesMongoOperators(value, operand.(map[string]interface{}))
+		default:
+			panic(fmt.Sprintf("unexpected operator %s", operator))
+		}
+
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Orders [left] and [right], if they are both numbers or both strings; values of different types are never in order.
+*/
+func compareFilterValues(left interface{}, right interface{}) (int, bool) {
+
+	switch typed := left.(type) {
+	case float64:
+		other, isNumber := right.(float64)
+		switch {
+		case !isNumber:
+			return 0, false
+		case typed < other:
+			return -1, true
+		case typed > other:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		other, isString := right.(string)
+		switch {
+		case !isString:
+			return 0, false
+		case typed < other:
+			return -1, true
+		case typed > other:
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+/*
+Translates generated expressions into filters, and checks that each filter matches exactly the documents
+the expression is true for - wherever the expression evaluates without error.
+*/
+func TestGeneratedMongoFiltersMatchEvaluation(test *testing.T) {
+
+	inputs := conformanceInputs(50)
+	random := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+
+		text := generateFilterExpression(random, 3)
+		expression, err := TNewEvaluableExpressionWithOptions(text, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+		filter, err := expression.TMongoFilter()
+		if err != nil {
+			test.Errorf("%s: %v", text, err)
+			continue
+		}
+
+		for _, parameters := range inputs {
+
+			result, err := expression.TEvaluate(parameters)
+			if err != nil {
+				continue
+			}
+			if matchesMongoFilter(filter, filterDocument(parameters)) != result {
+				encoded, _ := json.Marshal(filter)
+				test.Errorf("%s: translated into %s, which does not match as it evaluates (%v) given %v", text, encoded, result, parameters)
+				break
+			}
+		}
+	}
+}
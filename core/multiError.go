@@ -0,0 +1,25 @@
+package core
+
+import (
+	"strings"
+)
+
+/*
+TMultiError reports several independent problems at once, such as every rule of a rule set which failed to compile.
+*/
+type TMultiError struct {
+	Errors []error
+}
+
+func (e TMultiError) Error() string {
+
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e TMultiError) Unwrap() []error {
+	return e.Errors
+}
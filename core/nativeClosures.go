@@ -0,0 +1,272 @@
+package core
+
+/*
+Evaluates a stage natively, as set by TExpressionOptions.NativeClosures, in place of interpreting it.
+*/
+type nativeStage func(t *tEvaluableExpression, parameters tParameters) (interface{}, error)
+
+/*
+Like nativeStage, but for stages which only ever give a bool, so that it need not be boxed as an interface{}.
+*/
+type nativeCondition func(t *tEvaluableExpression, parameters tParameters) (bool, error)
+
+/*
+Comparisons of operands of the same concrete type, which give the same results the comparators would.
+*/
+var nativeNumberComparisons = map[tOperatorSymbol]func(float64, float64) bool{
+	tEQ:  func(left float64, right float64) bool { return left == right },
+	tNEQ: func(left float64, right float64) bool { return left != right },
+	tGT:  func(left float64, right float64) bool { return left > right },
+	tLT:  func(left float64, right float64) bool { return left < right },
+	tGTE: func(left float64, right float64) bool { return left >= right },
+	tLTE: func(left float64, right float64) bool { return left <= right },
+}
+
+var nativeStringComparisons = map[tOperatorSymbol]func(string, string) bool{
+	tEQ:  func(left string, right string) bool { return left == right },
+	tNEQ: func(left string, right string) bool { return left != right },
+	tGT:  func(left string, right string) bool { return left > right },
+	tLT:  func(left string, right string) bool { return left < right },
+	tGTE: func(left string, right string) bool { return left >= right },
+	tLTE: func(left string, right string) bool { return left <= right },
+}
+
+/*
+Lowers the stages of [root] which can be into native closures: `&&`, `||`, and `!` of conditions, and comparisons,
+which compare float64s and strings directly. Conditions nested in one another are called directly, without boxing
+their results, or dispatching on type checks which cannot fail.
+
+Operands of any other type are compared as the interpreter would compare them, and any other stage is interpreted,
+so that lowered stages give exactly the results (and errors) interpreting them would. Stages converted by
+TFeatureTruthiness or CoerceStringers, and comparisons with policies other than the defaults, are not lowered.
+*/
+func lowerStages(root *evaluationStage, options TExpressionOptions) {
+
+	lowering := stageLowering{
+		options:    options,
+		conditions: make(map[*evaluationStage]nativeCondition),
+	}
+	lowering.lower(root)
+}
+
+type stageLowering struct {
+	options TExpressionOptions
+
+	// the lowered conditions of stages already lowered, or nil for those which cannot be.
+	conditions map[*evaluationStage]nativeCondition
+}
+
+func (l *stageLowering) lower(stage *evaluationStage) {
+
+	if stage == nil {
+		return
+	}
+
+	// operands first, so that their conditions are known to their parent.
+	l.lower(stage.leftStage)
+	l.lower(stage.rightStage)
+
+	condition := l.lowerCondition(stage)
+	l.conditions[stage] = condition
+
+	if condition != nil && stage.symbol != tLITERAL {
+		stage.native = func(t *tEvaluableExpression, parameters tParameters) (interface{}, error) {
+
+			ret, err := condition(t, parameters)
+			if err != nil {
+				return nil, err
+			}
+			return boolIface(ret), nil
+		}
+	}
+}
+
+/*
+Returns [stage] lowered into a condition, or nil if it may give something other than a bool.
+*/
+func (l *stageLowering) lowerCondition(stage *evaluationStage) nativeCondition {
+
+	if stage.truthy || stage.stringers {
+		return nil
+	}
+
+	switch stage.symbol {
+	case tLITERAL:
+		value, err := stage.operator(nil, nil, nil)
+		if typed, isBool := value.(bool); err == nil && isBool {
+			return func(t *tEvaluableExpression, parameters tParameters) (bool, error) {
+				return typed, nil
+			}
+		}
+
+	case tNOOP:
+		return l.operand(stage.rightStage)
+
+	case tAND:
+		left, right := l.operand(stage.leftStage), l.operand(stage.rightStage)
+		if left != nil && right != nil {
+			return func(t *tEvaluableExpression, parameters tParameters) (bool, error) {
+
+				ret, err := left(t, parameters)
+				if err != nil || !ret {
+					return false, err
+				}
+				return right(t, parameters)
+			}
+		}
+
+	case tOR:
+		left, right := l.operand(stage.leftStage), l.operand(stage.rightStage)
+		if left != nil && right != nil {
+			return func(t *tEvaluableExpression, parameters tParameters) (bool, error) {
+
+				ret, err := left(t, parameters)
+				if err != nil || ret {
+					return ret, err
+				}
+				return right(t, parameters)
+			}
+		}
+
+	case tINVERT:
+		right := l.operand(stage.rightStage)
+		if right != nil {
+			return func(t *tEvaluableExpression, parameters tParameters) (bool, error) {
+
+				ret, err := right(t, parameters)
+				return !ret, err
+			}
+		}
+
+	case tEQ, tNEQ:
+		if l.options.DeepEquality {
+			return nil
+		}
+		fallthrough
+	case tGT, tLT, tGTE, tLTE:
+		if l.options.NaNComparisons != TNaNCompareFloat {
+			return nil
+		}
+		return l.lowerComparison(stage)
+	}
+	return nil
+}
+
+/*
+Returns the condition of [operand], if it has one which may be called in place of evaluating it.
+Memoized operands must be evaluated through their memo, so do not.
+*/
+func (l *stageLowering) operand(operand *evaluationStage) nativeCondition {
+
+	if operand == nil || operand.memo != 0 {
+		return nil
+	}
+	return l.conditions[operand]
+}
+
+func (l *stageLowering) lowerComparison(stage *evaluationStage) nativeCondition {
+
+	compareNumbers := nativeNumberComparisons[stage.symbol]
+	compareStrings := nativeStringComparisons[stage.symbol]
+	left := l.lowerValue(stage.leftStage)
+
+	// the most common comparisons, such as `age >= 18` or `country == 'US'`, compare a value to a literal.
+	if stage.rightStage.symbol == tLITERAL {
+
+		literal, err := stage.rightStage.operator(nil, nil, nil)
+		if err != nil {
+			return nil
+		}
+
+		switch typed := literal.(type) {
+		case float64:
+			return func(t *tEvaluableExpression, parameters tParameters) (bool, error) {
+
+				value, err := left(t, parameters)
+				if err != nil {
+					return false, err
+				}
+				if number, isNumber := value.(float64); isNumber {
+					return compareNumbers(number, typed), nil
+				}
+				return compareOperands(t, stage, value, literal, parameters)
+			}
+		case string:
+			return func(t *tEvaluableExpression, parameters tParameters) (bool, error) {
+
+				value, err := left(t, parameters)
+				if err != nil {
+					return false, err
+				}
+				if text, isString := value.(string); isString {
+					return compareStrings(text, typed), nil
+				}
+				return compareOperands(t, stage, value, literal, parameters)
+			}
+		}
+	}
+
+	right := l.lowerValue(stage.rightStage)
+
+	return func(t *tEvaluableExpression, parameters tParameters) (bool, error) {
+
+		leftValue, err := left(t, parameters)
+		if err != nil {
+			return false, err
+		}
+		rightValue, err := right(t, parameters)
+		if err != nil {
+			return false, err
+		}
+
+		switch typedLeft := leftValue.(type) {
+		case float64:
+			if typedRight, isNumber := rightValue.(float64); isNumber {
+				return compareNumbers(typedLeft, typedRight), nil
+			}
+		case string:
+			if typedRight, isString := rightValue.(string); isString {
+				return compareStrings(typedLeft, typedRight), nil
+			}
+		}
+		return compareOperands(t, stage, leftValue, rightValue, parameters)
+	}
+}
+
+/*
+Compares operands which are not both float64s or both strings as the comparison [stage] would when interpreted.
+*/
+func compareOperands(t *tEvaluableExpression, stage *evaluationStage, left interface{}, right interface{}, parameters tParameters) (bool, error) {
+
+	ret, err := t.applyOperator(stage, left, right, parameters)
+	if err != nil {
+		return false, err
+	}
+	return ret == true, nil
+}
+
+/*
+Returns [stage] as a closure which gives its value: directly, for literals and parameters, or else by evaluating it.
+*/
+func (l *stageLowering) lowerValue(stage *evaluationStage) nativeStage {
+
+	switch stage.symbol {
+	case tLITERAL:
+		value, err := stage.operator(nil, nil, nil)
+		if err == nil {
+			return func(t *tEvaluableExpression, parameters tParameters) (interface{}, error) {
+				return value, nil
+			}
+		}
+
+	case tVALUE:
+		operator := stage.operator
+		return func(t *tEvaluableExpression, parameters tParameters) (interface{}, error) {
+			return operator(nil, nil, parameters)
+		}
+	}
+
+	return func(t *tEvaluableExpression, parameters tParameters) (interface{}, error) {
+		return t.evaluateStage(stage, parameters)
+	}
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"math/rand"
+	"testing"
+)
+
+/*
+Conditions of every shape lowerStages lowers, or declines to.
+*/
+var nativeCorpus = []string{
+	"a > 1 && b < 3",
+	"a >= b || s == t",
+	"!(a == b) && !(s != 'abc')",
+	"(a < 2 || b > 7) && (flag || s <= t)",
+	"a == 'a' || s > 1",
+	"s == nil || a != nil",
+	"flag && flag == true",
+	"!flag || !(!flag)",
+	"user.Age >= 18 && user.Name == 'abc'",
+	"a + b > 5 && len(s) < 3",
+	"(a > 1 ? s : t) == 'a' && b <= 18",
+	"a in (1, 2, 3) && s =~ '^a'",
+	"a > 1 && (b ?? 0) == 0",
+}
+
+/*
+Inputs of conformanceInputs, with parameters of other types mixed in, so that lowered comparisons
+meet operands they must leave to the interpreter.
+*/
+func nativeInputs(count int) []map[string]interface{} {
+
+	inputs := conformanceInputs(count)
+	random := rand.New(rand.NewSource(2))
+
+	others := []interface{}{int64(2), int(-3), float32(2.5), "1", true, nil}
+	names := []string{"a", "b", "s", "t", "flag"}
+
+	for _, parameters := range inputs {
+		if random.Intn(3) == 0 {
+			parameters[names[random.Intn(len(names))]] = others[random.Intn(len(others))]
+		}
+	}
+	return inputs
+}
+
+/*
+Evaluates [expected] and [actual] on each of [inputs], failing unless both give the same results and the same errors.
+*/
+func assertSameEvaluations(test *testing.T, description string, expected *tEvaluableExpression, actual *tEvaluableExpression, inputs []map[string]interface{}) {
+
+	test.Helper()
+
+	for _, parameters := range inputs {
+
+		expectedResult, expectedErr := expected.TEvaluate(parameters)
+		actualResult, actualErr := actual.TEvaluate(parameters)
+
+		if (expectedErr == nil) != (actualErr == nil) || (expectedErr != nil && expectedErr.Error() != actualErr.Error()) {
+			test.Errorf("%s: %s fails differently given %v: %v, and %v", expected.TFormat(), description, parameters, expectedErr, actualErr)
+			return
+		}
+		if conformanceResult(expectedResult) != conformanceResult(actualResult) {
+			test.Errorf("%s: %s gives %v rather than %v given %v", expected.TFormat(), description, actualResult, expectedResult, parameters)
+			return
+		}
+	}
+}
+
+/*
+Compiles [text] both interpreted and with native closures, and checks that they agree on [inputs].
+*/
+func assertNativeClosuresAgree(test *testing.T, text string, inputs []map[string]interface{}) {
+
+	test.Helper()
+
+	native := conformanceOptions
+	native.NativeClosures = true
+
+	interpreted, interpretedErr := TNewEvaluableExpressionWithOptions(text, conformanceOptions)
+	lowered, loweredErr := TNewEvaluableExpressionWithOptions(text, native)
+	if (interpretedErr == nil) != (loweredErr == nil) {
+		test.Fatalf("%s: compiles differently with native closures: %v, and %v", text, interpretedErr, loweredErr)
+	}
+	if interpretedErr != nil {
+		return
+	}
+	assertSameEvaluations(test, "with native closures", interpreted, lowered, inputs)
+}
+
+func TestNativeClosuresAreLowered(test *testing.T) {
+
+	expression, err := TNew("a > 1 && s == 'x'", TWithNativeClosures())
+	if err != nil {
+		test.Fatal(err)
+	}
+	if expression.evaluationStages.native == nil {
+		test.Fatalf("expected the condition to be lowered into a native closure")
+	}
+
+	result, err := expression.TEvaluate(map[string]interface{}{"a": 2.0, "s": "x"})
+	if err != nil || result != true {
+		test.Errorf("expected true, got %v (%v)", result, err)
+	}
+}
+
+func TestNativeLowering(test *testing.T) {
+
+	cases := []struct {
+		expression string
+		options    TExpressionOptions
+		lowered    bool
+	}{
+		{"a > 1", TExpressionOptions{}, true},
+		{"(a > 1)", TExpressionOptions{}, true},
+		{"!(a == b) && !(s != 'abc')", TExpressionOptions{}, true},
+		{"user.Age >= 18 && user.Name == 'abc'", TExpressionOptions{}, true},
+		{"a + b > 5 && len(s) < 3", TExpressionOptions{}, true},
+
+		// only conditions are lowered, and only when each operand of a logical operator is one.
+		{"a + 1", TExpressionOptions{}, false},
+		{"a > 1 ? b : c", TExpressionOptions{}, false},
+		{"flag && a > 1", TExpressionOptions{}, false},
+		{"a in (1, 2, 3) && s =~ '^a'", TExpressionOptions{}, false},
+
+		// conversions of operands are left to the interpreter.
+		{"a > 1 && s == 'x'", TExpressionOptions{CoerceStringers: true}, false},
+	}
+
+	for _, c := range cases {
+
+		options := c.options
+		options.NativeClosures = true
+
+		expression, err := TNewEvaluableExpressionWithOptions(c.expression, options)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		if lowered := expression.evaluationStages.native != nil; lowered != c.lowered {
+			test.Errorf("%s: expected lowering to be %v, got %v", c.expression, c.lowered, lowered)
+		}
+	}
+}
+
+func TestNativeClosuresMatchInterpreter(test *testing.T) {
+
+	inputs := nativeInputs(100)
+	for _, text := range nativeCorpus {
+		assertNativeClosuresAgree(test, text, inputs)
+	}
+}
+
+func TestGeneratedNativeClosuresMatchInterpreter(test *testing.T) {
+
+	inputs := nativeInputs(20)
+	random := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 2000; i++ {
+
+		data := make([]byte, 32)
+		random.Read(data)
+		assertNativeClosuresAgree(test, generateExpression(data), inputs)
+	}
+}
+
+/*
+Compares evaluating [text] interpreted with evaluating it with native closures, given [parameters].
+*/
+func benchmarkNativeClosures(bench *testing.B, text string, parameters map[string]interface{}) {
+
+	for _, native := range []bool{false, true} {
+
+		name := "interpreted"
+		if native {
+			name = "native"
+		}
+
+		bench.Run(name, func(bench *testing.B) {
+
+			expression, err := TNewEvaluableExpressionWithOptions(text, TExpressionOptions{NativeClosures: native})
+			if err != nil {
+				bench.Fatal(err)
+			}
+
+			bench.ReportAllocs()
+			bench.ResetTimer()
+			for i := 0; i < bench.N; i++ {
+				_, err = expression.TEvaluate(parameters)
+				if err != nil {
+					bench.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNativeComparisons(bench *testing.B) {
+	benchmarkNativeClosures(bench, "a > 1 && s == 'x'", map[string]interface{}{"a": 2.0, "s": "x"})
+}
+
+func BenchmarkNativeParameterComparisons(bench *testing.B) {
+	benchmarkNativeClosures(bench, "a >= b || s == t", map[string]interface{}{"a": 1.0, "b": 2.0, "s": "x", "t": "y"})
+}
+
+func BenchmarkNativeAccessors(bench *testing.B) {
+	parameters := map[string]interface{}{"user": conformanceUser{Age: 30, Name: "abc"}}
+	benchmarkNativeClosures(bench, "user.Age >= 18 && user.Name == 'abc'", parameters)
+}
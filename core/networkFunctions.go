@@ -0,0 +1,136 @@
+package core
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+/*
+The optional network functions, by their unqualified names, with the number of string arguments each takes.
+*/
+var networkFunctions = map[string]struct {
+	arity     int
+	calculate func(qualified string, arguments []string) (interface{}, error)
+}{
+	"cidrContains": {2, cidrContains},
+	"isPrivateIP":  {1, isPrivateIP},
+	"ipInRange":    {3, ipInRange},
+}
+
+/*
+Returns the optional network functions named under [namespace], such as "net.cidrContains",
+or unqualified if [namespace] is empty. All are pure, take IPv4 or IPv6 addresses as strings, and return booleans:
+`cidrContains(cidr, ip)` returns whether [ip] is in the block [cidr], such as '10.0.0.0/8';
+`isPrivateIP(ip)` returns whether [ip] is a private address, as in RFC 1918 and RFC 4193;
+and `ipInRange(ip, first, last)` returns whether [ip] lies between [first] and [last], inclusive.
+IPv4 addresses mapped into IPv6, such as '::ffff:10.0.0.1', are treated as IPv4. Invalid addresses fail evaluation.
+*/
+func TNetworkFunctions(namespace string) map[string]tExpressionFunction {
+
+	ret := make(map[string]tExpressionFunction, len(networkFunctions))
+	for name := range networkFunctions {
+		qualified := qualifyName(namespace, name)
+		ret[qualified] = makeNetworkFunction(qualified, name)
+	}
+	return ret
+}
+
+/*
+Registers the functions returned by TNetworkFunctions as pure, for every expression created after this call.
+*/
+func TRegisterNetworkFunctions(namespace string) {
+
+	for name, function := range TNetworkFunctions(namespace) {
+		TRegisterPureFunction(name, function)
+	}
+}
+
+func makeNetworkFunction(qualified string, name string) tExpressionFunction {
+
+	definition := networkFunctions[name]
+
+	return func(arguments ...interface{}) (interface{}, error) {
+
+		if len(arguments) != definition.arity {
+			return nil, fmt.Errorf("%s expects %d arguments, got %d", qualified, definition.arity, len(arguments))
+		}
+
+		values := make([]string, len(arguments))
+		for i, argument := range arguments {
+
+			value, ok := argument.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s expects strings, got '%v'", qualified, argument)
+			}
+			values[i] = value
+		}
+
+		return definition.calculate(qualified, values)
+	}
+}
+
+func cidrContains(qualified string, arguments []string) (interface{}, error) {
+
+	prefix, err := netip.ParsePrefix(arguments[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s expects a CIDR block, got '%s'", qualified, arguments[0])
+	}
+
+	address, err := parseAddress(qualified, arguments[1])
+	if err != nil {
+		return nil, err
+	}
+
+	prefix = prefix.Masked()
+	if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+	return prefix.Contains(address), nil
+}
+
+func isPrivateIP(qualified string, arguments []string) (interface{}, error) {
+
+	address, err := parseAddress(qualified, arguments[0])
+	if err != nil {
+		return nil, err
+	}
+	return address.IsPrivate(), nil
+}
+
+func ipInRange(qualified string, arguments []string) (interface{}, error) {
+
+	var addresses [3]netip.Addr
+
+	for i, argument := range arguments {
+
+		address, err := parseAddress(qualified, argument)
+		if err != nil {
+			return nil, err
+		}
+		addresses[i] = address
+	}
+
+	address, first, last := addresses[0], addresses[1], addresses[2]
+	if first.BitLen() != last.BitLen() {
+		return nil, fmt.Errorf("%s expects both ends of its range to be the same kind of address, got '%s' and '%s'",
+			qualified, arguments[1], arguments[2])
+	}
+
+	// an address of the other kind is never in range, as Compare would order every IPv4 address before every IPv6 one.
+	if address.BitLen() != first.BitLen() {
+		return false, nil
+	}
+	return address.Compare(first) >= 0 && address.Compare(last) <= 0, nil
+}
+
+/*
+Parses [value] as an IP address, unmapping IPv4 addresses which were mapped into IPv6.
+*/
+func parseAddress(qualified string, value string) (netip.Addr, error) {
+
+	address, err := netip.ParseAddr(value)
+	if err != nil {
+		return address, fmt.Errorf("%s expects an IP address, got '%s'", qualified, value)
+	}
+	return address.Unmap(), nil
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+/*
+The three decisions an outcome may carry.
+*/
+const (
+	TDecisionAllow   string = "ALLOW"
+	TDecisionDeny    string = "DENY"
+	TDecisionAbstain string = "ABSTAIN"
+)
+
+/*
+TOutcome is a structured, machine-readable result produced by the `decide(decision, reason)` function.
+*/
+type TOutcome struct {
+	Decision string
+	Reason   string
+}
+
+func (o TOutcome) String() string {
+	return o.Decision + ": " + o.Reason
+}
+
+/*
+tDecisions validates the reason codes given to `decide`, so that downstream systems only ever see reasons they know.
+*/
+type tDecisions struct {
+	reasons map[string]bool
+}
+
+/*
+Creates a set of decision functions which accept only the given reason codes.
+*/
+func TNewDecisions(reasons ...string) *tDecisions {
+
+	ret := &tDecisions{reasons: make(map[string]bool, len(reasons))}
+	for _, reason := range reasons {
+		ret.reasons[reason] = true
+	}
+	return ret
+}
+
+/*
+Returns the functions to give an expression so that it can call `decide(decision, reason)`.
+*/
+func (d *tDecisions) TFunctions() map[string]tExpressionFunction {
+
+	return map[string]tExpressionFunction{
+		"decide": d.decide,
+	}
+}
+
+/*
+Checks every call to `decide` in the given [expression] whose arguments are literals,
+so that unknown decisions or reason codes are rejected before the expression is ever evaluated.
+*/
+func (d *tDecisions) TValidate(expression *tEvaluableExpression) error {
+	return d.validateStage(expression.evaluationStages)
+}
+
+func (d *tDecisions) validateStage(stage *evaluationStage) error {
+
+	if stage == nil {
+		return nil
+	}
+
+	if stage.symbol == tFUNCTIONAL && stage.name == "decide" && stage.rightStage != nil {
+
+		arguments, literal := literalArguments(stage.rightStage)
+		if literal {
+			_, err := d.decide(arguments...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err := d.validateStage(stage.leftStage)
+	if err != nil {
+		return err
+	}
+	return d.validateStage(stage.rightStage)
+}
+
+func (d *tDecisions) decide(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 2 || !isString(arguments[0]) || !isString(arguments[1]) {
+		return nil, errors.New("decide expects a decision and a reason code")
+	}
+
+	decision := arguments[0].(string)
+	reason := arguments[1].(string)
+
+	switch decision {
+	case TDecisionAllow, TDecisionDeny, TDecisionAbstain:
+	default:
+		return nil, fmt.Errorf("Unknown decision '%s', expected one of %s, %s, %s", decision, TDecisionAllow, TDecisionDeny, TDecisionAbstain)
+	}
+
+	if !d.reasons[reason] {
+		return nil, fmt.Errorf("Unknown reason code '%s', expected one of %v", reason, d.sortedReasons())
+	}
+
+	return TOutcome{Decision: decision, Reason: reason}, nil
+}
+
+func (d *tDecisions) sortedReasons() []string {
+
+	ret := make([]string, 0, len(d.reasons))
+	for reason := range d.reasons {
+		ret = append(ret, reason)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+/*
+If [stage] is a literal, or a (possibly parenthesized) list of literals, returns their values.
+*/
+func literalArguments(stage *evaluationStage) ([]interface{}, bool) {
+
+	switch stage.symbol {
+	case tNOOP:
+		if stage.rightStage == nil {
+			return nil, true
+		}
+		return literalArguments(stage.rightStage)
+
+	case tSEPARATE:
+		left, literal := literalArguments(stage.leftStage)
+		if !literal {
+			return nil, false
+		}
+		right, literal := literalArguments(stage.rightStage)
+		if !literal {
+			return nil, false
+		}
+		return append(left, right...), true
+
+	case tLITERAL:
+		value, err := stage.operator(nil, nil, nil)
+		if err != nil {
+			return nil, false
+		}
+		return []interface{}{value}, true
+	}
+
+	return nil, false
+}
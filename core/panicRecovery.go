@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+/*
+TPanicError is returned by evaluations in which a function, or the reflection behind an accessor, panicked -
+as user-supplied code may, by dereferencing a nil pointer or the like - rather than letting the panic crash the caller.
+*/
+type TPanicError struct {
+	Message string
+
+	// the value the panic was called with.
+	Value interface{}
+
+	// the stack of the goroutine which panicked, as of the panic.
+	Stack []byte
+}
+
+func (e TPanicError) Error() string {
+	return e.Message
+}
+
+/*
+Returns a TPanicError for the panic [recovered], described by [message]. Must be called by the deferred function which recovered it,
+so that the stack is that of the panic.
+*/
+func newPanicError(recovered interface{}, message string) TPanicError {
+
+	return TPanicError{
+		Message: message,
+		Value:   recovered,
+		Stack:   debug.Stack(),
+	}
+}
+
+/*
+Calls the function of the function call [stage] with the arguments evaluated into [right],
+returning a TPanicError if it panics.
+*/
+func callFunction(stage *evaluationStage, right interface{}, parameters tParameters) (ret interface{}, err error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			ret = nil
+			err = newPanicError(recovered, fmt.Sprintf("Function '%s' panicked: %v", stage.name, recovered))
+		}
+	}()
+
+	return stage.operator(nil, right, parameters)
+}
@@ -2,6 +2,8 @@ package core
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
 /*
@@ -19,14 +21,189 @@ type tParameters interface {
 
 type tMapParameters map[string]interface{}
 
+/*
+Returns tParameters backed by the given map.
+*/
+func TNewMapParameters(values map[string]interface{}) tParameters {
+	return tMapParameters(values)
+}
+
 func (p tMapParameters) tGet(name string) (interface{}, error) {
 
 	value, found := p[name]
 
 	if !found {
-		errorMessage := "No parameter '" + name + "' found."
-		return nil, errors.New(errorMessage)
+		return nil, tMissingParameterError{name}
 	}
 
 	return value, nil
 }
+
+/*
+The error returned by tParameters implementations when the requested parameter does not exist,
+as opposed to existing but failing to be retrieved.
+*/
+type tMissingParameterError struct {
+	name string
+}
+
+func (e tMissingParameterError) Error() string {
+	return "No parameter '" + e.name + "' found."
+}
+
+func isMissingParameter(err error) bool {
+
+	var missing tMissingParameterError
+	return errors.As(err, &missing)
+}
+
+/*
+lenientParameters is a wrapper for tParameters which resolves missing parameters to nil.
+*/
+type lenientParameters struct {
+	orig tParameters
+}
+
+func (p lenientParameters) tGet(name string) (interface{}, error) {
+
+	value, err := p.orig.tGet(name)
+	if err != nil && isMissingParameter(err) {
+		return nil, nil
+	}
+
+	return value, err
+}
+
+/*
+tChainedParameters consults an ordered list of parameter sources, returning the value from the first source which has it.
+Sources which fail for any reason other than a missing parameter abort the lookup.
+*/
+type tChainedParameters []tParameters
+
+/*
+Returns tParameters which consult each of the given [sources] in order, such as per-request values followed by defaults.
+*/
+func TNewChainedParameters(sources ...tParameters) tParameters {
+	return tChainedParameters(sources)
+}
+
+func (p tChainedParameters) tGet(name string) (interface{}, error) {
+
+	for _, source := range p {
+
+		if source == nil {
+			continue
+		}
+
+		value, err := source.tGet(name)
+		if err == nil {
+			return value, nil
+		}
+
+		if !isMissingParameter(err) {
+			return nil, err
+		}
+	}
+
+	return nil, tMissingParameterError{name}
+}
+
+/*
+inexactParameters is a wrapper for tParameters which, failing an exact match, finds parameters whose names [match] the one requested,
+such as regardless of case, or once normalized.
+Only map-backed sources (including those chained together) can be searched this way; other sources need exact names.
+*/
+type inexactParameters struct {
+	orig  tParameters
+	match func(key string, name string) bool
+}
+
+func (p inexactParameters) tGet(name string) (interface{}, error) {
+	return getInexactParameter(p.orig, name, p.match)
+}
+
+/*
+Returns how parameter names given at evaluation are matched to those used in an expression with the given [options],
+or nil if they must match exactly.
+*/
+func parameterNameMatcher(options TExpressionOptions) func(string, string) bool {
+
+	normalize := options.NormalizeNames
+
+	switch {
+	case normalize != nil && options.CaseInsensitiveNames:
+		return func(key string, name string) bool { return strings.EqualFold(normalize(key), name) }
+	case normalize != nil:
+		return func(key string, name string) bool { return normalize(key) == name }
+	case options.CaseInsensitiveNames:
+		return strings.EqualFold
+	}
+	return nil
+}
+
+func getInexactParameter(parameters tParameters, name string, match func(string, string) bool) (interface{}, error) {
+
+	switch typed := parameters.(type) {
+	case tMapParameters:
+		value, found := typed[name]
+		if found {
+			return value, nil
+		}
+
+		var matched string
+		for key, candidate := range typed {
+
+			if !match(key, name) {
+				continue
+			}
+			if matched != "" {
+				first, second := matched, key
+				if second < first {
+					first, second = second, first
+				}
+				return nil, fmt.Errorf("Parameter '%s' is ambiguous, it matches both '%s' and '%s'", name, first, second)
+			}
+			matched = key
+			value = candidate
+		}
+
+		if matched == "" {
+			return nil, tMissingParameterError{name}
+		}
+		return value, nil
+
+	case tChainedParameters:
+		for _, source := range typed {
+
+			if source == nil {
+				continue
+			}
+
+			value, err := getInexactParameter(source, name, match)
+			if err == nil {
+				return value, nil
+			}
+			if !isMissingParameter(err) {
+				return nil, err
+			}
+		}
+		return nil, tMissingParameterError{name}
+
+	case boundParameters:
+		// look through the binding, while keeping it for each of the sources underneath.
+		switch orig := typed.orig.(type) {
+		case tMapParameters:
+			return getInexactParameter(orig, name, match)
+		case tChainedParameters:
+			bound := make(tChainedParameters, len(orig))
+			for i, source := range orig {
+				if source != nil {
+					bound[i] = boundParameters{ctx: typed.ctx, orig: source}
+				}
+			}
+			return getInexactParameter(bound, name, match)
+		}
+	}
+
+	return parameters.tGet(name)
+}
@@ -9,9 +9,10 @@ import (
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 )
 
-func parseTokens(expression string, functions map[string]tExpressionFunction) ([]tExpressionToken, error) {
+func parseTokens(expression string, functions map[string]tExpressionFunction, options TExpressionOptions) ([]tExpressionToken, error) {
 
 	var ret []tExpressionToken
 	var token tExpressionToken
@@ -23,36 +24,82 @@ func parseTokens(expression string, functions map[string]tExpressionFunction) ([
 	stream = newLexerStream(expression)
 	state = validLexerStates[0]
 
+	var errs []error
+
 	for stream.canRead() {
 
-		token, err, found = readToken(stream, state, functions)
+		start := stream.position
+		token, err, found = readToken(stream, state, functions, options)
+
+		var expanded []tExpressionToken
+		if err == nil && found {
+			expanded, err = expandToken(token, functions, options)
+		}
 
 		if err != nil {
-			return ret, err
+			if !options.CollectParseErrors {
+				return ret, err
+			}
+
+			// readToken has already consumed the offending token, so lexing can carry on after it.
+			errs = append(errs, TParseError{Position: skipSpaces(stream.source, start), Message: err.Error()})
+			continue
 		}
 
 		if !found {
 			break
 		}
 
+		token = expanded[len(expanded)-1]
 		state, err = getLexerStateForToken(token.Kind)
 		if err != nil {
 			return ret, err
 		}
 
 		// append this valid token
-		ret = append(ret, token)
+		ret = append(ret, expanded...)
 	}
 
 	err = checkBalance(ret)
 	if err != nil {
-		return nil, err
+		if len(errs) == 0 {
+			return nil, err
+		}
+		errs = append(errs, err)
 	}
 
+	if len(errs) > 0 {
+		return nil, TMultiError{Errors: errs}
+	}
 	return ret, nil
 }
 
-func readToken(stream *lexerStream, state lexerState, functions map[string]tExpressionFunction) (tExpressionToken, error, bool) {
+/*
+TParseError is a single invalid token, as reported by parsing with TExpressionOptions.CollectParseErrors.
+*/
+type TParseError struct {
+
+	// the offset, in runes, of the start of the invalid token.
+	Position int
+	Message  string
+}
+
+func (e TParseError) Error() string {
+	return fmt.Sprintf("%s (at %d)", strings.TrimSuffix(e.Message, "\n"), e.Position)
+}
+
+/*
+Returns the position of the first non-space rune of [source] at or after [position].
+*/
+func skipSpaces(source []rune, position int) int {
+
+	for position < len(source) && unicode.IsSpace(source[position]) {
+		position++
+	}
+	return position
+}
+
+func readToken(stream *lexerStream, state lexerState, functions map[string]tExpressionFunction, options TExpressionOptions) (tExpressionToken, error, bool) {
 
 	var function tExpressionFunction
 	var ret tExpressionToken
@@ -64,6 +111,7 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]tExpr
 	var found bool
 	var completed bool
 	var err error
+	var start int
 
 	// numeric is 0-9, or . or 0x followed by digits
 	// string starts with '
@@ -79,6 +127,7 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]tExpr
 			continue
 		}
 
+		start = stream.position - 1
 		kind = tUNKNOWN
 
 		// numeric constant
@@ -96,21 +145,36 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]tExpr
 						return tExpressionToken{}, errors.New(errorMsg), false
 					}
 
+					// larger values are kept exact for the bitwise operators, and planned as float64s anywhere else.
 					kind = tNUMERIC
-					tokenValue = float64(tokenValueInt)
+					tokenValue = bitwiseOperand{bits: tokenValueInt, unsigned: true}.value()
 					break
 				} else {
 					stream.rewind(1)
 				}
 			}
 
-			tokenString = readTokenUntilFalse(stream, isNumeric)
+			if options.LocaleNumbers {
+				stream.rewind(1)
+				tokenString, err = readLocaleNumber(stream)
+				if err != nil {
+					return tExpressionToken{}, err, false
+				}
+			} else {
+				tokenString = readTokenUntilFalse(stream, isNumeric)
+			}
 			tokenValue, err = strconv.ParseFloat(tokenString, 64)
 
 			if err != nil {
 				errorMsg := fmt.Sprintf("Unable to parse numeric value '%v' to float64\n", tokenString)
 				return tExpressionToken{}, errors.New(errorMsg), false
 			}
+
+			// a duration, such as `90d`, is its number of seconds - as time literals are.
+			unit, found := readDurationUnit(stream)
+			if found {
+				tokenValue = tokenValue.(float64) * unit
+			}
 			kind = tNUMERIC
 			break
 		}
@@ -126,22 +190,65 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]tExpr
 		// escaped variable
 		if character == '[' {
 
-			tokenValue, completed = readUntilFalse(stream, true, false, true, isNotClosingBracket)
+			tokenValue, completed = readBracketedName(stream)
 			kind = tVARIABLE
 
 			if !completed {
 				return tExpressionToken{}, errors.New("Unclosed parameter bracket"), false
 			}
 
-			// above method normally rewinds us to the closing bracket, which we want to skip.
-			stream.rewind(-1)
+			tokenValue = normalizeName(tokenValue.(string), options)
+
+			// display names stand in for the parameter or accessor they alias.
+			alias, found := options.Aliases[tokenValue.(string)]
+			if found {
+				tokenValue = alias
+				if strings.Contains(alias, ".") {
+					kind = tACCESSOR
+					tokenValue = strings.Split(alias, ".")
+				}
+			}
+
+			// a bracketed name may be followed by fields, such as `[user profile].Address.City`.
+			if stream.canRead() && stream.source[stream.position] == '.' {
+
+				stream.readCharacter()
+				fields := normalizeName(readTokenUntilFalse(stream, isVariableName), options)
+				path, err := parseAccessorFields(fields, options)
+				if err != nil {
+					return tExpressionToken{}, err, false
+				}
+
+				if kind == tACCESSOR {
+					tokenValue = append(tokenValue.([]string), path...)
+				} else {
+					tokenValue = append([]string{tokenValue.(string)}, path...)
+				}
+				kind = tACCESSOR
+			}
+			break
+		}
+
+		// scoped function, such as the rule set's `@table`
+		if character == '@' {
+
+			tokenString, _ = readUntilFalse(stream, false, true, false, isVariableName)
+			tokenString = "@" + tokenString
+
+			function, found = functions[tokenString]
+			if !found {
+				return tExpressionToken{}, errors.New("Undefined function " + tokenString), false
+			}
+
+			kind = tFUNCTION
+			tokenValue = tNamedFunction{name: tokenString, function: function, pure: isPureFunction(tokenString, options), position: start}
 			break
 		}
 
 		// regular variable - or function?
 		if unicode.IsLetter(character) {
 
-			tokenString = readTokenUntilFalse(stream, isVariableName)
+			tokenString = normalizeName(readTokenUntilFalse(stream, isVariableName), options)
 
 			tokenValue = tokenString
 			kind = tVARIABLE
@@ -160,6 +267,13 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]tExpr
 				}
 			}
 
+			// nil literal?
+			if options.NilLiteral && tokenValue == "nil" {
+
+				kind = tNIL
+				tokenValue = nil
+			}
+
 			// textual operator?
 			if tokenValue == "in" || tokenValue == "tIN" {
 
@@ -168,11 +282,47 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]tExpr
 				kind = tCOMPARATOR
 			}
 
-			// function?
+			// word forms of logical operators, where an operator may come next.
+			if options.WordOperators {
+
+				word := strings.ToLower(tokenString)
+				if logicalWords[word] != "" && state.canTransitionTo(tLOGICALOP) {
+					kind = tLOGICALOP
+					tokenValue = logicalWords[word]
+					break
+				}
+				if word == "not" && state.canTransitionTo(tPREFIX) {
+					kind = tPREFIX
+					tokenValue = "!"
+					break
+				}
+			}
+
+			// SQL-style `is null` or `is not null`, expanded into a comparison with nil by expandToken.
+			if strings.EqualFold(tokenString, "is") && state.canTransitionTo(tCOMPARATOR) {
+
+				predicate, found := readNullPredicate(stream)
+				if found {
+					ret.Kind = tCOMPARATOR
+					ret.Value = predicate
+					return ret, nil, true
+				}
+			}
+
+			// function? Registered functions may be namespaced with dots (`math.abs`), and take precedence over accessors.
+			// Built-in functions are only functions where they are called, so that parameters may share their names,
+			// as in `count > 5`.
 			function, found = functions[tokenString]
-			if found {
+			if found && (!isBuiltinFunction(tokenString) || stream.peekPastSpace() == '(') {
 				kind = tFUNCTION
-				tokenValue = function
+				tokenValue = tNamedFunction{
+					name:     tokenString,
+					function: function,
+					pure:     isPureFunction(tokenString, options),
+					builtin:  isBuiltinImplementation(tokenString, function),
+					position: start,
+				}
+				break
 			}
 
 			// accessor?
@@ -189,32 +339,60 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]tExpr
 				splits := strings.Split(tokenString, ".")
 				tokenValue = splits
 
-				// check that none of them are unexported
-				for i := 1; i < len(splits); i++ {
-
-					firstCharacter := getFirstRune(splits[i])
-
-					if unicode.ToUpper(firstCharacter) != firstCharacter {
-						errorMsg := fmt.Sprintf("Unable to access unexported field '%s' in token '%s'", splits[i], tokenString)
-						return tExpressionToken{}, errors.New(errorMsg), false
-					}
+				err = checkAccessorFields(splits[1:], tokenString, options)
+				if err != nil {
+					return tExpressionToken{}, err, false
 				}
 			}
 			break
 		}
 
-		if !isNotQuote(character) {
-			tokenValue, completed = readUntilFalse(stream, true, false, true, isNotQuote)
+		if !isNotQuote(character) || character == '`' {
 
-			if !completed {
-				return tExpressionToken{}, errors.New("Unclosed string literal"), false
-			}
+			if character == '`' {
+				tokenValue, err = readRawStringLiteral(stream)
+				if err != nil {
+					return tExpressionToken{}, err, false
+				}
+
+				// raw strings are taken as they are written, so are never times.
+				kind = tSTRING
+				break
+			} else if options.LegacyStringEscapes {
+				tokenValue, completed = readUntilFalse(stream, true, false, true, isNotQuote)
+
+				if !completed {
+					return tExpressionToken{}, errors.New("Unclosed string literal"), false
+				}
+
+				// advance the stream one position, since reading until false assumes the terminator is a real token
+				stream.rewind(-1)
+			} else {
+				literal, err := readStringLiteral(stream, options.Interpolation && character == '"')
+				if err != nil {
+					return tExpressionToken{}, err, false
+				}
 
-			// advance the stream one position, since reading until false assumes the terminator is a real token
-			stream.rewind(-1)
+				// interpolated strings are expanded into the expressions they stand for by expandToken.
+				if len(literal.expressions) > 0 {
+					ret.Kind = tSTRING
+					ret.Value = literal
+					return ret, nil, true
+				}
+				tokenValue = literal.literals[0]
+			}
 
-			// check to see if this can be parsed as a time.
-			tokenTime, found = tryParseTime(tokenValue.(string))
+			// check to see if this can be parsed as a time, in one of the given formats before the standard ones.
+			found = false
+			for _, format := range options.DateFormats {
+				tokenTime, found = tryParseExactTime(tokenValue.(string), format)
+				if found {
+					break
+				}
+			}
+			if !found {
+				tokenTime, found = tryParseTime(tokenValue.(string))
+			}
 			if found {
 				kind = tTIME
 				tokenValue = tokenTime
@@ -236,6 +414,18 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]tExpr
 			break
 		}
 
+		// pattern literal, such as `/^a\d+$/i`. Where an operator is expected instead, `/` divides.
+		if character == '/' && state.canTransitionTo(tPREFIX) && state.canTransitionTo(tPATTERN) {
+
+			tokenValue, err = readPatternLiteral(stream)
+			if err != nil {
+				return tExpressionToken{}, err, false
+			}
+
+			kind = tPATTERN
+			break
+		}
+
 		// must be a known symbol
 		tokenString = readTokenUntilFalse(stream, isNotAlphanumeric)
 		tokenValue = tokenString
@@ -288,6 +478,351 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]tExpr
 	return ret, nil, (kind != tUNKNOWN)
 }
 
+/*
+tNullPredicate is an SQL-style `is null` (or, if negated, `is not null`) predicate.
+*/
+type tNullPredicate struct {
+	negated bool
+}
+
+/*
+Reads the rest of an `is null` or `is not null` predicate, whose `is` has already been read, regardless of case.
+If the words which follow are not `null` or `not null`, the stream is left untouched and false is returned.
+*/
+func readNullPredicate(stream *lexerStream) (tNullPredicate, bool) {
+
+	start := stream.position
+
+	word := readWord(stream)
+	negated := strings.EqualFold(word, "not")
+	if negated {
+		word = readWord(stream)
+	}
+
+	if !strings.EqualFold(word, "null") {
+		stream.position = start
+		return tNullPredicate{}, false
+	}
+	return tNullPredicate{negated: negated}, true
+}
+
+/*
+Skips any spaces, then reads the variable name which follows them, if any.
+*/
+func readWord(stream *lexerStream) string {
+
+	stream.position = skipSpaces(stream.source, stream.position)
+
+	start := stream.position
+	for stream.canRead() && isVariableName(stream.source[stream.position]) {
+		stream.position++
+	}
+	return string(stream.source[start:stream.position])
+}
+
+/*
+Splits [fields], such as ".Address.City", which follow a bracketed name, into the names of each field.
+*/
+func parseAccessorFields(fields string, options TExpressionOptions) ([]string, error) {
+
+	splits := strings.Split(fields, ".")[1:]
+	for _, field := range splits {
+		if field == "" {
+			return nil, fmt.Errorf("Hanging accessor on token '%s'", fields)
+		}
+	}
+
+	err := checkAccessorFields(splits, fields, options)
+	if err != nil {
+		return nil, err
+	}
+	return splits, nil
+}
+
+/*
+Checks that none of the given [fields] of an accessor are unexported, unless they may be resolved by struct tag or regardless of case.
+*/
+func checkAccessorFields(fields []string, token string, options TExpressionOptions) error {
+
+	if options.TagName != "" || options.CaseInsensitiveNames {
+		return nil
+	}
+
+	for _, field := range fields {
+
+		firstCharacter := getFirstRune(field)
+
+		if unicode.ToUpper(firstCharacter) != firstCharacter {
+			errorMsg := fmt.Sprintf("Unable to access unexported field '%s' in token '%s'", field, token)
+			return errors.New(errorMsg)
+		}
+	}
+	return nil
+}
+
+func isPureFunction(name string, options TExpressionOptions) bool {
+
+	for _, pure := range options.PureFunctions {
+		if pure == name {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Reads a numeric literal as written under TExpressionOptions.LocaleNumbers, returning it in the form strconv expects.
+A comma or period directly between two digits is a decimal mark, and may appear only once; a comma followed by anything else
+separates values, as in `max(1,5, 2)`, which is given 1.5 and 2.
+A thin space directly between two digits groups them, and is dropped.
+*/
+func readLocaleNumber(stream *lexerStream) (string, error) {
+
+	var ret strings.Builder
+	var decimal bool
+
+	start := stream.position
+
+	for stream.canRead() {
+
+		character := stream.source[stream.position]
+		between := ret.Len() > 0 && stream.position+1 < stream.length && unicode.IsDigit(stream.source[stream.position+1])
+
+		switch {
+		case unicode.IsDigit(character):
+			ret.WriteRune(character)
+
+		case (character == ',' || character == '.') && between:
+			if decimal {
+				literal := string(stream.source[start : stream.position+2])
+				return "", fmt.Errorf("Ambiguous numeric literal '%s', which has more than one decimal mark; separate values with ', '", literal)
+			}
+			decimal = true
+			ret.WriteRune('.')
+
+		case isGroupingSpace(character) && between:
+
+		case character == '.':
+			// as without LocaleNumbers, a trailing period is left for strconv to accept or reject.
+			ret.WriteRune(character)
+
+		default:
+			return ret.String(), nil
+		}
+
+		stream.position++
+	}
+
+	return ret.String(), nil
+}
+
+/*
+The units of duration literals, by suffix, in seconds.
+*/
+var durationUnits = map[rune]float64{
+	's': 1,
+	'm': 60,
+	'h': 60 * 60,
+	'd': 24 * 60 * 60,
+	'w': 7 * 24 * 60 * 60,
+}
+
+/*
+Reads the unit which directly follows a number, if it is a duration such as `90d`, and returns the seconds in one of that unit.
+*/
+func readDurationUnit(stream *lexerStream) (float64, bool) {
+
+	if !stream.canRead() {
+		return 0, false
+	}
+
+	unit, found := durationUnits[stream.source[stream.position]]
+	if !found {
+		return 0, false
+	}
+
+	// `5min` is not a duration, but a syntax error.
+	next := stream.position + 1
+	if next < stream.length && isVariableName(stream.source[next]) {
+		return 0, false
+	}
+
+	stream.position = next
+	return unit, true
+}
+
+/*
+Returns whether [character] is a thin space, as used to group the digits of numbers in many locales.
+*/
+func isGroupingSpace(character rune) bool {
+	return character == '\u2009' || character == '\u202f'
+}
+
+/*
+Reads the rest of a string literal whose opening quote has already been read, up to and including its closing quote.
+Backslashes introduce `\n`, `\t`, `\r`, and `\uXXXX` escapes; before any other character, they simply escape it,
+so that `\\`, `\'`, and `\"` stand for the character itself.
+If [interpolate] is set, each `${...}` in the literal is read as the source of an interpolated expression.
+*/
+func readStringLiteral(stream *lexerStream, interpolate bool) (tInterpolation, error) {
+
+	var ret tInterpolation
+	var value strings.Builder
+
+	for stream.canRead() {
+
+		character := stream.readCharacter()
+
+		if !isNotQuote(character) {
+			ret.literals = append(ret.literals, value.String())
+			return ret, nil
+		}
+
+		if interpolate && character == '$' && stream.canRead() && stream.source[stream.position] == '{' {
+
+			stream.readCharacter()
+			source, err := readInterpolatedSource(stream)
+			if err != nil {
+				return ret, err
+			}
+
+			ret.literals = append(ret.literals, value.String())
+			ret.expressions = append(ret.expressions, source)
+			value.Reset()
+			continue
+		}
+
+		if character != '\\' {
+			value.WriteRune(character)
+			continue
+		}
+
+		if !stream.canRead() {
+			break
+		}
+
+		character = stream.readCharacter()
+		switch character {
+		case 'n':
+			value.WriteRune('\n')
+		case 't':
+			value.WriteRune('\t')
+		case 'r':
+			value.WriteRune('\r')
+		case 'u':
+			code, err := readUnicodeEscape(stream)
+			if err != nil {
+				return ret, err
+			}
+			value.WriteRune(code)
+		default:
+			value.WriteRune(character)
+		}
+	}
+
+	return ret, errors.New("Unclosed string literal")
+}
+
+/*
+Reads the rest of a raw string literal whose opening backtick has already been read, up to and including its closing backtick.
+Nothing inside is escaped, so that patterns and paths such as `C:\temp\d+` can be written as-is,
+and raw strings are never read as times, so that strings such as `2024-01-01` can be written too.
+*/
+func readRawStringLiteral(stream *lexerStream) (string, error) {
+
+	var value strings.Builder
+
+	for stream.canRead() {
+
+		character := stream.readCharacter()
+		if character == '`' {
+			return value.String(), nil
+		}
+		value.WriteRune(character)
+	}
+
+	return "", errors.New("Unclosed raw string literal")
+}
+
+/*
+Reads the four hexadecimal digits of a `\uXXXX` escape, whose `\u` has already been read.
+*/
+func readUnicodeEscape(stream *lexerStream) (rune, error) {
+
+	var digits string
+	for len(digits) < 4 && stream.canRead() && unicode.Is(unicode.ASCII_Hex_Digit, stream.source[stream.position]) {
+		digits += string(stream.readCharacter())
+	}
+
+	code, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || len(digits) < 4 || !utf8.ValidRune(rune(code)) {
+		return 0, fmt.Errorf("Invalid escape sequence '\\u%s' in string literal", digits)
+	}
+	return rune(code), nil
+}
+
+/*
+Reads the rest of a pattern literal whose opening `/` has already been read, and compiles it.
+Only `\/` is unescaped; every other backslash is left for the regular expression itself.
+Trailing flags `i`, `m`, and `s` are passed on to the regular expression as `(?ims)`.
+*/
+func readPatternLiteral(stream *lexerStream) (*regexp.Regexp, error) {
+
+	var pattern strings.Builder
+	var closed bool
+
+	for stream.canRead() {
+
+		character := stream.readCharacter()
+
+		if character == '\\' && stream.canRead() {
+
+			next := stream.readCharacter()
+			if next != '/' {
+				pattern.WriteRune(character)
+			}
+			pattern.WriteRune(next)
+			continue
+		}
+
+		if character == '/' {
+			closed = true
+			break
+		}
+		pattern.WriteRune(character)
+	}
+
+	if !closed {
+		return nil, errors.New("Unclosed pattern literal")
+	}
+
+	// flags must immediately follow the closing slash.
+	var flags string
+	for stream.canRead() {
+
+		flag := stream.readCharacter()
+		if !unicode.IsLetter(flag) {
+			stream.rewind(1)
+			break
+		}
+		if !strings.ContainsRune("ims", flag) {
+			return nil, fmt.Errorf("Unknown pattern flag '%c', expected i, m, or s", flag)
+		}
+		flags += string(flag)
+	}
+
+	source := pattern.String()
+	if flags != "" {
+		source = "(?" + flags + ")" + source
+	}
+
+	compiled, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to compile pattern literal '/%s/%s': %v", pattern.String(), flags, err)
+	}
+	return compiled, nil
+}
+
 func readTokenUntilFalse(stream *lexerStream, condition func(rune) bool) string {
 
 	var ret string
@@ -314,7 +849,7 @@ func readUntilFalse(stream *lexerStream, includeWhitespace bool, breakWhitespace
 		character = stream.readCharacter()
 
 		// Use backslashes to escape anything
-		if allowEscaping && character == '\\' {
+		if allowEscaping && character == '\\' && stream.canRead() {
 
 			character = stream.readCharacter()
 			tokenBuffer.WriteString(string(character))
@@ -455,14 +990,69 @@ func isNotAlphanumeric(character rune) bool {
 func isVariableName(character rune) bool {
 
 	return unicode.IsLetter(character) ||
+		unicode.IsMark(character) ||
 		unicode.IsDigit(character) ||
 		character == '_' ||
 		character == '.'
 }
 
-func isNotClosingBracket(character rune) bool {
+/*
+Returns [name] as normalized by TExpressionOptions.NormalizeNames, if set.
+*/
+func normalizeName(name string, options TExpressionOptions) string {
+
+	if options.NormalizeNames == nil {
+		return name
+	}
+	return options.NormalizeNames(name)
+}
+
+/*
+Reads the rest of a bracketed name, such as `[items[0].sku]`, up to and including its closing bracket.
+Brackets within the name open and close indices, so that a path such as `items[0].sku` may be written without escaping,
+and a quoted index, such as `['a]']`, may contain any brackets. Any character may still be escaped with a backslash;
+an escaped `]` closes an open index, so that names escaped as `[items[0\].sku]` read the same as they always have.
+Returns false if the name is not closed.
+*/
+func readBracketedName(stream *lexerStream) (string, bool) {
+
+	var tokenBuffer bytes.Buffer
+	var quote rune
+	depth := 0
+
+	for stream.canRead() {
+
+		character := stream.readCharacter()
+
+		if character == '\\' && stream.canRead() {
+
+			character = stream.readCharacter()
+			if character == ']' && depth > 0 && quote == 0 {
+				depth--
+			}
+			tokenBuffer.WriteRune(character)
+			continue
+		}
+
+		switch {
+		case quote != 0:
+			if character == quote {
+				quote = 0
+			}
+		case depth > 0 && (character == '\'' || character == '"'):
+			quote = character
+		case character == '[':
+			depth++
+		case character == ']':
+			if depth == 0 {
+				return tokenBuffer.String(), true
+			}
+			depth--
+		}
+		tokenBuffer.WriteRune(character)
+	}
 
-	return character != ']'
+	return tokenBuffer.String(), false
 }
 
 /*
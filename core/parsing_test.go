@@ -0,0 +1,106 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestNilIsAParameterByDefault(test *testing.T) {
+
+	parameters := map[string]interface{}{"a": 1.0, "nil": 1.0}
+
+	for _, text := range []string{"a == nil", "a == [nil]"} {
+
+		expression, err := TNewEvaluableExpression(text)
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+		result, err := expression.TEvaluate(parameters)
+		if err != nil || result != true {
+			test.Errorf("%s: expected the parameter named nil to be compared, got %v (%v)", text, result, err)
+		}
+	}
+}
+
+func TestNilLiteral(test *testing.T) {
+
+	options := TExpressionOptions{NilLiteral: true, LenientParameters: true}
+	parameters := map[string]interface{}{"a": 1.0, "nil": 1.0}
+
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"a == nil", false},
+		{"missing == nil", true},
+		{"a == [nil]", true},
+		{"nil ?? a", 1.0},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpressionWithOptions(c.expression, options)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		result, err := expression.TEvaluate(parameters)
+		if err != nil || result != c.expected {
+			test.Errorf("%s: expected %v, got %v (%v)", c.expression, c.expected, result, err)
+		}
+	}
+}
+
+func TestPartialEvaluationOnlySubstitutesNilLiterals(test *testing.T) {
+
+	expression, err := TNewEvaluableExpression("a ?? b")
+	if err != nil {
+		test.Fatal(err)
+	}
+	partial, err := expression.TPartialEvaluate(map[string]interface{}{"a": nil})
+	if err != nil {
+		test.Fatal(err)
+	}
+	if partial.TFormat() != "a ?? b" {
+		test.Errorf("expected nil not to be substituted without NilLiteral, got %s", partial.TFormat())
+	}
+
+	expression, err = TNewEvaluableExpressionWithOptions("a ?? b", TExpressionOptions{NilLiteral: true})
+	if err != nil {
+		test.Fatal(err)
+	}
+	partial, err = expression.TPartialEvaluate(map[string]interface{}{"a": nil})
+	if err != nil {
+		test.Fatal(err)
+	}
+	if partial.TFormat() != "nil ?? b" {
+		test.Errorf("expected nil to be substituted with NilLiteral, got %s", partial.TFormat())
+	}
+}
+
+func TestParametersMayShareBuiltinNames(test *testing.T) {
+
+	parameters := map[string]interface{}{"count": 7.0, "max": 5.0, "min": 2.0, "len": 3.0, "now": 9.0, "matches": true}
+
+	cases := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"count > 5", true},
+		{"max - min", 3.0},
+		{"len == 3", true},
+		{"now > 5", true},
+		{"matches", true},
+		{"max(count, len) + len ('abc')", 10.0},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpression(c.expression)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		result, err := expression.TEvaluate(parameters)
+		if err != nil || result != c.expected {
+			test.Errorf("%s: expected %v, got %v (%v)", c.expression, c.expected, result, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package core
+
+/*
+Substitutes the given [parameters] into this expression, and re-plans it so that everything which only depended on them
+is folded into literals. Returns a new expression which needs only the remaining parameters.
+This expression is left unchanged.
+
+Only values which can be written as literals (numbers, strings, bools, and nil, if NilLiteral is set) are substituted;
+parameters of any other type are left for the new expression to be given again.
+*/
+func (t tEvaluableExpression) TPartialEvaluate(parameters map[string]interface{}) (*tEvaluableExpression, error) {
+
+	var err error
+
+	tokens := make([]tExpressionToken, len(t.tokens))
+	copy(tokens, t.tokens)
+
+	for index, token := range tokens {
+
+		var value interface{}
+		var found bool
+
+		switch token.Kind {
+		case tVARIABLE:
+			value, found = parameters[token.Value.(string)]
+
+		case tACCESSOR:
+			// method calls may have side effects, or depend on arguments, so only fields are substituted.
+			if index+1 < len(tokens) && tokens[index+1].Kind == tCLAUSE {
+				continue
+			}
+
+			path := token.Value.([]string)
+			_, found = parameters[path[0]]
+			if found {
+				value, err = makeAccessorStage(path, t.options)(nil, nil, tMapParameters(parameters))
+				found = err == nil
+			}
+		}
+
+		if !found {
+			continue
+		}
+
+		literal, isLiteral := literalToken(castToFloat64(value))
+		if isLiteral && (literal.Kind != tNIL || t.options.NilLiteral) {
+			tokens[index] = literal
+		}
+	}
+
+	err = checkExpressionSyntax(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := t
+	ret.tokens = tokens
+	ret.inputExpression = renderTokens(tokens, nil)
+
+	ret.evaluationStages, err = planStages(tokens, t.options)
+	if err != nil {
+		return nil, err
+	}
+	ret.memoSlots = countMemoSlots(ret.evaluationStages)
+
+	if ret.referenceStages != nil {
+		ret.referenceStages, err = planReferenceStages(tokens, t.options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ret, nil
+}
+
+/*
+Returns the token which, when parsed, would have produced the given [value] - if there is one.
+*/
+func literalToken(value interface{}) (tExpressionToken, bool) {
+
+	switch value.(type) {
+	case float64:
+		return tExpressionToken{Kind: tNUMERIC, Value: value}, true
+	case string:
+		return tExpressionToken{Kind: tSTRING, Value: value}, true
+	case bool:
+		return tExpressionToken{Kind: tBOOLEAN, Value: value}, true
+	case nil:
+		return tExpressionToken{Kind: tNIL}, true
+	}
+	return tExpressionToken{}, false
+}
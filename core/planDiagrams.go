@@ -0,0 +1,111 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Renders the planned stages of this expression as a Graphviz (dot) digraph, as evaluated: after operators are
+reordered for precedence, and literals are folded. Operands are numbered in the order they are evaluated,
+and literals folded from other stages are shaded, and say what they were folded from.
+*/
+func (t tEvaluableExpression) TExplainDot() string {
+
+	var buffer strings.Builder
+	buffer.WriteString("digraph expression {\n\tnode [shape=box];\n")
+
+	explainStages(t.evaluationStages, func(id int, stage *evaluationStage) {
+
+		attributes := ""
+		if stage.folded != "" {
+			attributes = ", style=\"filled,dashed\", fillcolor=lightgrey"
+		}
+		fmt.Fprintf(&buffer, "\ts%d [label=%s%s];\n", id, dotString(explainLabel(stage)), attributes)
+
+	}, func(parent int, child int, order int) {
+		fmt.Fprintf(&buffer, "\ts%d -> s%d [label=\"%d\"];\n", parent, child, order)
+	})
+
+	buffer.WriteString("}\n")
+	return buffer.String()
+}
+
+/*
+Renders the planned stages of this expression as a Mermaid flowchart, as TExplainDot does.
+*/
+func (t tEvaluableExpression) TExplainMermaid() string {
+
+	var buffer strings.Builder
+	buffer.WriteString("flowchart TD\n")
+
+	folded := false
+	explainStages(t.evaluationStages, func(id int, stage *evaluationStage) {
+
+		class := ""
+		if stage.folded != "" {
+			class = ":::folded"
+			folded = true
+		}
+		fmt.Fprintf(&buffer, "\ts%d[\"%s\"]%s\n", id, mermaidString(explainLabel(stage)), class)
+
+	}, func(parent int, child int, order int) {
+		fmt.Fprintf(&buffer, "\ts%d -->|%d| s%d\n", parent, order, child)
+	})
+
+	if folded {
+		buffer.WriteString("\tclassDef folded fill:#ddd,stroke-dasharray:4 4\n")
+	}
+	return buffer.String()
+}
+
+/*
+Visits [root] and the stages below it, parents before children, giving each a number to identify it by.
+Each child is also visited as an edge from its parent, numbered by whether it is evaluated first or second.
+*/
+func explainStages(root *evaluationStage, node func(id int, stage *evaluationStage), edge func(parent int, child int, order int)) {
+
+	next := 0
+
+	var visit func(stage *evaluationStage) int
+	visit = func(stage *evaluationStage) int {
+
+		id := next
+		next++
+		node(id, stage)
+
+		order := 1
+		for _, child := range []*evaluationStage{stage.leftStage, stage.rightStage} {
+
+			if child == nil {
+				continue
+			}
+
+			edge(id, visit(child), order)
+			order++
+		}
+		return id
+	}
+
+	if root != nil {
+		visit(root)
+	}
+}
+
+func explainLabel(stage *evaluationStage) string {
+
+	if stage.folded != "" {
+		return stage.label() + "\nfolded from " + stage.folded
+	}
+	return stage.label()
+}
+
+func dotString(label string) string {
+
+	label = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(label)
+	return "\"" + label + "\""
+}
+
+func mermaidString(label string) string {
+	return strings.NewReplacer("\"", "#quot;", "<", "#lt;", ">", "#gt;", "\n", "<br/>").Replace(label)
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+/*
+TErrPreempted is returned by evaluations which gave up because they ran past their TEvaluationOptions.Deadline.
+*/
+var TErrPreempted = errors.New("Evaluation preempted: deadline exceeded")
+
+// how many stages are evaluated between checks of the clock.
+const preemptionInterval = 64
+
+/*
+Tracks the deadline of a single evaluation. Only ever used by one goroutine.
+*/
+type stagePreemption struct {
+	deadline  time.Time
+	countdown int
+}
+
+/*
+Returns true if the deadline has passed. Only consults the clock once every preemptionInterval calls,
+so that large expressions are checked in chunks rather than at every stage.
+*/
+func (p *stagePreemption) expired() bool {
+
+	p.countdown--
+	if p.countdown > 0 {
+		return false
+	}
+
+	p.countdown = preemptionInterval
+	return !time.Now().Before(p.deadline)
+}
+
+/*
+Like TRun, but yields once [budget] has elapsed instead of after a number of steps, for callers with a latency target.
+The clock is checked between rules, and periodically while evaluating each rule; a rule which is interrupted is
+not recorded, and will be evaluated again from its start when the run is continued. The first rule of every call
+is always finished, so that a run makes progress however small its budget.
+
+Returns the results so far, and a checkpoint from which to continue, with a nil error if the run merely yielded.
+Use TDone to tell whether any rules remain.
+*/
+func (r *tRuleSet) TRunWithin(parameters map[string]interface{}, checkpoint *TCheckpoint, budget time.Duration) (*TCheckpoint, error) {
+	return r.run(parameters, checkpoint, 0, time.Now().Add(budget))
+}
@@ -0,0 +1,558 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"time"
+)
+
+/*
+The operators of expression.proto, in the order of their enum numbers, starting from 1.
+*/
+var protoOperators = []tOperatorSymbol{
+	tEQ, tNEQ, tGT, tLT, tGTE, tLTE, tREQ, tNREQ, tIN,
+	tAND, tOR, tXOR,
+	tPLUS, tMINUS, tBITWISE_AND, tBITWISE_OR, tBITWISE_XOR, tBITWISE_LSHIFT, tBITWISE_RSHIFT,
+	tMULTIPLY, tDIVIDE, tFLOOR_DIVIDE, tMODULUS, tEXPONENT,
+	tNEGATE, tINVERT, tBITWISE_NOT,
+	tTERNARY_TRUE, tTERNARY_FALSE, tCOALESCE,
+	tSEPARATE,
+}
+
+// how deeply nodes may nest in a decoded expression, so that crafted input cannot exhaust the stack.
+const maxProtoDepth = 1000
+
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+/*
+Encodes this expression as a geval.v1.Expression message, as described by expression.proto,
+so that it can be stored compactly or evaluated elsewhere. The expression is encoded as written:
+literals are not folded, and functions are referred to by name. TFromProto decodes it.
+The codec is hand-written, rather than generated by protoc, so that core does not depend on the protobuf runtime.
+*/
+func (t tEvaluableExpression) TMarshalProto() ([]byte, error) {
+
+	var ret []byte
+
+	stage := t.planSourceStages()
+	if stage == nil {
+		return ret, nil
+	}
+
+	node, err := protoNode(stage)
+	if err != nil {
+		return nil, err
+	}
+	return appendProtoBytes(ret, 1, node), nil
+}
+
+/*
+Decodes [data], a geval.v1.Expression message as encoded by TMarshalProto, back into expression text,
+which may then be compiled with whatever functions and options it needs - NilLiteral, if it compares with nil.
+Fields which are not part of expression.proto are ignored.
+*/
+func TFromProto(data []byte) (string, error) {
+
+	var root *evaluationStage
+
+	reader := protoReader{data: data}
+	for !reader.done() {
+
+		field, wireType, err := reader.tag()
+		if err != nil {
+			return "", err
+		}
+
+		if field == 1 && wireType == protoBytes {
+
+			message, err := reader.bytes()
+			if err != nil {
+				return "", err
+			}
+			root, err = stageFromProto(message, 0)
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		err = reader.skip(wireType)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return renderStage(root), nil
+}
+
+/*
+Encodes [stage] as a geval.v1.Node message.
+*/
+func protoNode(stage *evaluationStage) ([]byte, error) {
+
+	var ret []byte
+
+	switch stage.symbol {
+	case tLITERAL:
+		literal, err := protoLiteral(stage)
+		if err != nil {
+			return nil, err
+		}
+		return appendProtoBytes(ret, 1, literal), nil
+
+	case tVALUE:
+		return appendProtoBytes(ret, 2, []byte(stage.name)), nil
+
+	case tACCESS:
+		var accessor []byte
+		for _, name := range stage.path {
+			accessor = appendProtoBytes(accessor, 1, []byte(name))
+		}
+
+		accessor, err := appendProtoNode(accessor, 2, stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+		return appendProtoBytes(ret, 3, accessor), nil
+
+	case tFUNCTIONAL:
+		call := appendProtoBytes(nil, 1, []byte(stage.name))
+
+		call, err := appendProtoNode(call, 2, stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+		return appendProtoBytes(ret, 4, call), nil
+
+	case tNOOP:
+		clause, err := appendProtoNode(nil, 1, stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+		return appendProtoBytes(ret, 6, clause), nil
+	}
+
+	for index, symbol := range protoOperators {
+
+		if symbol != stage.symbol {
+			continue
+		}
+
+		operation := appendProtoVarint(nil, 1, uint64(index+1))
+
+		operation, err := appendProtoNode(operation, 2, stage.leftStage)
+		if err != nil {
+			return nil, err
+		}
+		operation, err = appendProtoNode(operation, 3, stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+		return appendProtoBytes(ret, 5, operation), nil
+	}
+
+	return nil, fmt.Errorf("Cannot encode '%s' as protobuf", renderStage(stage))
+}
+
+/*
+Appends [stage] as the Node field [field] of a message, unless it is nil.
+*/
+func appendProtoNode(message []byte, field int, stage *evaluationStage) ([]byte, error) {
+
+	if stage == nil {
+		return message, nil
+	}
+
+	node, err := protoNode(stage)
+	if err != nil {
+		return nil, err
+	}
+	return appendProtoBytes(message, field, node), nil
+}
+
+/*
+Encodes the literal [stage] as a geval.v1.Literal message.
+*/
+func protoLiteral(stage *evaluationStage) ([]byte, error) {
+
+	value, err := stage.operator(nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typed := value.(type) {
+	case nil:
+		return appendProtoVarint(nil, 1, 1), nil
+	case bool:
+		if typed {
+			return appendProtoVarint(nil, 2, 1), nil
+		}
+		return appendProtoVarint(nil, 2, 0), nil
+	case float64:
+		// times are planned as seconds since the epoch, but are written as dates.
+		if stage.source != "" {
+			return appendProtoVarint(nil, 6, uint64(time.Unix(int64(typed), 0).UnixNano())), nil
+		}
+		return appendProtoFixed64(nil, 3, math.Float64bits(typed)), nil
+	case string:
+		return appendProtoBytes(nil, 4, []byte(typed)), nil
+	case *regexp.Regexp:
+		return appendProtoBytes(nil, 5, []byte(typed.String())), nil
+	case uint64:
+		return appendProtoVarint(nil, 7, typed), nil
+	case int64:
+		return appendProtoVarint(nil, 8, uint64(typed)), nil
+	}
+
+	return nil, fmt.Errorf("Cannot encode '%s' as protobuf", renderStage(stage))
+}
+
+func appendProtoTag(message []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(message, uint64(field)<<3|uint64(wireType))
+}
+
+func appendProtoVarint(message []byte, field int, value uint64) []byte {
+	message = appendProtoTag(message, field, protoVarint)
+	return binary.AppendUvarint(message, value)
+}
+
+func appendProtoFixed64(message []byte, field int, value uint64) []byte {
+	message = appendProtoTag(message, field, protoFixed64)
+	return binary.LittleEndian.AppendUint64(message, value)
+}
+
+func appendProtoBytes(message []byte, field int, value []byte) []byte {
+	message = appendProtoTag(message, field, protoBytes)
+	message = binary.AppendUvarint(message, uint64(len(value)))
+	return append(message, value...)
+}
+
+/*
+Decodes [data], a geval.v1.Node message, into a stage which renderStage can write, nested [depth] nodes deep.
+*/
+func stageFromProto(data []byte, depth int) (*evaluationStage, error) {
+
+	if depth > maxProtoDepth {
+		return nil, fmt.Errorf("Cannot decode protobuf nested more than %d nodes deep", maxProtoDepth)
+	}
+
+	var ret *evaluationStage
+
+	err := readProtoFields(data, func(field int, wireType int, reader *protoReader) error {
+
+		if wireType != protoBytes {
+			return reader.skip(wireType)
+		}
+
+		message, err := reader.bytes()
+		if err != nil {
+			return err
+		}
+
+		switch field {
+		case 1:
+			ret, err = literalFromProto(message)
+		case 2:
+			ret = &evaluationStage{symbol: tVALUE, name: string(message)}
+		case 3:
+			ret, err = accessorFromProto(message, depth)
+		case 4:
+			ret, err = callFromProto(message, depth)
+		case 5:
+			ret, err = operationFromProto(message, depth)
+		case 6:
+			ret = &evaluationStage{symbol: tNOOP}
+			err = readProtoFields(message, func(field int, wireType int, reader *protoReader) error {
+				return readProtoNode(reader, wireType, field == 1, &ret.rightStage, depth)
+			})
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if ret == nil {
+		return nil, errors.New("Cannot decode a protobuf node which has no kind")
+	}
+	return ret, nil
+}
+
+func literalFromProto(data []byte) (*evaluationStage, error) {
+
+	var value interface{}
+	var source string
+
+	err := readProtoFields(data, func(field int, wireType int, reader *protoReader) error {
+
+		var err error
+		var number uint64
+		var message []byte
+
+		switch wireType {
+		case protoVarint:
+			number, err = reader.varint()
+		case protoFixed64:
+			number, err = reader.fixed64()
+		case protoBytes:
+			message, err = reader.bytes()
+		default:
+			return reader.skip(wireType)
+		}
+		if err != nil {
+			return err
+		}
+
+		source = ""
+		switch field {
+		case 1:
+			value = nil
+		case 2:
+			value = number != 0
+		case 3:
+			value = math.Float64frombits(number)
+		case 4:
+			value = string(message)
+		case 5:
+			value, err = regexp.Compile(string(message))
+		case 6:
+			date := time.Unix(0, int64(number)).UTC()
+			value = float64(date.Unix())
+			source = quoteTime(date)
+		case 7:
+			value = number
+		case 8:
+			value = int64(number)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if source == "" {
+		source = renderLiteral(value)
+	}
+	return &evaluationStage{symbol: tLITERAL, operator: makeLiteralStage(value), source: source}, nil
+}
+
+func accessorFromProto(data []byte, depth int) (*evaluationStage, error) {
+
+	ret := &evaluationStage{symbol: tACCESS}
+
+	err := readProtoFields(data, func(field int, wireType int, reader *protoReader) error {
+
+		if field == 1 && wireType == protoBytes {
+
+			name, err := reader.bytes()
+			if err != nil {
+				return err
+			}
+			ret.path = append(ret.path, string(name))
+			return nil
+		}
+		return readProtoNode(reader, wireType, field == 2, &ret.rightStage, depth)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ret.path) < 2 {
+		return nil, errors.New("Cannot decode a protobuf accessor without both a parameter and a field")
+	}
+	return ret, nil
+}
+
+func callFromProto(data []byte, depth int) (*evaluationStage, error) {
+
+	ret := &evaluationStage{symbol: tFUNCTIONAL}
+
+	err := readProtoFields(data, func(field int, wireType int, reader *protoReader) error {
+
+		if field == 1 && wireType == protoBytes {
+
+			name, err := reader.bytes()
+			ret.name = string(name)
+			return err
+		}
+		return readProtoNode(reader, wireType, field == 2, &ret.rightStage, depth)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !isBareName(ret.name) {
+		return nil, fmt.Errorf("Cannot decode a protobuf call to '%s', which is not a function name", ret.name)
+	}
+
+	// arguments are always written in parentheses.
+	if ret.rightStage != nil && ret.rightStage.symbol != tNOOP {
+		ret.rightStage = &evaluationStage{symbol: tNOOP, rightStage: ret.rightStage}
+	}
+	return ret, nil
+}
+
+func operationFromProto(data []byte, depth int) (*evaluationStage, error) {
+
+	var operator uint64
+	ret := new(evaluationStage)
+
+	err := readProtoFields(data, func(field int, wireType int, reader *protoReader) error {
+
+		if field == 1 && wireType == protoVarint {
+
+			var err error
+			operator, err = reader.varint()
+			return err
+		}
+
+		if field == 2 {
+			return readProtoNode(reader, wireType, true, &ret.leftStage, depth)
+		}
+		return readProtoNode(reader, wireType, field == 3, &ret.rightStage, depth)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if operator < 1 || operator > uint64(len(protoOperators)) {
+		return nil, fmt.Errorf("Cannot decode the unknown protobuf operator %d", operator)
+	}
+	ret.symbol = protoOperators[operator-1]
+
+	prefix := ret.symbol == tNEGATE || ret.symbol == tINVERT || ret.symbol == tBITWISE_NOT
+	if ret.rightStage == nil || (ret.leftStage == nil) != prefix {
+		return nil, fmt.Errorf("Cannot decode a protobuf '%s' operation with the wrong operands", ret.symbol.String())
+	}
+	return ret, nil
+}
+
+/*
+Decodes the Node field being read by [reader] into [stage] if [isNode], and otherwise skips it.
+*/
+func readProtoNode(reader *protoReader, wireType int, isNode bool, stage **evaluationStage, depth int) error {
+
+	if !isNode || wireType != protoBytes {
+		return reader.skip(wireType)
+	}
+
+	message, err := reader.bytes()
+	if err != nil {
+		return err
+	}
+
+	*stage, err = stageFromProto(message, depth+1)
+	return err
+}
+
+/*
+Calls [read] with each field of the message [data], which must read or skip that field's value.
+*/
+func readProtoFields(data []byte, read func(field int, wireType int, reader *protoReader) error) error {
+
+	reader := protoReader{data: data}
+	for !reader.done() {
+
+		field, wireType, err := reader.tag()
+		if err != nil {
+			return err
+		}
+
+		err = read(field, wireType, &reader)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type protoReader struct {
+	data     []byte
+	position int
+}
+
+var errTruncatedProto = errors.New("Cannot decode truncated protobuf")
+
+func (r *protoReader) done() bool {
+	return r.position >= len(r.data)
+}
+
+func (r *protoReader) tag() (int, int, error) {
+
+	tag, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	if tag>>3 == 0 || tag>>3 > math.MaxInt32 {
+		return 0, 0, fmt.Errorf("Cannot decode the invalid protobuf field number %d", tag>>3)
+	}
+	return int(tag >> 3), int(tag & 7), nil
+}
+
+func (r *protoReader) varint() (uint64, error) {
+
+	value, length := binary.Uvarint(r.data[r.position:])
+	if length <= 0 {
+		return 0, errTruncatedProto
+	}
+	r.position += length
+	return value, nil
+}
+
+func (r *protoReader) fixed64() (uint64, error) {
+
+	if len(r.data)-r.position < 8 {
+		return 0, errTruncatedProto
+	}
+	value := binary.LittleEndian.Uint64(r.data[r.position:])
+	r.position += 8
+	return value, nil
+}
+
+func (r *protoReader) bytes() ([]byte, error) {
+
+	length, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(r.data)-r.position) {
+		return nil, errTruncatedProto
+	}
+
+	value := r.data[r.position : r.position+int(length)]
+	r.position += int(length)
+	return value, nil
+}
+
+/*
+Skips over a value of the given wire type, such as that of a field this package does not know of.
+*/
+func (r *protoReader) skip(wireType int) error {
+
+	var err error
+
+	switch wireType {
+	case protoVarint:
+		_, err = r.varint()
+	case protoFixed64:
+		_, err = r.fixed64()
+	case protoBytes:
+		_, err = r.bytes()
+	case protoFixed32:
+		if len(r.data)-r.position < 4 {
+			return errTruncatedProto
+		}
+		r.position += 4
+	default:
+		return fmt.Errorf("Cannot decode the unsupported protobuf wire type %d", wireType)
+	}
+	return err
+}
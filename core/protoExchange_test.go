@@ -0,0 +1,357 @@
+package core
+
+import (
+	"bufio"
+	"encoding/hex"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type protoFieldDescriptor struct {
+	name     string
+	typeName string
+}
+
+/*
+The messages and enums of a .proto file, as far as its encoding is concerned.
+*/
+type protoDescriptor struct {
+	messages map[string]map[int]protoFieldDescriptor
+	enums    map[string]map[string]int
+}
+
+var (
+	protoDeclarationPattern = regexp.MustCompile(`^(message|enum|oneof)\s+(\w+)\s*\{$`)
+	protoFieldPattern       = regexp.MustCompile(`^(?:repeated\s+)?(\w+)\s+(\w+)\s*=\s*(\d+);$`)
+	protoEnumValuePattern   = regexp.MustCompile(`^(\w+)\s*=\s*(\d+);$`)
+)
+
+/*
+Reads the messages and enums of the .proto file at [path]. Only what expression.proto uses is understood.
+*/
+func readProtoDescriptor(test *testing.T, path string) protoDescriptor {
+
+	file, err := os.Open(path)
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer file.Close()
+
+	ret := protoDescriptor{
+		messages: make(map[string]map[int]protoFieldDescriptor),
+		enums:    make(map[string]map[string]int),
+	}
+
+	// the kind and name of each enclosing declaration.
+	var kinds, names []string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+
+		line := scanner.Text()
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = line[:comment]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "syntax") || strings.HasPrefix(line, "package") || strings.HasPrefix(line, "option"):
+
+		case protoDeclarationPattern.MatchString(line):
+			match := protoDeclarationPattern.FindStringSubmatch(line)
+			kinds = append(kinds, match[1])
+			names = append(names, match[2])
+
+			switch match[1] {
+			case "message":
+				ret.messages[match[2]] = make(map[int]protoFieldDescriptor)
+			case "enum":
+				ret.enums[match[2]] = make(map[string]int)
+			}
+
+		case line == "}":
+			kinds, names = kinds[:len(kinds)-1], names[:len(names)-1]
+
+		case len(kinds) > 0 && kinds[len(kinds)-1] == "enum" && protoEnumValuePattern.MatchString(line):
+			match := protoEnumValuePattern.FindStringSubmatch(line)
+			number, _ := strconv.Atoi(match[2])
+			ret.enums[names[len(names)-1]][match[1]] = number
+
+		case protoFieldPattern.MatchString(line):
+			match := protoFieldPattern.FindStringSubmatch(line)
+			number, _ := strconv.Atoi(match[3])
+
+			// fields of a oneof belong to the message enclosing it.
+			message := names[len(names)-1]
+			if kinds[len(kinds)-1] == "oneof" {
+				message = names[len(names)-2]
+			}
+			ret.messages[message][number] = protoFieldDescriptor{name: match[2], typeName: match[1]}
+
+		default:
+			test.Fatalf("Unable to read the line '%s' of %s", line, path)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		test.Fatal(err)
+	}
+	return ret
+}
+
+/*
+The wire type a field of [typeName] is encoded with.
+*/
+func (d protoDescriptor) wireType(typeName string) int {
+
+	switch typeName {
+	case "double", "fixed64":
+		return protoFixed64
+	case "bool", "int64", "uint64", "int32", "uint32":
+		return protoVarint
+	}
+	if _, isEnum := d.enums[typeName]; isEnum {
+		return protoVarint
+	}
+	return protoBytes
+}
+
+/*
+Checks that each field of [data], a [message] encoded by TMarshalProto, is declared by [descriptor] with the wire type
+it is encoded with, recording each field seen in [seen].
+*/
+func checkProtoMessage(test *testing.T, descriptor protoDescriptor, message string, data []byte, seen map[string]bool) {
+
+	test.Helper()
+
+	fields, found := descriptor.messages[message]
+	if !found {
+		test.Fatalf("expression.proto declares no message %s", message)
+	}
+
+	err := readProtoFields(data, func(number int, wireType int, reader *protoReader) error {
+
+		field, found := fields[number]
+		if !found {
+			test.Errorf("the codec encodes field %d of %s, which expression.proto does not declare", number, message)
+			return reader.skip(wireType)
+		}
+
+		seen[message+"."+field.name] = true
+
+		if expected := descriptor.wireType(field.typeName); wireType != expected {
+			test.Errorf("the codec encodes %s.%s with wire type %d, but expression.proto declares it as %s", message, field.name, wireType, field.typeName)
+			return reader.skip(wireType)
+		}
+
+		if _, isMessage := descriptor.messages[field.typeName]; isMessage {
+
+			nested, err := reader.bytes()
+			if err != nil {
+				return err
+			}
+			checkProtoMessage(test, descriptor, field.typeName, nested, seen)
+			return nil
+		}
+		return reader.skip(wireType)
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+}
+
+func TestProtoCodecMatchesDescriptor(test *testing.T) {
+
+	descriptor := readProtoDescriptor(test, "expression.proto")
+	functions := map[string]tExpressionFunction{
+		"f": func(arguments ...interface{}) (interface{}, error) { return nil, nil },
+	}
+
+	// together, these encode every field of every message.
+	corpus := []string{
+		"a == nil && flag == true",
+		"[odd name] + 1.5 > 'text'",
+		"s =~ /^a+$/",
+		"date > '2024-01-02'",
+		"0xFFFFFFFFFFFFFFFF & mask",
+		"user.Name + user.Greet('hi')",
+		"f() + f(1, 2)",
+		"(a) ? -b : c",
+	}
+
+	var expressions []*tEvaluableExpression
+	for _, text := range corpus {
+
+		expression, err := TNewEvaluableExpressionWithFunctionsAndOptions(text, functions, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+		expressions = append(expressions, expression)
+	}
+
+	// no expression text gives a signed integer literal, but they can be given as tokens.
+	expression, err := TNewEvaluableExpressionFromTokens([]TExpressionToken{{Kind: tNUMERIC, Value: int64(-1) << 60}})
+	if err != nil {
+		test.Fatal(err)
+	}
+	expressions = append(expressions, expression)
+
+	seen := make(map[string]bool)
+	for _, expression := range expressions {
+
+		data, err := expression.TMarshalProto()
+		if err != nil {
+			test.Fatalf("%s: %v", expression.TFormat(), err)
+		}
+		checkProtoMessage(test, descriptor, "Expression", data, seen)
+	}
+
+	for message, fields := range descriptor.messages {
+		for _, field := range fields {
+			if !seen[message+"."+field.name] {
+				test.Errorf("the codec never encoded %s.%s", message, field.name)
+			}
+		}
+	}
+}
+
+func TestProtoOperatorsMatchDescriptor(test *testing.T) {
+
+	descriptor := readProtoDescriptor(test, "expression.proto")
+
+	// the enum names of expression.proto, by the symbol each stands for.
+	names := map[tOperatorSymbol]string{
+		tEQ: "EQ", tNEQ: "NEQ", tGT: "GT", tLT: "LT", tGTE: "GTE", tLTE: "LTE", tREQ: "REQ", tNREQ: "NREQ", tIN: "IN",
+		tAND: "AND", tOR: "OR", tXOR: "XOR",
+		tPLUS: "PLUS", tMINUS: "MINUS", tBITWISE_AND: "BITWISE_AND", tBITWISE_OR: "BITWISE_OR", tBITWISE_XOR: "BITWISE_XOR",
+		tBITWISE_LSHIFT: "BITWISE_LSHIFT", tBITWISE_RSHIFT: "BITWISE_RSHIFT",
+		tMULTIPLY: "MULTIPLY", tDIVIDE: "DIVIDE", tFLOOR_DIVIDE: "FLOOR_DIVIDE", tMODULUS: "MODULUS", tEXPONENT: "EXPONENT",
+		tNEGATE: "NEGATE", tINVERT: "INVERT", tBITWISE_NOT: "BITWISE_NOT",
+		tTERNARY_TRUE: "TERNARY_TRUE", tTERNARY_FALSE: "TERNARY_FALSE", tCOALESCE: "COALESCE",
+		tSEPARATE: "SEPARATE",
+	}
+
+	values := descriptor.enums["Operator"]
+	if len(values) != len(protoOperators)+1 {
+		test.Errorf("expression.proto declares %d operators, but the codec knows of %d", len(values)-1, len(protoOperators))
+	}
+
+	for index, symbol := range protoOperators {
+
+		name, found := names[symbol]
+		if !found {
+			test.Errorf("the codec encodes '%s', which has no name in expression.proto", symbol.String())
+			continue
+		}
+		if values[name] != index+1 {
+			test.Errorf("the codec encodes %s as %d, but expression.proto numbers it %d", name, index+1, values[name])
+		}
+	}
+}
+
+/*
+Encodings worked out by hand from expression.proto, so that the codec is checked against the wire format itself,
+and not only against its own decoder.
+*/
+func TestProtoEncoding(test *testing.T) {
+
+	functions := map[string]tExpressionFunction{
+		"f": func(arguments ...interface{}) (interface{}, error) { return nil, nil },
+	}
+
+	cases := []struct {
+		expression string
+		expected   string
+	}{
+		// Expression{root: Node{operation: {operator: GT, left: {parameter: "a"}, right: {literal: {number: 1}}}}}
+		{"a > 1", "0a16" + "2a14" + "0803" + "1203" + "120161" + "1a0b" + "0a09" + "19000000000000f03f"},
+
+		// Node{operation: {operator: INVERT, right: {parameter: "flag"}}}
+		{"!flag", "0a0c" + "2a0a" + "081a" + "1a06" + "1204666c6167"},
+
+		// Node{clause: {inner: {parameter: "a"}}}
+		{"(a)", "0a07" + "3205" + "0a03" + "120161"},
+
+		// Node{operation: {operator: EQ, left: {accessor: {path: ["user", "Name"]}}, right: {literal: {null: true}}}}
+		{"user.Name == nil", "0a1a" + "2a18" + "0801" + "120e" + "1a0c" + "0a0475736572" + "0a044e616d65" + "1a04" + "0a02" + "0801"},
+
+		// Node{call: {function: "f", arguments: {clause: {inner: {operation: {operator: SEPARATE, ...}}}}}}
+		{"f(s, 'x')", "0a1b" + "2219" + "0a0166" + "1214" + "3212" + "0a10" + "2a0e" + "081f" + "1203120173" + "1a05" + "0a03" + "220178"},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpressionWithFunctionsAndOptions(c.expression, functions, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		data, err := expression.TMarshalProto()
+		if err != nil {
+			test.Errorf("%s: %v", c.expression, err)
+			continue
+		}
+		if encoded := hex.EncodeToString(data); encoded != c.expected {
+			test.Errorf("%s: expected %s, got %s", c.expression, c.expected, encoded)
+		}
+	}
+}
+
+func TestProtoRoundTrip(test *testing.T) {
+
+	for _, text := range []string{
+		"a == nil && flag == true",
+		"[odd name] + 1.5 > 'text'",
+		"s =~ '^a+$'",
+		"user.Name + user.Greet('hi')",
+		"(a) ? -b : c",
+		"a ?? b ?? 1",
+		"x in (1, 2, 3)",
+	} {
+
+		expression, err := TNewEvaluableExpressionWithOptions(text, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+
+		data, err := expression.TMarshalProto()
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+
+		decoded, err := TFromProto(data)
+		if err != nil {
+			test.Errorf("%s: failed to decode: %v", text, err)
+			continue
+		}
+
+		// parentheses are kept, so the decoded expression must encode exactly as the original did.
+		reencoded, err := TNewEvaluableExpressionWithOptions(decoded, conformanceOptions)
+		if err != nil {
+			test.Errorf("%s: decoded as %s, which fails to compile: %v", text, decoded, err)
+			continue
+		}
+		reencodedData, err := reencoded.TMarshalProto()
+		if err != nil || string(reencodedData) != string(data) {
+			test.Errorf("%s: decoded as %s, which encodes differently", text, decoded)
+		}
+	}
+}
+
+func TestProtoRejectsMalformedInput(test *testing.T) {
+
+	for name, data := range map[string][]byte{
+		"truncated":        {0x0a, 0x05, 0x12},
+		"unknown operator": {0x0a, 0x04, 0x2a, 0x02, 0x08, 0x63},
+		"no kind":          {0x0a, 0x00},
+		"bad wire type":    {0x0f},
+	} {
+
+		_, err := TFromProto(data)
+		if err == nil {
+			test.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+tRuleSet is a named collection of expressions which share functions, options, constants, and lookup tables.
+Member expressions read constants with `@const('name')` and lookup tables with `@table('name', key)`.
+Constants and tables may be replaced at any time, including while rules are being evaluated.
+Each `@const` and `@table` call sees either the old or the new copy of everything, never a mix - but each call reads
+the current copy for itself, so a rule which makes several calls while another goroutine replaces a constant or table
+may see the old copy in one call and the new one in a later call.
+*/
+type tRuleSet struct {
+	lock      sync.RWMutex
+	rules     map[string]*tEvaluableExpression
+	order     []string
+	functions map[string]tExpressionFunction
+	options   TExpressionOptions
+
+	// the current *ruleSetData. Replaced wholesale, never modified in place.
+	data atomic.Value
+}
+
+/*
+TRuleSet allows packages outside of core to refer to rule sets.
+*/
+type TRuleSet = tRuleSet
+
+type ruleSetData struct {
+	constants map[string]interface{}
+	tables    map[string]map[interface{}]interface{}
+}
+
+/*
+Creates an empty rule set whose rules will be compiled with the given [functions] and [options].
+*/
+func TNewRuleSet(functions map[string]tExpressionFunction, options TExpressionOptions) *tRuleSet {
+
+	ret := &tRuleSet{
+		rules:     make(map[string]*tEvaluableExpression),
+		functions: make(map[string]tExpressionFunction, len(functions)+2),
+		options:   options,
+	}
+
+	for name, function := range functions {
+		ret.functions[name] = function
+	}
+	ret.functions["@const"] = ret.constFunction
+	ret.functions["@table"] = ret.tableFunction
+
+	ret.data.Store(&ruleSetData{
+		constants: make(map[string]interface{}),
+		tables:    make(map[string]map[interface{}]interface{}),
+	})
+	return ret
+}
+
+/*
+Compiles [expression] and adds it to this rule set as [name], replacing any rule already of that name.
+*/
+func (r *tRuleSet) TAdd(name string, expression string) error {
+
+	compiled, err := TNewEvaluableExpressionWithFunctionsAndOptions(expression, r.functions, r.options)
+	if err != nil {
+		return fmt.Errorf("Unable to compile rule '%s': %v", name, err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	_, found := r.rules[name]
+	if !found {
+		r.order = append(r.order, name)
+	}
+	r.rules[name] = compiled
+	return nil
+}
+
+/*
+Returns the compiled rule of the given [name], or nil if there is no such rule.
+*/
+func (r *tRuleSet) TRule(name string) *tEvaluableExpression {
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.rules[name]
+}
+
+/*
+Returns the names of all rules, in the order they were first added.
+*/
+func (r *tRuleSet) TNames() []string {
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return append([]string(nil), r.order...)
+}
+
+/*
+Evaluates the rule of the given [name].
+*/
+func (r *tRuleSet) TEvaluate(name string, parameters map[string]interface{}) (interface{}, error) {
+
+	rule := r.TRule(name)
+	if rule == nil {
+		return nil, errors.New("No rule '" + name + "' in rule set")
+	}
+
+	return rule.TEvaluate(parameters)
+}
+
+/*
+Evaluates every rule, returning each result by rule name.
+Stops at, and returns, the first error encountered.
+*/
+func (r *tRuleSet) TEvaluateAll(parameters map[string]interface{}) (map[string]interface{}, error) {
+
+	ret := make(map[string]interface{})
+
+	for _, name := range r.TNames() {
+
+		result, err := r.TEvaluate(name, parameters)
+		if err != nil {
+			return nil, fmt.Errorf("Rule '%s': %v", name, err)
+		}
+		ret[name] = result
+	}
+	return ret, nil
+}
+
+/*
+Sets the constant readable by rules as `@const('name')`.
+*/
+func (r *tRuleSet) TSetConstant(name string, value interface{}) {
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	current := r.data.Load().(*ruleSetData)
+	constants := make(map[string]interface{}, len(current.constants)+1)
+	for key, existing := range current.constants {
+		constants[key] = existing
+	}
+	constants[name] = castToFloat64(value)
+
+	r.data.Store(&ruleSetData{constants: constants, tables: current.tables})
+}
+
+/*
+Sets the lookup table readable by rules as `@table('name', key)`.
+[table] may be any map, such as a map[string]float64; its keys and values are converted the same way as parameters.
+*/
+func (r *tRuleSet) TSetTable(name string, table interface{}) error {
+
+	value := reflect.ValueOf(table)
+	if value.Kind() != reflect.Map {
+		return fmt.Errorf("Table '%s' must be a map, not %T", name, table)
+	}
+
+	entries := make(map[interface{}]interface{}, value.Len())
+	iterator := value.MapRange()
+	for iterator.Next() {
+		entries[castToFloat64(iterator.Key().Interface())] = castToFloat64(iterator.Value().Interface())
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	current := r.data.Load().(*ruleSetData)
+	tables := make(map[string]map[interface{}]interface{}, len(current.tables)+1)
+	for key, existing := range current.tables {
+		tables[key] = existing
+	}
+	tables[name] = entries
+
+	r.data.Store(&ruleSetData{constants: current.constants, tables: tables})
+	return nil
+}
+
+func (r *tRuleSet) constFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 1 || !isString(arguments[0]) {
+		return nil, errors.New("@const expects a single constant name")
+	}
+
+	data := r.data.Load().(*ruleSetData)
+	value, found := data.constants[arguments[0].(string)]
+	if !found {
+		return nil, fmt.Errorf("No constant '%v' in rule set", arguments[0])
+	}
+	return value, nil
+}
+
+/*
+Looks up a key in a table, returning nil if the table has no entry for it.
+*/
+func (r *tRuleSet) tableFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 2 || !isString(arguments[0]) {
+		return nil, errors.New("@table expects a table name and a key")
+	}
+
+	data := r.data.Load().(*ruleSetData)
+	table, found := data.tables[arguments[0].(string)]
+	if !found {
+		return nil, fmt.Errorf("No table '%v' in rule set", arguments[0])
+	}
+
+	if arguments[1] == nil || !reflect.TypeOf(arguments[1]).Comparable() {
+		return nil, nil
+	}
+	return table[arguments[1]], nil
+}
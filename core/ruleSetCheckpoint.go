@@ -0,0 +1,101 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+/*
+TCheckpoint records the progress of a rule set run: the index of the next rule to evaluate,
+and the result of every rule evaluated so far, bound under that rule's name.
+Checkpoints marshal to and from JSON, so that a run can be resumed in a different process.
+*/
+type TCheckpoint struct {
+	Next     int                    `json:"next"`
+	Bindings map[string]interface{} `json:"bindings"`
+}
+
+/*
+Restores a checkpoint previously produced by TMarshal.
+*/
+func TUnmarshalCheckpoint(data []byte) (*TCheckpoint, error) {
+
+	ret := new(TCheckpoint)
+
+	err := json.Unmarshal(data, ret)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to restore checkpoint: %v", err)
+	}
+	return ret, nil
+}
+
+func (c *TCheckpoint) TMarshal() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+/*
+Returns true if every rule of the given rule set has been evaluated.
+*/
+func (c *TCheckpoint) TDone(rules *tRuleSet) bool {
+	return c.Next >= len(rules.TNames())
+}
+
+/*
+Evaluates up to [steps] rules of this rule set, in the order they were added, starting from [checkpoint]
+(or from the first rule, if [checkpoint] is nil). A [steps] of zero or less evaluates all remaining rules.
+
+Every rule can refer to the results of the rules evaluated before it by name, as if they were parameters;
+these take precedence over the given [parameters].
+Returns a new checkpoint from which the run can be continued, even if the run failed.
+*/
+func (r *tRuleSet) TRun(parameters map[string]interface{}, checkpoint *TCheckpoint, steps int) (*TCheckpoint, error) {
+	return r.run(parameters, checkpoint, steps, time.Time{})
+}
+
+/*
+Runs rules from [checkpoint] until [steps] rules have been evaluated, or [deadline] has passed; either may be zero for no limit.
+*/
+func (r *tRuleSet) run(parameters map[string]interface{}, checkpoint *TCheckpoint, steps int, deadline time.Time) (*TCheckpoint, error) {
+
+	ret := &TCheckpoint{Bindings: make(map[string]interface{})}
+	if checkpoint != nil {
+		ret.Next = checkpoint.Next
+		for name, value := range checkpoint.Bindings {
+			ret.Bindings[name] = value
+		}
+	}
+
+	names := r.TNames()
+	sources := TNewChainedParameters(tMapParameters(ret.Bindings), tMapParameters(parameters))
+
+	for evaluated := 0; ret.Next < len(names); evaluated++ {
+
+		if steps > 0 && evaluated >= steps {
+			break
+		}
+
+		var options TEvaluationOptions
+		if !deadline.IsZero() && evaluated > 0 {
+			if !time.Now().Before(deadline) {
+				break
+			}
+			options.Deadline = deadline
+		}
+
+		name := names[ret.Next]
+
+		result, err := r.TRule(name).TEvaluateWithOptions(sources, options)
+		if err == TErrPreempted {
+			break
+		}
+		if err != nil {
+			return ret, fmt.Errorf("Rule '%s': %v", name, err)
+		}
+
+		ret.Bindings[name] = result
+		ret.Next++
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+Compiles every one of the given [expressions] (keyed by rule name) concurrently and adds them to this rule set.
+Rules may refer to the results of other rules by name, as they do with TRun; once compiled, all rules are ordered
+so that every rule comes after the rules it refers to.
+
+Either every rule is added, or none are. All compilation errors, and any circular references between rules,
+are reported together as a TMultiError.
+*/
+func (r *tRuleSet) TAddAll(expressions map[string]string) error {
+
+	names := make([]string, 0, len(expressions))
+	for name := range expressions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	compiled := make([]*tEvaluableExpression, len(names))
+	failures := make([]error, len(names))
+
+	work := make(chan int)
+	var waiter sync.WaitGroup
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(names) {
+		workers = len(names)
+	}
+
+	for i := 0; i < workers; i++ {
+
+		waiter.Add(1)
+		go func() {
+			defer waiter.Done()
+
+			for index := range work {
+
+				name := names[index]
+				expression, err := TNewEvaluableExpressionWithFunctionsAndOptions(expressions[name], r.functions, r.options)
+				if err != nil {
+					failures[index] = fmt.Errorf("Unable to compile rule '%s': %v", name, err)
+					continue
+				}
+				compiled[index] = expression
+			}
+		}()
+	}
+
+	for index := range names {
+		work <- index
+	}
+	close(work)
+	waiter.Wait()
+
+	var errs []error
+	for _, err := range failures {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return TMultiError{Errors: errs}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	rules := make(map[string]*tEvaluableExpression, len(r.rules)+len(names))
+	for name, rule := range r.rules {
+		rules[name] = rule
+	}
+	for index, name := range names {
+		rules[name] = compiled[index]
+	}
+
+	order, err := orderRules(rules)
+	if err != nil {
+		return err
+	}
+
+	r.rules = rules
+	r.order = order
+	return nil
+}
+
+/*
+Orders the given [rules] so that every rule comes after all rules it refers to.
+Rules which do not depend on each other are ordered by name.
+*/
+func orderRules(rules map[string]*tEvaluableExpression) ([]string, error) {
+
+	dependents := make(map[string][]string)
+	remaining := make(map[string]int)
+
+	for name, rule := range rules {
+
+		remaining[name] = 0
+		for _, variable := range rule.TVars() {
+
+			_, isRule := rules[variable]
+			if !isRule || variable == name {
+				continue
+			}
+
+			dependents[variable] = append(dependents[variable], name)
+			remaining[name]++
+		}
+	}
+
+	var ready, ret []string
+	for name, count := range remaining {
+		if count == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	for len(ready) > 0 {
+
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		ret = append(ret, name)
+
+		for _, dependent := range dependents[name] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(ret) < len(rules) {
+
+		var cyclic []string
+		for name, count := range remaining {
+			if count > 0 {
+				cyclic = append(cyclic, name)
+			}
+		}
+		sort.Strings(cyclic)
+
+		return nil, TMultiError{Errors: []error{
+			errors.New("Circular references between rules: " + strings.Join(cyclic, ", ")),
+		}}
+	}
+
+	return ret, nil
+}
@@ -1,5 +1,12 @@
 package core
 
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"time"
+)
+
 // sanitizedParameters is a wrapper for tParameters that does sanitization as
 // parameters are accessed.
 type sanitizedParameters struct {
@@ -12,9 +19,108 @@ func (p sanitizedParameters) tGet(key string) (interface{}, error) {
 		return nil, err
 	}
 
+	value, err = unwrapValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read parameter '%s': %w", key, err)
+	}
+
 	return castToFloat64(value), nil
 }
 
+/*
+Returns the value underlying common wrapper types, so that database rows can be used as parameters as they are:
+driver.Valuers, such as sql.NullString, give their Value - nil if they are not valid, and a string rather than []byte -
+and pointers to scalars, such as *int64 or *time.Time, give what they point to, or nil.
+Values which are TEqualers or TComparers are kept as they are, since they decide for themselves how they compare.
+*/
+func unwrapValue(value interface{}) (interface{}, error) {
+
+	if value == nil || hasCustomEquality(value) {
+		return value, nil
+	}
+
+	if valuer, ok := value.(driver.Valuer); ok {
+
+		// a nil pointer to a type whose Value has a value receiver would panic, as database/sql notes.
+		reflected := reflect.ValueOf(value)
+		if reflected.Kind() == reflect.Ptr && reflected.IsNil() {
+			return nil, nil
+		}
+
+		unwrapped, err := callValuer(valuer)
+		if err != nil {
+			return nil, err
+		}
+		if bytes, ok := unwrapped.([]byte); ok {
+			return string(bytes), nil
+		}
+		return unwrapped, nil
+	}
+
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() != reflect.Ptr || !isScalarType(indirectType(reflected.Type())) {
+		return value, nil
+	}
+
+	for reflected.Kind() == reflect.Ptr {
+		if reflected.IsNil() {
+			return nil, nil
+		}
+		reflected = reflected.Elem()
+	}
+
+	switch reflected.Kind() {
+	case reflect.Bool:
+		return reflected.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflected.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflected.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return reflected.Float(), nil
+	case reflect.String:
+		return reflected.String(), nil
+	}
+
+	// time.Time.
+	return reflected.Interface(), nil
+}
+
+/*
+Returns the Value of [valuer], or a TPanicError if it panics.
+*/
+func callValuer(valuer driver.Valuer) (ret driver.Value, err error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			ret = nil
+			err = newPanicError(recovered, fmt.Sprintf("Value of '%T' panicked: %v", valuer, recovered))
+		}
+	}()
+
+	return valuer.Value()
+}
+
+func indirectType(valueType reflect.Type) reflect.Type {
+
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	return valueType
+}
+
+func isScalarType(valueType reflect.Type) bool {
+
+	switch valueType.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return valueType == reflect.TypeOf(time.Time{})
+}
+
 func castToFloat64(value interface{}) interface{} {
 	switch value.(type) {
 	case uint8:
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/*
+Checks this expression against the parameter types declared in [schema], without evaluating it.
+Returns a TMultiError listing every parameter the schema does not declare, and every operator which is given
+an operand whose declared (or inferred) type it can never accept, such as `name > 3` where name is a string.
+Operands of unknown type (TAnyType) are assumed to be acceptable.
+
+Calls to unknown functions are already rejected when the expression is compiled.
+Accessors such as `user.Age` are accepted if either the full path or the parameter it accesses (`user`) is declared.
+*/
+func (t tEvaluableExpression) TValidate(schema TSchema) error {
+
+	var errs []error
+	validateStage(t.evaluationStages, schema, &errs)
+
+	if len(errs) > 0 {
+		return TMultiError{Errors: errs}
+	}
+	return nil
+}
+
+func validateStage(stage *evaluationStage, schema TSchema, errs *[]error) {
+
+	if stage == nil {
+		return
+	}
+
+	validateStage(stage.leftStage, schema, errs)
+	validateStage(stage.rightStage, schema, errs)
+
+	switch stage.symbol {
+	case tVALUE:
+		fallthrough
+	case tACCESS:
+		if !isDeclared(stage.name, schema) {
+			*errs = append(*errs, fmt.Errorf("Parameter '%s' is not declared", stage.name))
+		}
+		return
+
+	case tLITERAL, tFUNCTIONAL, tNOOP, tSEPARATE:
+		return
+	}
+
+	var left, right interface{}
+	leftKnown := stage.leftStage != nil
+	rightKnown := stage.rightStage != nil
+
+	if leftKnown {
+		left, leftKnown = sampleOfType(inferStageType(stage.leftStage, schema))
+	}
+	if rightKnown {
+		right, rightKnown = sampleOfType(inferStageType(stage.rightStage, schema))
+	}
+
+	if stage.typeCheck != nil {
+		if leftKnown && rightKnown && !stage.typeCheck(left, right) {
+			*errs = append(*errs, fmt.Errorf("Operator '%v' can never accept a %v and a %v",
+				stage.symbol, typeOfValue(left), typeOfValue(right)))
+		}
+		return
+	}
+
+	if leftKnown && stage.leftTypeCheck != nil && !stage.leftTypeCheck(left) {
+		*errs = append(*errs, fmt.Errorf("Operator '%v' can never accept a %v", stage.symbol, typeOfValue(left)))
+	}
+	if rightKnown && stage.rightTypeCheck != nil && !stage.rightTypeCheck(right) {
+		*errs = append(*errs, fmt.Errorf("Operator '%v' can never accept a %v", stage.symbol, typeOfValue(right)))
+	}
+}
+
+func isDeclared(name string, schema TSchema) bool {
+
+	_, found := schema[name]
+	if found {
+		return true
+	}
+
+	root, _, isPath := strings.Cut(name, ".")
+	if isPath {
+		_, found = schema[root]
+	}
+	return found
+}
+
+/*
+Returns a representative value of the given type, which type checks can be tried against,
+or false if the type is not known.
+*/
+func sampleOfType(valueType TValueType) (interface{}, bool) {
+
+	switch valueType {
+	case TNumberType:
+		return 0.0, true
+	case TStringType:
+		return "", true
+	case TBoolType:
+		return false, true
+	case TTimeType:
+		return time.Time{}, true
+	case TArrayType:
+		return []interface{}{}, true
+	case TNilType:
+		return nil, true
+	}
+	return nil, false
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+const maskedValue string = "***"
+
+/*
+Returns what should be printed in place of [value], which was produced by [stage].
+Values of sensitive stages are masked, values of types with a registered stringer are rendered by it,
+and all others are returned as-is.
+*/
+func (t tEvaluableExpression) displayValue(stage *evaluationStage, value interface{}) interface{} {
+
+	if stage != nil && stage.sensitive {
+		return maskValue(value, t.options.MaskWithHash)
+	}
+
+	rendered, found := stringifyValue(value)
+	if found {
+		return rendered
+	}
+	return value
+}
+
+/*
+Marks each stage of [stage] which reads one of the sensitive parameters [names], or whose operands do,
+such as `lower(ssn)` or `toString(ssn)`, so that whatever it gives is masked wherever it is printed.
+Returns whether [stage] itself was marked.
+*/
+func markSensitiveStages(stage *evaluationStage, names []string) bool {
+
+	if stage == nil {
+		return false
+	}
+
+	// both operands are marked, even if the first is sensitive.
+	left := markSensitiveStages(stage.leftStage, names)
+	right := markSensitiveStages(stage.rightStage, names)
+	stage.sensitive = left || right
+
+	switch stage.symbol {
+	case tVALUE:
+		stage.sensitive = stage.sensitive || containsString(names, stage.name)
+	case tACCESS:
+		// fields of a sensitive parameter are as sensitive as it is.
+		stage.sensitive = stage.sensitive || containsString(names, stage.name) || containsString(names, stage.path[0])
+	}
+	return stage.sensitive
+}
+
+func maskValue(value interface{}, hash bool) string {
+
+	if !hash {
+		return maskedValue
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%T:%v", value, value)))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
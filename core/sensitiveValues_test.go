@@ -0,0 +1,104 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type recordingTracer struct {
+	events []TTraceEvent
+}
+
+func (r *recordingTracer) TEnter(event TTraceEvent) {}
+
+func (r *recordingTracer) TExit(event TTraceEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestSensitiveDerivedValuesAreMasked(test *testing.T) {
+
+	const ssn = "123-45-6789"
+
+	functions := map[string]tExpressionFunction{
+		"lower": func(arguments ...interface{}) (interface{}, error) {
+			return strings.ToLower(fmt.Sprint(arguments[0])), nil
+		},
+	}
+	options := TExpressionOptions{SensitiveParameters: []string{"ssn", "user"}}
+	parameters := map[string]interface{}{
+		"ssn":  ssn,
+		"user": struct{ SSN string }{ssn},
+	}
+
+	for _, text := range []string{
+		"toString(ssn)",
+		"ssn + ''",
+		"lower(ssn)",
+		"(ssn)",
+		"lower(ssn + '') == 'x'",
+		"ssn == '000-00-0000' ? 'found' : ssn",
+		"user.SSN + ''",
+	} {
+
+		expression, err := TNewEvaluableExpressionWithFunctionsAndOptions(text, functions, options)
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+
+		tracer := new(recordingTracer)
+		_, err = expression.TEvaluateWithOptions(tMapParameters(parameters), TEvaluationOptions{Tracer: tracer})
+		if err != nil {
+			test.Fatalf("%s: %v", text, err)
+		}
+
+		for _, event := range tracer.events {
+			for _, value := range []interface{}{event.Left, event.Right, event.Result} {
+				if strings.Contains(fmt.Sprint(value), ssn) {
+					test.Errorf("%s: the trace of '%s' printed %v", text, event.Expression, value)
+				}
+			}
+		}
+	}
+}
+
+func TestSensitiveDerivedValuesAreMaskedInErrors(test *testing.T) {
+
+	const ssn = "123-45-6789"
+
+	options := TExpressionOptions{SensitiveParameters: []string{"ssn"}}
+
+	expression, err := TNewEvaluableExpressionWithFunctionsAndOptions("toString(ssn) - 1", nil, options)
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	_, err = expression.TEvaluate(map[string]interface{}{"ssn": ssn})
+	if err == nil {
+		test.Fatal("expected a type error")
+	}
+	if strings.Contains(err.Error(), ssn) {
+		test.Errorf("the error printed the sensitive value: %v", err)
+	}
+}
+
+func TestInsensitiveValuesAreNotMasked(test *testing.T) {
+
+	options := TExpressionOptions{SensitiveParameters: []string{"ssn"}}
+
+	expression, err := TNewEvaluableExpressionWithFunctionsAndOptions("toString(age) + ''", nil, options)
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	tracer := new(recordingTracer)
+	_, err = expression.TEvaluateWithOptions(tMapParameters(map[string]interface{}{"age": 40}), TEvaluationOptions{Tracer: tracer})
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	last := tracer.events[len(tracer.events)-1]
+	if last.Result != "40" {
+		test.Errorf("expected the result to be printed as 40, got %v", last.Result)
+	}
+}
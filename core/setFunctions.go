@@ -0,0 +1,174 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+)
+
+/*
+`union(a, b)` returns the values in either of the arrays [a] and [b], in the order they first appear, without duplicates.
+Like the other set functions, it accepts arrays of any type, whose numbers are compared as float64s.
+Nil is taken to be an empty array, so that rules can combine tags which may be missing,
+and any other value to be an array of just that value - since `('beta')` is not an array, as `('beta', 'new')` is.
+*/
+func unionFunction(arguments ...interface{}) (interface{}, error) {
+
+	left, right, err := setArguments("union", arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	var seen valueSet
+	ret := make([]interface{}, 0, len(left)+len(right))
+
+	for _, value := range append(left, right...) {
+		if seen.add(value) {
+			ret = append(ret, value)
+		}
+	}
+	return ret, nil
+}
+
+/*
+`intersect(a, b)` returns the values of [a] which are also in [b], without duplicates.
+*/
+func intersectFunction(arguments ...interface{}) (interface{}, error) {
+
+	left, right, err := setArguments("intersect", arguments)
+	if err != nil {
+		return nil, err
+	}
+	return filterSet(left, newValueSet(right), true), nil
+}
+
+/*
+`difference(a, b)` returns the values of [a] which are not in [b], without duplicates.
+*/
+func differenceFunction(arguments ...interface{}) (interface{}, error) {
+
+	left, right, err := setArguments("difference", arguments)
+	if err != nil {
+		return nil, err
+	}
+	return filterSet(left, newValueSet(right), false), nil
+}
+
+/*
+`subset(a, b)` returns whether every value of [a] is also in [b].
+*/
+func subsetFunction(arguments ...interface{}) (interface{}, error) {
+
+	left, right, err := setArguments("subset", arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	others := newValueSet(right)
+	for _, value := range left {
+		if !others.contains(value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+/*
+Returns the values of [values] which are (or, if not [keep], are not) in [others], without duplicates.
+*/
+func filterSet(values []interface{}, others valueSet, keep bool) []interface{} {
+
+	var seen valueSet
+	ret := make([]interface{}, 0, len(values))
+
+	for _, value := range values {
+		if others.contains(value) == keep && seen.add(value) {
+			ret = append(ret, value)
+		}
+	}
+	return ret
+}
+
+func setArguments(function string, arguments []interface{}) ([]interface{}, []interface{}, error) {
+
+	if len(arguments) != 2 {
+		return nil, nil, fmt.Errorf("%s expects two arrays", function)
+	}
+
+	return toSetArray(arguments[0]), toSetArray(arguments[1]), nil
+}
+
+/*
+Returns the elements of the slice or array [value], with numbers converted to float64s.
+*/
+func toSetArray(value interface{}) []interface{} {
+
+	if value == nil {
+		return nil
+	}
+
+	reflected := reflect.ValueOf(value)
+	if !isList(reflected) {
+		return []interface{}{castToFloat64(value)}
+	}
+
+	ret := make([]interface{}, reflected.Len())
+	for i := range ret {
+		ret[i] = castToFloat64(reflected.Index(i).Interface())
+	}
+	return ret
+}
+
+/*
+A set of values, in which values are equal as they are for `in`.
+Values which can be hashed are, while the rest are compared with every other.
+*/
+type valueSet struct {
+	hashed map[interface{}]struct{}
+	others []interface{}
+}
+
+func newValueSet(values []interface{}) valueSet {
+
+	var ret valueSet
+	for _, value := range values {
+		ret.add(value)
+	}
+	return ret
+}
+
+/*
+Adds [value] to the set, returning false if it was already there.
+*/
+func (s *valueSet) add(value interface{}) bool {
+
+	if s.contains(value) {
+		return false
+	}
+
+	if isComparable(value) && !hasCustomEquality(value) {
+		if s.hashed == nil {
+			s.hashed = make(map[interface{}]struct{})
+		}
+		s.hashed[value] = struct{}{}
+		return true
+	}
+
+	s.others = append(s.others, value)
+	return true
+}
+
+func (s valueSet) contains(value interface{}) bool {
+
+	if isComparable(value) {
+		if _, found := s.hashed[value]; found {
+			return true
+		}
+	}
+
+	for _, other := range s.others {
+		if deepEqual(value, other) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package core
+
+/*
+Returns a simplified copy of this expression, with boolean and arithmetic identities applied:
+`x && true` and `x || false` become `x`, `x * 1` becomes `x`, `!!x` becomes `x`, redundant parentheses are removed, and so on.
+This expression is left unchanged.
+
+Simplification assumes the expression would evaluate without type errors; for instance `!!x` becomes `x`
+even though the original fails if `x` is not a bool. Since `+` also concatenates strings, `x + 0` is only simplified
+if `x` is known to be a number - either from its form, or because [schema] (which may be nil) declares it so.
+*/
+func (t tEvaluableExpression) TSimplify(schema TSchema) (*tEvaluableExpression, error) {
+
+	if t.evaluationStages == nil {
+		return &t, nil
+	}
+
+	simplified := simplifyStage(copyStages(t.evaluationStages), schema)
+
+	// re-parse the simplified form, so that its tokens (and everything derived from them) match its stages.
+	functions := make(map[string]tExpressionFunction)
+	for _, token := range t.tokens {
+		if token.Kind == tFUNCTION {
+			function := token.Value.(tNamedFunction)
+			functions[function.name] = function.function
+		}
+	}
+
+	ret, err := TNewEvaluableExpressionWithFunctionsAndOptions(renderStage(simplified), functions, t.options)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+/*
+Returns a deep copy of the given stage tree.
+*/
+func copyStages(stage *evaluationStage) *evaluationStage {
+
+	if stage == nil {
+		return nil
+	}
+
+	ret := *stage
+	ret.leftStage = copyStages(stage.leftStage)
+	ret.rightStage = copyStages(stage.rightStage)
+	return &ret
+}
+
+func simplifyStage(stage *evaluationStage, schema TSchema) *evaluationStage {
+
+	if stage == nil {
+		return nil
+	}
+
+	stage.leftStage = simplifyStage(stage.leftStage, schema)
+	stage.rightStage = simplifyStage(stage.rightStage, schema)
+
+	switch stage.symbol {
+	case tNOOP:
+		// parentheses only matter for planning, the stage tree already encodes their grouping.
+		if stage.rightStage != nil {
+			return stage.rightStage
+		}
+
+	case tAND:
+		if isLiteralValue(stage.leftStage, true) {
+			return stage.rightStage
+		}
+		if isLiteralValue(stage.rightStage, true) {
+			return stage.leftStage
+		}
+		if isLiteralValue(stage.leftStage, false) {
+			return stage.leftStage
+		}
+
+	case tOR:
+		if isLiteralValue(stage.leftStage, false) {
+			return stage.rightStage
+		}
+		if isLiteralValue(stage.rightStage, false) {
+			return stage.leftStage
+		}
+		if isLiteralValue(stage.leftStage, true) {
+			return stage.leftStage
+		}
+
+	case tXOR:
+		if isLiteralValue(stage.leftStage, false) {
+			return stage.rightStage
+		}
+		if isLiteralValue(stage.rightStage, false) {
+			return stage.leftStage
+		}
+
+	case tPLUS:
+		if isLiteralValue(stage.rightStage, 0.0) && inferStageType(stage.leftStage, schema) == TNumberType {
+			return stage.leftStage
+		}
+		if isLiteralValue(stage.leftStage, 0.0) && inferStageType(stage.rightStage, schema) == TNumberType {
+			return stage.rightStage
+		}
+
+	case tMINUS:
+		if isLiteralValue(stage.rightStage, 0.0) {
+			return stage.leftStage
+		}
+
+	case tMULTIPLY:
+		if isLiteralValue(stage.rightStage, 1.0) {
+			return stage.leftStage
+		}
+		if isLiteralValue(stage.leftStage, 1.0) {
+			return stage.rightStage
+		}
+
+	case tDIVIDE:
+		fallthrough
+	case tEXPONENT:
+		if isLiteralValue(stage.rightStage, 1.0) {
+			return stage.leftStage
+		}
+
+	case tINVERT:
+		fallthrough
+	case tNEGATE:
+		fallthrough
+	case tBITWISE_NOT:
+		if stage.rightStage != nil && stage.rightStage.symbol == stage.symbol {
+			return stage.rightStage.rightStage
+		}
+	}
+
+	return elideStage(stage)
+}
+
+func isLiteralValue(stage *evaluationStage, expected interface{}) bool {
+
+	if stage == nil || stage.symbol != tLITERAL {
+		return false
+	}
+
+	value, err := stage.operator(nil, nil, nil)
+	return err == nil && value == expected
+}
@@ -0,0 +1,522 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlEnd sqlTokenKind = iota
+	sqlIdentifier
+	sqlKeyword
+	sqlNumber
+	sqlString
+	sqlSymbol
+)
+
+type sqlToken struct {
+	kind     sqlTokenKind
+	text     string
+	position int
+}
+
+var sqlKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IN": true, "BETWEEN": true, "LIKE": true, "ESCAPE": true,
+	"IS": true, "NULL": true, "TRUE": true, "FALSE": true,
+}
+
+var sqlComparators = map[string]string{
+	"=":  "==",
+	"<>": "!=",
+	"!=": "!=",
+	"<":  "<",
+	">":  ">",
+	"<=": "<=",
+	">=": ">=",
+}
+
+/*
+Translates [where], the predicate of a SQL WHERE clause (without the WHERE), into an equivalent expression,
+in the canonical form of TFormat, such as `age >= 18 AND name LIKE 'a%'` into `age >= 18 && name =~ '(?s)^a.*$'`.
+
+AND, OR, NOT, the comparators, IN, BETWEEN, LIKE, IS [NOT] NULL, arithmetic, and `||` (as concatenation) translate.
+Line comments, from `--`, and block comments are ignored, as they are in SQL.
+Column names may be quoted with double quotes, backticks, or brackets; qualified names, such as `users.age`,
+translate to parameters of the same name, as `[users.age]`. Comparisons with NULL translate to comparisons with nil, which, unlike SQL, may be true;
+such expressions must be compiled with NilLiteral set.
+String literals stay strings, even those which look like times, such as '2024-01-01', which are written as raw strings.
+Anything else, such as function calls and subqueries, fails to translate with an error naming where it was found.
+*/
+func TFromSQL(where string) (string, error) {
+
+	tokens, err := lexSQL(where)
+	if err != nil {
+		return "", err
+	}
+
+	parser := sqlParser{tokens: tokens}
+	translated, err := parser.parseOr()
+	if err != nil {
+		return "", err
+	}
+	if parser.peek().kind != sqlEnd {
+		return "", parser.unexpected()
+	}
+
+	expression, err := TNewEvaluableExpressionWithOptions(translated, TExpressionOptions{NilLiteral: true})
+	if err != nil {
+		return "", fmt.Errorf("Unable to translate SQL: %v", err)
+	}
+	return expression.TFormat(), nil
+}
+
+func lexSQL(where string) ([]sqlToken, error) {
+
+	var tokens []sqlToken
+	runes := []rune(where)
+
+	for i := 0; i < len(runes); {
+
+		character := runes[i]
+		start := i
+
+		switch {
+		case unicode.IsSpace(character):
+			i++
+			continue
+
+		// comments are skipped as spaces are, as in SQL; `--` is never two minus signs.
+		case character == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			continue
+
+		case character == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i+1 < len(runes) && (runes[i] != '*' || runes[i+1] != '/'); i++ {
+			}
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("Unclosed comment in SQL at %d", start)
+			}
+			i += 2
+			continue
+
+		case unicode.IsLetter(character) || character == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+
+			text := string(runes[start:i])
+			kind := sqlIdentifier
+			if sqlKeywords[strings.ToUpper(text)] {
+				kind = sqlKeyword
+				text = strings.ToUpper(text)
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: text, position: start})
+			continue
+
+		case unicode.IsDigit(character) || (character == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '+' || runes[i] == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlNumber, text: string(runes[start:i]), position: start})
+			continue
+
+		case character == '\'' || character == '"' || character == '`' || character == '[':
+			closing := character
+			if character == '[' {
+				closing = ']'
+			}
+
+			// a doubled closing quote stands for one, as in 'O''Brien'.
+			var text strings.Builder
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("Unclosed %c in SQL at %d", character, start)
+				}
+				if runes[i] == closing {
+					if i+1 < len(runes) && runes[i+1] == closing && closing != ']' {
+						i++
+					} else {
+						break
+					}
+				}
+				text.WriteRune(runes[i])
+			}
+			i++
+
+			kind := sqlIdentifier
+			if character == '\'' {
+				kind = sqlString
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: text.String(), position: start})
+			continue
+		}
+
+		// the longest symbol which matches.
+		symbol := string(character)
+		if i+1 < len(runes) {
+			switch pair := string(runes[i : i+2]); pair {
+			case "<>", "!=", "<=", ">=", "||":
+				symbol = pair
+			}
+		}
+
+		if !strings.Contains("=<>!|+-*/%(),", symbol[:1]) || symbol == "!" || symbol == "|" {
+			return nil, fmt.Errorf("Unexpected '%s' in SQL at %d", symbol, start)
+		}
+
+		tokens = append(tokens, sqlToken{kind: sqlSymbol, text: symbol, position: start})
+		i += len([]rune(symbol))
+	}
+
+	return append(tokens, sqlToken{kind: sqlEnd, position: len(runes)}), nil
+}
+
+/*
+Parses SQL tokens by recursive descent, translating each construct into (parenthesized) expression text as it goes.
+*/
+type sqlParser struct {
+	tokens []sqlToken
+	index  int
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.index]
+}
+
+func (p *sqlParser) next() sqlToken {
+
+	token := p.tokens[p.index]
+	if token.kind != sqlEnd {
+		p.index++
+	}
+	return token
+}
+
+/*
+Consumes the next token if it is the keyword or symbol [text].
+*/
+func (p *sqlParser) accept(text string) bool {
+
+	token := p.peek()
+	if (token.kind == sqlKeyword || token.kind == sqlSymbol) && token.text == text {
+		p.index++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) expect(text string) error {
+
+	if !p.accept(text) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *sqlParser) unexpected() error {
+
+	token := p.peek()
+	if token.kind == sqlEnd {
+		return errors.New("Unexpected end of SQL")
+	}
+	return fmt.Errorf("Unable to translate '%s' in SQL at %d", token.text, token.position)
+}
+
+func (p *sqlParser) parseOr() (string, error) {
+	return p.parseJoined("OR", "||", p.parseAnd)
+}
+
+func (p *sqlParser) parseAnd() (string, error) {
+	return p.parseJoined("AND", "&&", p.parseNot)
+}
+
+func (p *sqlParser) parseJoined(keyword string, operator string, parseOperand func() (string, error)) (string, error) {
+
+	ret, err := parseOperand()
+	if err != nil {
+		return "", err
+	}
+
+	for p.accept(keyword) {
+
+		operand, err := parseOperand()
+		if err != nil {
+			return "", err
+		}
+		ret = "(" + ret + " " + operator + " " + operand + ")"
+	}
+	return ret, nil
+}
+
+func (p *sqlParser) parseNot() (string, error) {
+
+	if p.accept("NOT") {
+
+		operand, err := p.parseNot()
+		if err != nil {
+			return "", err
+		}
+		return "!(" + operand + ")", nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *sqlParser) parsePredicate() (string, error) {
+
+	left, err := p.parseAdditive()
+	if err != nil {
+		return "", err
+	}
+
+	token := p.peek()
+	if comparator, found := sqlComparators[token.text]; found && token.kind == sqlSymbol {
+
+		p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " " + comparator + " " + right + ")", nil
+	}
+
+	if p.accept("IS") {
+
+		comparator := "=="
+		if p.accept("NOT") {
+			comparator = "!="
+		}
+		if err := p.expect("NULL"); err != nil {
+			return "", err
+		}
+		return "(" + left + " " + comparator + " nil)", nil
+	}
+
+	negated := p.accept("NOT")
+
+	var ret string
+	switch {
+	case p.accept("IN"):
+		ret, err = p.parseIn(left)
+	case p.accept("BETWEEN"):
+		ret, err = p.parseBetween(left)
+	case p.accept("LIKE"):
+		ret, err = p.parseLike(left)
+	default:
+		if negated {
+			return "", p.unexpected()
+		}
+		return left, nil
+	}
+
+	if err != nil || !negated {
+		return ret, err
+	}
+	return "!" + ret, nil
+}
+
+func (p *sqlParser) parseIn(left string) (string, error) {
+
+	if err := p.expect("("); err != nil {
+		return "", err
+	}
+
+	var values []string
+	for {
+		value, err := p.parseAdditive()
+		if err != nil {
+			return "", err
+		}
+		values = append(values, value)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if err := p.expect(")"); err != nil {
+		return "", err
+	}
+
+	// a list of one cannot be written, since `(1)` is only a parenthesized 1.
+	if len(values) == 1 {
+		return "(" + left + " == " + values[0] + ")", nil
+	}
+	return "(" + left + " in (" + strings.Join(values, ", ") + "))", nil
+}
+
+func (p *sqlParser) parseBetween(left string) (string, error) {
+
+	low, err := p.parseAdditive()
+	if err != nil {
+		return "", err
+	}
+	if err = p.expect("AND"); err != nil {
+		return "", err
+	}
+	high, err := p.parseAdditive()
+	if err != nil {
+		return "", err
+	}
+	return "(" + left + " >= " + low + " && " + left + " <= " + high + ")", nil
+}
+
+func (p *sqlParser) parseLike(left string) (string, error) {
+
+	token := p.peek()
+	if token.kind != sqlString {
+		return "", p.unexpected()
+	}
+	p.next()
+
+	if p.peek().kind == sqlKeyword && p.peek().text == "ESCAPE" {
+		return "", p.unexpected()
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("(?s)^")
+
+	for _, character := range token.text {
+		switch character {
+		case '%':
+			pattern.WriteString(".*")
+		case '_':
+			pattern.WriteString(".")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(character)))
+		}
+	}
+
+	pattern.WriteString("$")
+	return "(" + left + " =~ " + quoteString(pattern.String()) + ")", nil
+}
+
+func (p *sqlParser) parseAdditive() (string, error) {
+
+	ret, err := p.parseMultiplicative()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		token := p.peek()
+		if token.kind != sqlSymbol || (token.text != "+" && token.text != "-" && token.text != "||") {
+			return ret, nil
+		}
+		p.next()
+
+		// `||` concatenates strings in SQL, as `+` does here.
+		operator := token.text
+		if operator == "||" {
+			operator = "+"
+		}
+
+		operand, err := p.parseMultiplicative()
+		if err != nil {
+			return "", err
+		}
+		ret = "(" + ret + " " + operator + " " + operand + ")"
+	}
+}
+
+func (p *sqlParser) parseMultiplicative() (string, error) {
+
+	ret, err := p.parseUnary()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		token := p.peek()
+		if token.kind != sqlSymbol || (token.text != "*" && token.text != "/" && token.text != "%") {
+			return ret, nil
+		}
+		p.next()
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		ret = "(" + ret + " " + token.text + " " + operand + ")"
+	}
+}
+
+func (p *sqlParser) parseUnary() (string, error) {
+
+	if p.accept("-") {
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		return "-(" + operand + ")", nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *sqlParser) parsePrimary() (string, error) {
+
+	token := p.peek()
+
+	switch token.kind {
+	case sqlNumber:
+		// exponents, such as 1e3, cannot be written here.
+		number, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return "", p.unexpected()
+		}
+		p.next()
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+
+	case sqlString:
+		p.next()
+		return quoteString(token.text), nil
+
+	case sqlIdentifier:
+		p.next()
+
+		// function calls are not translated, since there are no functions of the same names to call.
+		if p.peek().kind == sqlSymbol && p.peek().text == "(" {
+			p.index--
+			return "", p.unexpected()
+		}
+		// bracketed, so that columns may be named as functions are, such as `count`.
+		return bracketName(token.text), nil
+
+	case sqlKeyword:
+		switch token.text {
+		case "TRUE":
+			p.next()
+			return "true", nil
+		case "FALSE":
+			p.next()
+			return "false", nil
+		case "NULL":
+			p.next()
+			return "nil", nil
+		}
+
+	case sqlSymbol:
+		if p.accept("(") {
+
+			ret, err := p.parseOr()
+			if err != nil {
+				return "", err
+			}
+			if err = p.expect(")"); err != nil {
+				return "", err
+			}
+			return "(" + ret + ")", nil
+		}
+	}
+
+	return "", p.unexpected()
+}
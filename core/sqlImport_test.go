@@ -0,0 +1,101 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromSQL(test *testing.T) {
+
+	cases := []struct {
+		sql      string
+		expected string
+	}{
+		{"age >= 18 AND country = 'US'", "age >= 18 && country == 'US'"},
+		{"a <> 1 OR NOT b", "a != 1 || !b"},
+		{"x IN (1, 2, 3)", "x in (1, 2, 3)"},
+		{"name IS NULL", "name == nil"},
+		{"users.age > 18", "[users.age] > 18"},
+		{"a - -1 > 0", "a - -1 > 0"},
+		{"count > 5", "count > 5"},
+		{"status = 'active' AND max >= 3", "status == 'active' && max >= 3"},
+		{"created = '2024-01-01'", "created == `2024-01-01`"},
+		{`"odd]name" = 1`, `[odd\]name] == 1`},
+	}
+
+	for _, c := range cases {
+
+		translated, err := TFromSQL(c.sql)
+		if err != nil {
+			test.Errorf("%s: failed to translate: %v", c.sql, err)
+			continue
+		}
+		if translated != c.expected {
+			test.Errorf("%s: expected %s, got %s", c.sql, c.expected, translated)
+		}
+	}
+}
+
+/*
+String literals stay strings, even those which look like times.
+*/
+func TestFromSQLStrings(test *testing.T) {
+
+	translated, err := TFromSQL("created = '2024-01-01' AND day = '2024-01-01 10:00'")
+	if err != nil {
+		test.Fatal(err)
+	}
+	expression, err := TNewEvaluableExpressionWithOptions(translated, conformanceOptions)
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	result, err := expression.TEvaluate(map[string]interface{}{"created": "2024-01-01", "day": "2024-01-01 10:00"})
+	if err != nil || result != true {
+		test.Errorf("%s: expected the strings to equal, got %v (%v)", translated, result, err)
+	}
+}
+
+func TestFromSQLComments(test *testing.T) {
+
+	cases := []struct {
+		sql      string
+		expected string
+	}{
+		{"a = 1 -- comment", "a == 1"},
+		{"a = 1 --comment", "a == 1"},
+		{"a = 1 -- comment\nAND b = 2", "a == 1 && b == 2"},
+		{"a = 1 /* comment */ AND b = 2", "a == 1 && b == 2"},
+		{"a = /* multi\nline */ 1", "a == 1"},
+		{"a = '-- not a comment'", "a == '-- not a comment'"},
+		{"a = '/* not a comment */'", "a == '/* not a comment */'"},
+	}
+
+	for _, c := range cases {
+
+		translated, err := TFromSQL(c.sql)
+		if err != nil {
+			test.Errorf("%q: failed to translate: %v", c.sql, err)
+			continue
+		}
+		if translated != c.expected {
+			test.Errorf("%q: expected %s, got %s", c.sql, c.expected, translated)
+		}
+
+		// a comment must never read a parameter of the words within it.
+		expression, err := TNewEvaluableExpressionWithOptions(translated, conformanceOptions)
+		if err != nil {
+			test.Fatal(err)
+		}
+		for _, name := range expression.TVars() {
+			if strings.Contains(name, "comment") {
+				test.Errorf("%q: translated into %s, which reads '%s'", c.sql, translated, name)
+			}
+		}
+	}
+
+	_, err := TFromSQL("a = 1 /* unclosed")
+	if err == nil {
+		test.Errorf("expected an unclosed comment to fail to translate")
+	}
+}
@@ -3,6 +3,8 @@ package core
 import (
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 )
 
@@ -17,6 +19,7 @@ var stageSymbolMap = map[tOperatorSymbol]evaluationOperator{
 	tNREQ:           notRegexStage,
 	tAND:            andStage,
 	tOR:             orStage,
+	tXOR:            xorStage,
 	tIN:             inStage,
 	tBITWISE_OR:     bitwiseOrStage,
 	tBITWISE_AND:    bitwiseAndStage,
@@ -27,6 +30,7 @@ var stageSymbolMap = map[tOperatorSymbol]evaluationOperator{
 	tMINUS:          subtractStage,
 	tMULTIPLY:       multiplyStage,
 	tDIVIDE:         divideStage,
+	tFLOOR_DIVIDE:   floorDivideStage,
 	tMODULUS:        modulusStage,
 	tEXPONENT:       exponentStage,
 	tNEGATE:         negateStage,
@@ -104,13 +108,13 @@ func init() {
 	planShift = makePrecedentFromPlanner(&precedencePlanner{
 		validSymbols:    bitwiseShiftSymbols,
 		validKinds:      []tTokenKind{tMODIFIER},
-		typeErrorFormat: modifierErrorFormat,
+		typeErrorFormat: bitwiseErrorFormat,
 		next:            planAdditive,
 	})
 	planBitwise = makePrecedentFromPlanner(&precedencePlanner{
 		validSymbols:    bitwiseSymbols,
 		validKinds:      []tTokenKind{tMODIFIER},
-		typeErrorFormat: modifierErrorFormat,
+		typeErrorFormat: bitwiseErrorFormat,
 		next:            planShift,
 	})
 	planComparator = makePrecedentFromPlanner(&precedencePlanner{
@@ -126,7 +130,7 @@ func init() {
 		next:            planComparator,
 	})
 	planLogicalOr = makePrecedentFromPlanner(&precedencePlanner{
-		validSymbols:    map[string]tOperatorSymbol{"||": tOR},
+		validSymbols:    map[string]tOperatorSymbol{"||": tOR, "xor": tXOR},
 		validKinds:      []tTokenKind{tLOGICALOP},
 		typeErrorFormat: logicalErrorFormat,
 		next:            planLogicalAnd,
@@ -178,20 +182,63 @@ Creates a `evaluationStageList` object which represents an execution plan (or tr
 which is used to completely evaluate a set of tokens at evaluation-time.
 The three stages of evaluation can be thought of as parsing strings to tokens, then tokens to a stage list, then evaluation with parameters.
 */
-func planStages(tokens []tExpressionToken) (*evaluationStage, error) {
+func planStages(tokens []tExpressionToken, options TExpressionOptions) (*evaluationStage, error) {
+
+	stage, err := planReferenceStages(tokens, options)
+	if err != nil || stage == nil {
+		return stage, err
+	}
+
+	stage = elideLiterals(stage)
+	eliminateCommonSubexpressions(stage)
+	prepareLazyStages(stage)
+
+	if options.NativeClosures {
+		lowerStages(stage, options)
+	}
+	return stage, nil
+}
+
+/*
+Plans the given tokens without applying any optional optimizations.
+The resulting plan is slower, but simple enough to be used as a reference for the optimized one.
+*/
+func planReferenceStages(tokens []tExpressionToken, options TExpressionOptions) (*evaluationStage, error) {
 
 	stream := newTokenStream(tokens)
+	stream.options = options
 
 	stage, err := planTokens(stream)
-	if err != nil {
+	if err != nil || stage == nil {
 		return nil, err
 	}
 
 	// while we're now fully-planned, we now need to re-order same-precedence operators.
 	// this could probably be avoided with a different planning method
 	reorderStages(stage)
+	prepareSeparators(stage)
 
-	stage = elideLiterals(stage)
+	if len(options.Enums) > 0 {
+		err = resolveEnums(stage, options.Enums)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Features[TFeatureTruthiness] {
+		applyTruthiness(stage)
+	}
+
+	if options.CoerceStringers {
+		applyStringerCoercion(stage)
+	}
+
+	prepareExactOperands(stage, options)
+	widenInexactLiterals(stage, false)
+
+	if len(options.SensitiveParameters) > 0 {
+		markSensitiveStages(stage, options.SensitiveParameters)
+	}
 	return stage, nil
 }
 
@@ -276,7 +323,7 @@ func planPrecedenceLevel(
 			symbol:     symbol,
 			leftStage:  leftStage,
 			rightStage: rightStage,
-			operator:   stageSymbolMap[symbol],
+			operator:   findOperator(symbol, stream.options),
 
 			leftTypeCheck:   checks.left,
 			rightTypeCheck:  checks.right,
@@ -310,11 +357,22 @@ func planFunction(stream *tokenStream) (*evaluationStage, error) {
 		return nil, err
 	}
 
+	function := token.Value.(tNamedFunction)
+
+	// a single argument is found directly inside the call's parentheses, where several would be separated.
+	// Only built-ins are given it as it is; other functions are given an array argument spread, as they always have been.
+	single := function.builtin && rightStage != nil && rightStage.symbol == tNOOP &&
+		rightStage.rightStage != nil && rightStage.rightStage.symbol != tSEPARATE
+
 	return &evaluationStage{
 
 		symbol:          tFUNCTIONAL,
+		name:            function.name,
+		pure:            function.pure,
+		single:          single,
+		position:        function.position,
 		rightStage:      rightStage,
-		operator:        makeFunctionStage(token.Value.(tExpressionFunction)),
+		operator:        makeFunctionStage(function.function, single),
 		typeErrorFormat: "Unable to run function '%v': %v",
 	}, nil
 }
@@ -358,8 +416,10 @@ func planAccessor(stream *tokenStream) (*evaluationStage, error) {
 	return &evaluationStage{
 
 		symbol:          tACCESS,
+		name:            strings.Join(token.Value.([]string), "."),
+		path:            token.Value.([]string),
 		rightStage:      rightStage,
-		operator:        makeAccessorStage(token.Value.([]string)),
+		operator:        makeAccessorStage(token.Value.([]string), stream.options),
 		typeErrorFormat: "Unable to access parameter field or method '%v': %v",
 	}, nil
 }
@@ -405,6 +465,11 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 
 	case tVARIABLE:
 		operator = makeParameterStage(token.Value.(string))
+		return &evaluationStage{
+			symbol:   symbol,
+			name:     token.Value.(string),
+			operator: operator,
+		}, nil
 	case tNUMERIC:
 		fallthrough
 	case tSTRING:
@@ -412,11 +477,16 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 	case tPATTERN:
 		fallthrough
 	case tBOOLEAN:
+		fallthrough
+	case tNIL:
 		symbol = tLITERAL
 		operator = makeLiteralStage(token.Value)
 	case tTIME:
-		symbol = tLITERAL
-		operator = makeLiteralStage(float64(token.Value.(time.Time).Unix()))
+		return &evaluationStage{
+			symbol:   tLITERAL,
+			operator: makeLiteralStage(float64(token.Value.(time.Time).Unix())),
+			source:   renderToken(token),
+		}, nil
 
 	case tPREFIX:
 		stream.rewind()
@@ -444,6 +514,43 @@ type typeChecks struct {
 	combined stageCombinedTypeCheck
 }
 
+/*
+Returns the operator which evaluates the given [symbol], as changed by [options].
+*/
+func findOperator(symbol tOperatorSymbol, options TExpressionOptions) evaluationOperator {
+
+	switch symbol {
+	case tDIVIDE:
+		if options.IntegerMath {
+			return guardDivision(integerDivideStage, options.DivisionByZero)
+		}
+		return guardDivision(stageSymbolMap[symbol], options.DivisionByZero)
+	case tMODULUS:
+		if options.EuclideanModulus {
+			return guardDivision(euclideanModulusStage, options.DivisionByZero)
+		}
+		fallthrough
+	case tFLOOR_DIVIDE:
+		return guardDivision(stageSymbolMap[symbol], options.DivisionByZero)
+	case tBITWISE_LSHIFT:
+		if options.Overflow == TOverflowError {
+			return checkedLeftShiftStage
+		}
+	case tEQ, tNEQ:
+		if options.DeepEquality {
+			return guardNaN(makeDeepEqualityStage(symbol == tNEQ), options.NaNComparisons)
+		}
+		fallthrough
+	case tGT, tLT, tGTE, tLTE:
+		return guardNaN(stageSymbolMap[symbol], options.NaNComparisons)
+	case tIN:
+		if options.DeepEquality {
+			return deepInStage
+		}
+	}
+	return stageSymbolMap[symbol]
+}
+
 /*
 Maps a given [symbol] to a set of typechecks to be used during runtime.
 */
@@ -470,6 +577,8 @@ func findTypeChecks(symbol tOperatorSymbol) typeChecks {
 	case tAND:
 		fallthrough
 	case tOR:
+		fallthrough
+	case tXOR:
 		return typeChecks{
 			left:  isBool,
 			right: isBool,
@@ -488,8 +597,8 @@ func findTypeChecks(symbol tOperatorSymbol) typeChecks {
 		fallthrough
 	case tBITWISE_XOR:
 		return typeChecks{
-			left:  isFloat64,
-			right: isFloat64,
+			left:  isBitwiseOperand,
+			right: isBitwiseOperand,
 		}
 	case tPLUS:
 		return typeChecks{
@@ -501,6 +610,8 @@ func findTypeChecks(symbol tOperatorSymbol) typeChecks {
 		fallthrough
 	case tDIVIDE:
 		fallthrough
+	case tFLOOR_DIVIDE:
+		fallthrough
 	case tMODULUS:
 		fallthrough
 	case tEXPONENT:
@@ -518,7 +629,7 @@ func findTypeChecks(symbol tOperatorSymbol) typeChecks {
 		}
 	case tBITWISE_NOT:
 		return typeChecks{
-			right: isFloat64,
+			right: isBitwiseOperand,
 		}
 	case tTERNARY_TRUE:
 		return typeChecks{
@@ -539,6 +650,24 @@ func findTypeChecks(symbol tOperatorSymbol) typeChecks {
 	}
 }
 
+/*
+Recurses through all stages, so that the first separator of each list begins a new list,
+rather than appending to its left operand when that is an array - as in `(tags, 'new')`.
+*/
+func prepareSeparators(stage *evaluationStage) {
+
+	if stage == nil {
+		return
+	}
+
+	if stage.symbol == tSEPARATE && (stage.leftStage == nil || stage.leftStage.symbol != tSEPARATE) {
+		stage.operator = firstSeparatorStage
+	}
+
+	prepareSeparators(stage.leftStage)
+	prepareSeparators(stage.rightStage)
+}
+
 /*
 During stage planning, stages of equal precedence are parsed such that they'll be evaluated in reverse order.
 For commutative operators like "+" or "-", it's no big deal. But for order-specific operators, it ruins the expected result.
@@ -645,9 +774,84 @@ func elideLiterals(root *evaluationStage) *evaluationStage {
 		root.rightStage = elideLiterals(root.rightStage)
 	}
 
+	pruned := pruneDeadBranch(root)
+	if pruned != root {
+		return pruned
+	}
 	return elideStage(root)
 }
 
+/*
+Prunes the branch of a short-circuiting stage which its literal condition means can never be evaluated,
+such as `x` in `false && x` or `true ? y : x`, leaving only what would be evaluated.
+Returns the unmodified [root] stage if its condition is not a literal, or if nothing can be pruned.
+*/
+func pruneDeadBranch(root *evaluationStage) *evaluationStage {
+
+	if !root.isShortCircuitable() {
+		return root
+	}
+
+	// an else whose condition was pruned, leaving only its value, gives what `??` would.
+	if root.symbol == tTERNARY_FALSE && unparenthesized(root.leftStage).symbol != tTERNARY_TRUE {
+		root.symbol = tCOALESCE
+	}
+
+	condition := unparenthesized(root.leftStage)
+	if condition == nil || condition.symbol != tLITERAL {
+		return root
+	}
+
+	value, err := condition.operator(nil, nil, nil)
+	if err != nil {
+		return root
+	}
+
+	// with TFeatureTruthiness, conditions are converted to bools, as they would be when evaluated.
+	if root.truthy && (root.symbol == tAND || root.symbol == tOR || root.symbol == tTERNARY_TRUE) {
+		value = isTruthy(value)
+	}
+
+	switch root.symbol {
+	case tAND:
+		if value == false {
+			return foldedLiteral(root, false)
+		}
+	case tOR:
+		if value == true {
+			return foldedLiteral(root, true)
+		}
+
+	// a ternary without an else gives nil if its condition is false; otherwise its value, which is given to
+	// the else (if any), which gives that value, or its own if the value is nil.
+	case tTERNARY_TRUE:
+		switch value {
+		case true:
+			return root.rightStage
+		case false:
+			return foldedLiteral(root, nil)
+		}
+	case tTERNARY_FALSE, tCOALESCE:
+		if value != nil {
+			return root.leftStage
+		}
+		return root.rightStage
+	}
+	return root
+}
+
+/*
+Returns a literal stage of [value], which was found by folding [root].
+*/
+func foldedLiteral(root *evaluationStage, value interface{}) *evaluationStage {
+
+	return &evaluationStage{
+		symbol:   tLITERAL,
+		operator: makeLiteralStage(value),
+		folded:   renderStage(root),
+	}
+}
+
 /*
 Elides a specific stage, if possible.
 Returns the unmodified [root] stage if it cannot or should not be elided.
@@ -658,6 +862,10 @@ func elideStage(root *evaluationStage) *evaluationStage {
 	var leftValue, rightValue, result interface{}
 	var err error
 
+	if root.symbol == tFUNCTIONAL && root.pure {
+		return elidePureFunction(root)
+	}
+
 	// right side must be a non-nil value. Left side must be nil or a value.
 	if root.rightStage == nil ||
 		root.rightStage.symbol != tLITERAL ||
@@ -687,12 +895,12 @@ func elideStage(root *evaluationStage) *evaluationStage {
 	}
 
 	// typcheck, since the grammar checker is a bit loose with which operator symbols go together.
-	err = typeCheck(root.leftTypeCheck, leftValue, root.symbol, root.typeErrorFormat)
+	err = typeCheck(root.leftTypeCheck, leftValue, leftValue, root.symbol, root.typeErrorFormat)
 	if err != nil {
 		return root
 	}
 
-	err = typeCheck(root.rightTypeCheck, rightValue, root.symbol, root.typeErrorFormat)
+	err = typeCheck(root.rightTypeCheck, rightValue, rightValue, root.symbol, root.typeErrorFormat)
 	if err != nil {
 		return root
 	}
@@ -710,5 +918,47 @@ func elideStage(root *evaluationStage) *evaluationStage {
 	return &evaluationStage{
 		symbol:   tLITERAL,
 		operator: makeLiteralStage(result),
+		folded:   renderStage(root),
+	}
+}
+
+/*
+Elides a call to a pure function, if all of its arguments are literals and it returns neither an array nor a map.
+*/
+func elidePureFunction(root *evaluationStage) *evaluationStage {
+
+	var arguments, result interface{}
+	var err error
+
+	if root.rightStage != nil {
+
+		_, literal := literalArguments(root.rightStage)
+		if !literal {
+			return root
+		}
+
+		// the arguments are only literals, lists, and parentheses - none of which need parameters.
+		arguments, err = tEvaluableExpression{checksTypes: true}.evaluateStage(root.rightStage, tDUMMY_PARAMETERS)
+		if err != nil {
+			return root
+		}
+	}
+
+	// calls which fail, or panic, are left to fail when evaluated.
+	result, err = callFunction(root, arguments, nil)
+	if err != nil {
+		return root
+	}
+
+	// every evaluation would share one array or map, which whoever is given it could modify.
+	switch reflect.ValueOf(result).Kind() {
+	case reflect.Slice, reflect.Map:
+		return root
+	}
+
+	return &evaluationStage{
+		symbol:   tLITERAL,
+		operator: makeLiteralStage(result),
+		folded:   renderStage(root),
 	}
 }
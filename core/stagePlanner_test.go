@@ -0,0 +1,147 @@
+package core
+
+import (
+	"math/rand"
+	"testing"
+)
+
+/*
+Expressions whose conditions are, or fold into, literals, so that pruneDeadBranch removes a branch of each.
+*/
+var prunedCorpus = []string{
+	"true && a > 1",
+	"false && a > 1",
+	"true || s == 'a'",
+	"false || s == 'a'",
+	"(1 > 2) || flag",
+	"!(false) && b <= 3",
+	"true ? a : b",
+	"false ? a : b",
+	"(2 > 1) ? s : t",
+	"false ? a",
+	"true ? a",
+	"(false ? a) ?? b",
+	"true ? (false ? a : b) : s",
+	"nil ?? a",
+	"'x' ?? a",
+	"(true ? nil : 1) ?? a",
+	"false && (a / 0 > 1)",
+	"true ? s : (1 =~ 'a')",
+	"(false || a > 1) && (true && b < 3)",
+}
+
+/*
+Compiles [text] with a reference plan, and checks that its optimized plan evaluates as the reference does on [inputs].
+*/
+func assertMatchesReferencePlan(test *testing.T, text string, inputs []map[string]interface{}) {
+
+	test.Helper()
+
+	options := conformanceOptions
+	options.Failover = true
+
+	optimized, err := TNewEvaluableExpressionWithOptions(text, options)
+	if err != nil {
+		return
+	}
+
+	reference := *optimized
+	reference.evaluationStages = optimized.referenceStages
+	reference.referenceStages = nil
+
+	assertSameEvaluations(test, "once planned", &reference, optimized, inputs)
+}
+
+func TestDeadBranchesArePruned(test *testing.T) {
+
+	cases := []struct {
+		expression string
+		expected   tOperatorSymbol
+	}{
+		{"false && a > 1", tLITERAL},
+		{"true || a > 1", tLITERAL},
+		{"false ? a : b", tVALUE},
+		{"nil ?? a", tVALUE},
+		{"(2 > 1) ? a : b", tCOALESCE}, // an else still gives b when a is nil
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpressionWithOptions(c.expression, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		if symbol := unparenthesized(expression.evaluationStages).symbol; symbol != c.expected {
+			test.Errorf("%s: expected to be planned as %v, got %v", c.expression, c.expected, symbol)
+		}
+	}
+}
+
+func TestPrunedPlans(test *testing.T) {
+
+	cases := []struct {
+		expression string
+		expected   string
+	}{
+		{"false ? a : b", "b"},
+		{"nil ?? a", "a"},
+		{"'x' ?? a", "'x'"},
+		{"false && a > 1", "false"},
+		{"true || s == 'a'", "true"},
+		{"true ? (false ? a : b) : s", "(b) ?? s"},
+
+		// a condition which does not decide the result is kept, so that the other operand is still checked to be a bool.
+		{"true && a > 1", "true && a > 1"},
+		{"(1 > 2) || flag", "(false) || flag"},
+	}
+
+	for _, c := range cases {
+
+		expression, err := TNewEvaluableExpressionWithOptions(c.expression, conformanceOptions)
+		if err != nil {
+			test.Fatalf("%s: %v", c.expression, err)
+		}
+		if planned := expression.String(); planned != c.expected {
+			test.Errorf("%s: expected to be planned as %s, got %s", c.expression, c.expected, planned)
+		}
+	}
+}
+
+func TestPrunedPlansMatchReferencePlan(test *testing.T) {
+
+	inputs := nativeInputs(100)
+	for _, text := range prunedCorpus {
+		assertMatchesReferencePlan(test, text, inputs)
+	}
+}
+
+/*
+Generates expressions, then gives each a literal condition, so that every kind of branch is pruned from something.
+*/
+func TestGeneratedPrunedPlansMatchReferencePlan(test *testing.T) {
+
+	inputs := nativeInputs(20)
+	random := rand.New(rand.NewSource(1))
+	conditions := []string{"true", "false", "nil", "1", "(1 > 2)", "!true"}
+
+	for i := 0; i < 1000; i++ {
+
+		data := make([]byte, 32)
+		random.Read(data)
+		generated := generateExpression(data)
+
+		random.Read(data)
+		other := generateExpression(data)
+
+		condition := conditions[random.Intn(len(conditions))]
+		for _, text := range []string{
+			condition + " && " + generated,
+			condition + " || " + generated,
+			condition + " ? " + generated + " : " + other,
+			"(" + condition + " ? " + generated + ") ?? " + other,
+			condition + " ?? " + generated,
+		} {
+			assertMatchesReferencePlan(test, text, inputs)
+		}
+	}
+}
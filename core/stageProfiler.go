@@ -0,0 +1,311 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+TStageProfiler aggregates the time spent in each evaluation stage across any number of evaluations,
+of any number of expressions. Evaluate with `TProfile` to record samples into a profiler.
+A single profiler is safe to share between goroutines.
+*/
+type TStageProfiler struct {
+	lock    sync.Mutex
+	samples map[*evaluationStage]*stageSample
+	roots   map[*evaluationStage]string
+	order   []*evaluationStage
+}
+
+type stageSample struct {
+	hits  int64
+	total time.Duration
+}
+
+/*
+A single aggregated line of a profile report.
+[Self] excludes time spent in child stages, [Total] includes it.
+*/
+type TProfileEntry struct {
+	Label string
+	Hits  int64
+	Self  time.Duration
+	Total time.Duration
+}
+
+func TNewStageProfiler() *TStageProfiler {
+
+	return &TStageProfiler{
+		samples: make(map[*evaluationStage]*stageSample),
+		roots:   make(map[*evaluationStage]string),
+	}
+}
+
+/*
+Evaluates this expression, recording the time spent in every stage into the given [profiler].
+*/
+func (t tEvaluableExpression) TProfile(parameters map[string]interface{}, profiler *TStageProfiler) (interface{}, error) {
+
+	if profiler != nil && t.evaluationStages != nil {
+		profiler.register(t.evaluationStages, t.inputExpression)
+	}
+
+	t.profiler = profiler
+	return t.TEvaluate(parameters)
+}
+
+func (p *TStageProfiler) register(root *evaluationStage, expression string) {
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	_, found := p.roots[root]
+	if found {
+		return
+	}
+
+	p.roots[root] = expression
+	p.order = append(p.order, root)
+}
+
+func (p *TStageProfiler) record(stage *evaluationStage, start time.Time) {
+
+	elapsed := time.Since(start)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	sample, found := p.samples[stage]
+	if !found {
+		sample = new(stageSample)
+		p.samples[stage] = sample
+	}
+
+	sample.hits++
+	sample.total += elapsed
+}
+
+/*
+Clears all recorded samples.
+*/
+func (p *TStageProfiler) TReset() {
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.samples = make(map[*evaluationStage]*stageSample)
+	p.roots = make(map[*evaluationStage]string)
+	p.order = nil
+}
+
+/*
+Writes all samples in the "folded stack" format understood by flamegraph.pl, speedscope, and `go tool pprof -raw` converters.
+Each line is a semicolon-separated path from the expression down to a stage, followed by the self time of that stage in nanoseconds.
+*/
+func (p *TStageProfiler) TWriteFolded(writer io.Writer) error {
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, root := range p.order {
+
+		frames := []string{foldedFrame(p.roots[root])}
+
+		err := p.writeFoldedStage(writer, root, frames)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *TStageProfiler) writeFoldedStage(writer io.Writer, stage *evaluationStage, frames []string) error {
+
+	frames = append(frames, foldedFrame(stage.label()))
+
+	self := p.selfTime(stage)
+	if self > 0 {
+		_, err := fmt.Fprintf(writer, "%s %d\n", strings.Join(frames, ";"), self.Nanoseconds())
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, child := range []*evaluationStage{stage.leftStage, stage.rightStage} {
+
+		if child == nil {
+			continue
+		}
+
+		err := p.writeFoldedStage(writer, child, frames[:len(frames):len(frames)])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Returns aggregated samples, grouped by operator symbol, function name, or parameter name.
+Entries are sorted by descending self time.
+*/
+func (p *TStageProfiler) TReport() []TProfileEntry {
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var ret []TProfileEntry
+	indices := make(map[string]int)
+
+	for stage, sample := range p.samples {
+
+		label := stage.label()
+		if stage.symbol == tLITERAL {
+			label = "literal"
+		}
+
+		index, found := indices[label]
+		if !found {
+			index = len(ret)
+			indices[label] = index
+			ret = append(ret, TProfileEntry{Label: label})
+		}
+
+		ret[index].Hits += sample.hits
+		ret[index].Total += sample.total
+		ret[index].Self += p.selfTime(stage)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Self == ret[j].Self {
+			return ret[i].Label < ret[j].Label
+		}
+		return ret[i].Self > ret[j].Self
+	})
+	return ret
+}
+
+func (p *TStageProfiler) selfTime(stage *evaluationStage) time.Duration {
+
+	sample, found := p.samples[stage]
+	if !found {
+		return 0
+	}
+
+	self := sample.total
+	for _, child := range []*evaluationStage{stage.leftStage, stage.rightStage} {
+
+		if child == nil {
+			continue
+		}
+
+		childSample, found := p.samples[child]
+		if found {
+			self -= childSample.total
+		}
+	}
+
+	if self < 0 {
+		return 0
+	}
+	return self
+}
+
+// semicolons delimit frames and newlines delimit stacks, neither may appear inside a frame.
+func foldedFrame(label string) string {
+	return strings.NewReplacer(";", ":", "\n", " ").Replace(label)
+}
+
+/*
+The profile of a single expression, as returned by TProfileReport and TStageReports.
+*/
+type TStageReport struct {
+
+	// the expression, as it was given.
+	Expression string
+
+	// the number of times the expression was evaluated, and the time all those evaluations took.
+	Hits  int64
+	Total time.Duration
+
+	// every stage of the expression which was evaluated, in the order they appear in its tree,
+	// so that a stage's operands follow it, one Depth deeper.
+	Stages []TStageProfileEntry
+}
+
+/*
+A single stage of a TStageReport.
+[Self] excludes time spent in the stage's operands, [Total] includes it.
+*/
+type TStageProfileEntry struct {
+	Label      string
+	Expression string
+	Depth      int
+	Hits       int64
+	Self       time.Duration
+	Total      time.Duration
+}
+
+/*
+Evaluates this expression, returning its result along with a report of the time spent in, and hits of, each of its stages.
+*/
+func (t tEvaluableExpression) TProfileReport(parameters map[string]interface{}) (interface{}, TStageReport, error) {
+
+	profiler := TNewStageProfiler()
+
+	result, err := t.TProfile(parameters, profiler)
+	if t.evaluationStages == nil {
+		return result, TStageReport{Expression: t.inputExpression}, err
+	}
+	return result, profiler.TStageReports()[0], err
+}
+
+/*
+Returns the samples of each expression evaluated into this profiler, stage by stage, in the order the expressions were first evaluated.
+Unlike TReport, stages are not grouped, so that each sub-expression of a slow expression can be seen to take the time it does.
+*/
+func (p *TStageProfiler) TStageReports() []TStageReport {
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	ret := make([]TStageReport, len(p.order))
+	for i, root := range p.order {
+
+		ret[i].Expression = p.roots[root]
+		if sample, found := p.samples[root]; found {
+			ret[i].Hits = sample.hits
+			ret[i].Total = sample.total
+		}
+		ret[i].Stages = p.appendStageEntries(nil, root, 0)
+	}
+	return ret
+}
+
+func (p *TStageProfiler) appendStageEntries(entries []TStageProfileEntry, stage *evaluationStage, depth int) []TStageProfileEntry {
+
+	sample, found := p.samples[stage]
+	if !found {
+		return entries
+	}
+
+	entries = append(entries, TStageProfileEntry{
+		Label:      stage.label(),
+		Expression: renderStage(stage),
+		Depth:      depth,
+		Hits:       sample.hits,
+		Self:       p.selfTime(stage),
+		Total:      sample.total,
+	})
+
+	for _, child := range []*evaluationStage{stage.leftStage, stage.rightStage} {
+		if child != nil {
+			entries = p.appendStageEntries(entries, child, depth+1)
+		}
+	}
+	return entries
+}
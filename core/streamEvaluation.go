@@ -0,0 +1,115 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+/*
+TResultWriter receives each result of a streaming evaluation as soon as it is produced.
+[index] counts records from zero. Exactly one of [result] and [err] is meaningful.
+*/
+type TResultWriter interface {
+	TWrite(index int, result interface{}, err error) error
+	TFlush() error
+}
+
+/*
+Evaluates this expression once for every record returned by [next], until [next] returns false,
+handing each result straight to [writer] instead of collecting them, so that memory use stays flat
+no matter how many records are evaluated.
+
+Records which fail to evaluate are written as errors, and do not stop the stream.
+Returns the number of records evaluated, and stops early only if [writer] fails.
+*/
+func (t tEvaluableExpression) TEvaluateStream(next func() (map[string]interface{}, bool), writer TResultWriter) (int, error) {
+
+	var index int
+
+	for ; ; index++ {
+
+		parameters, more := next()
+		if !more {
+			break
+		}
+
+		result, err := t.TEvaluate(parameters)
+
+		err = writer.TWrite(index, result, err)
+		if err != nil {
+			return index, err
+		}
+	}
+
+	return index, writer.TFlush()
+}
+
+type jsonLinesWriter struct {
+	encoder *json.Encoder
+}
+
+type jsonLine struct {
+	Index  int         `json:"index"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+/*
+Returns a TResultWriter which writes one JSON object per line, such as `{"index":0,"result":true}`,
+or `{"index":1,"error":"..."}` for records which failed to evaluate.
+*/
+func TNewJSONLinesWriter(writer io.Writer) TResultWriter {
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	return jsonLinesWriter{encoder: encoder}
+}
+
+func (w jsonLinesWriter) TWrite(index int, result interface{}, err error) error {
+
+	line := jsonLine{Index: index, Result: result}
+	if err != nil {
+		line = jsonLine{Index: index, Error: err.Error()}
+	}
+	return w.encoder.Encode(line)
+}
+
+func (w jsonLinesWriter) TFlush() error {
+	return nil
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	row    []string
+}
+
+/*
+Returns a TResultWriter which writes one CSV row of `index,result,error` per record.
+*/
+func TNewCSVWriter(writer io.Writer) TResultWriter {
+	return &csvWriter{writer: csv.NewWriter(writer), row: make([]string, 3)}
+}
+
+func (w *csvWriter) TWrite(index int, result interface{}, err error) error {
+
+	w.row[0] = strconv.Itoa(index)
+	w.row[1] = ""
+	w.row[2] = ""
+
+	if err != nil {
+		w.row[2] = err.Error()
+	} else if result != nil {
+		w.row[1] = fmt.Sprintf("%v", result)
+	}
+
+	return w.writer.Write(w.row)
+}
+
+func (w *csvWriter) TFlush() error {
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+/*
+Recurses through all stages, marking those which accept strings to convert fmt.Stringer operands to strings first.
+*/
+func applyStringerCoercion(stage *evaluationStage) {
+
+	if stage == nil {
+		return
+	}
+
+	switch stage.symbol {
+	case tPLUS, tEQ, tNEQ, tGT, tLT, tGTE, tLTE, tREQ, tNREQ, tIN:
+		stage.stringers = true
+	}
+
+	applyStringerCoercion(stage.leftStage)
+	applyStringerCoercion(stage.rightStage)
+}
+
+/*
+Converts whichever of [left] and [right] are fmt.Stringers to strings, leaving the list on the right of `in` as it is.
+*/
+func coerceStringers(symbol tOperatorSymbol, left interface{}, right interface{}) (interface{}, interface{}) {
+
+	left = coerceStringer(left)
+	if symbol != tIN {
+		right = coerceStringer(right)
+	}
+	return left, right
+}
+
+func coerceStringer(value interface{}) interface{} {
+
+	switch value.(type) {
+	case nil, string, float64, bool, time.Time, *regexp.Regexp:
+		return value
+	}
+
+	if hasCustomEquality(value) {
+		return value
+	}
+
+	if stringer, ok := value.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return value
+}
@@ -4,6 +4,9 @@ type tokenStream struct {
 	tokens      []tExpressionToken
 	index       int
 	tokenLength int
+
+	// only used during stage planning.
+	options TExpressionOptions
 }
 
 func newTokenStream(tokens []tExpressionToken) *tokenStream {
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+TTokenizer lexes an expression as it is being typed, for live syntax feedback in editors.
+Input is given a piece at a time with TWrite; tokens which can no longer change are lexed once and kept,
+and only the unfinished end of the input is lexed again on each write.
+*/
+type TTokenizer struct {
+	functions map[string]tExpressionFunction
+	options   TExpressionOptions
+
+	source []rune
+
+	// tokens which are followed by more input, and so cannot change; and the lexer state and open parentheses after them.
+	committed []tExpressionToken
+	offset    int
+	state     lexerState
+	depth     int
+
+	// the tokens of the input after [offset], as if the input ended where it currently does.
+	tentative      []tExpressionToken
+	tentativeState lexerState
+	tentativeDepth int
+
+	// whether the input ends in something which is not (or not yet) a valid token.
+	unfinished bool
+}
+
+/*
+Creates a tokenizer which recognizes the given [functions], and lexes as an expression compiled with [options] would.
+Registered functions are recognized too.
+*/
+func TNewTokenizer(functions map[string]tExpressionFunction, options TExpressionOptions) *TTokenizer {
+
+	ret := &TTokenizer{
+		functions: mergeRegisteredFunctions(functions, options),
+		options:   options,
+	}
+	ret.TReset()
+	return ret
+}
+
+/*
+Discards all input.
+*/
+func (z *TTokenizer) TReset() {
+
+	z.source = nil
+	z.committed = nil
+	z.offset = 0
+	z.state = validLexerStates[0]
+	z.depth = 0
+	z.tentative = nil
+	z.tentativeState = z.state
+	z.tentativeDepth = 0
+	z.unfinished = false
+}
+
+/*
+Appends [text] to the input, and lexes it.
+Returns a TParseError if the input can no longer become a valid expression, however it is continued;
+problems which more input may still fix, such as an unclosed string, are not errors.
+*/
+func (z *TTokenizer) TWrite(text string) error {
+
+	z.source = append(z.source, []rune(text)...)
+
+	stream := &lexerStream{source: z.source, position: z.offset, length: len(z.source)}
+
+	z.tentative = nil
+	z.tentativeState = z.state
+	z.tentativeDepth = z.depth
+	z.unfinished = false
+
+	for stream.canRead() {
+
+		start := stream.position
+		token, err, found := readToken(stream, z.tentativeState, z.functions, z.options)
+
+		// a token running to the end of the input may yet be extended, such as `=` into `==`. Parentheses cannot be.
+		final := !stream.canRead() && token.Kind != tCLAUSE && token.Kind != tCLAUSE_CLOSE
+
+		var expanded []tExpressionToken
+		if err == nil && found {
+			expanded, err = expandToken(token, z.functions, z.options)
+		}
+		for i := 0; i < len(expanded) && err == nil; i++ {
+			err = z.advance(expanded[i])
+		}
+
+		if err != nil {
+			z.unfinished = true
+			if final {
+				return nil
+			}
+			return TParseError{Position: skipSpaces(z.source, start), Message: err.Error()}
+		}
+
+		if !found {
+			break
+		}
+
+		z.tentative = append(z.tentative, expanded...)
+
+		if !final {
+			z.committed = append(z.committed, z.tentative...)
+			z.tentative = nil
+			z.offset = stream.position
+			z.state = z.tentativeState
+			z.depth = z.tentativeDepth
+		}
+	}
+
+	return nil
+}
+
+/*
+Moves the tentative state past [token], or returns an error if [token] cannot come next.
+*/
+func (z *TTokenizer) advance(token tExpressionToken) error {
+
+	if !z.tentativeState.canTransitionTo(token.Kind) {
+		return fmt.Errorf("Unexpected %v [%v] after %v", token.Kind, token.Value, z.tentativeState.kind)
+	}
+
+	next, err := getLexerStateForToken(token.Kind)
+	if err != nil {
+		return err
+	}
+
+	depth := z.tentativeDepth
+	switch token.Kind {
+	case tCLAUSE:
+		depth++
+	case tCLAUSE_CLOSE:
+		depth--
+		if depth < 0 {
+			return errors.New("Unbalanced parenthesis")
+		}
+	}
+
+	z.tentativeState = next
+	z.tentativeDepth = depth
+	return nil
+}
+
+/*
+Returns every token of the input so far, treating its end as the end of the last token.
+*/
+func (z *TTokenizer) TTokens() []TExpressionToken {
+	return append(append([]tExpressionToken(nil), z.committed...), z.tentative...)
+}
+
+/*
+Returns the kinds of token which may come next, treating the end of the input as the end of the last token.
+Returns nil if the input ends part-way through a token which is not yet valid, such as an unclosed string,
+or if TWrite has reported an error.
+*/
+func (z *TTokenizer) TExpected() []TTokenKind {
+
+	if z.unfinished {
+		return nil
+	}
+
+	var ret []TTokenKind
+	for _, kind := range z.tentativeState.validNextKinds {
+
+		// a closing parenthesis is only allowed once one has been opened.
+		if kind == tCLAUSE_CLOSE && z.tentativeDepth == 0 {
+			continue
+		}
+		ret = append(ret, kind)
+	}
+	return ret
+}
+
+/*
+Returns true if the input so far is a syntactically complete expression.
+*/
+func (z *TTokenizer) TComplete() bool {
+	return !z.unfinished && z.tentativeState.isEOF && z.tentativeDepth == 0 && len(z.source) > 0
+}
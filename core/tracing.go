@@ -0,0 +1,73 @@
+package core
+
+import (
+	"time"
+)
+
+/*
+TTracer is told of every stage of an evaluation as it is entered and exited, as given by TExpressionOptions.Tracer,
+so that it can log exactly why a rule matched or failed.
+Stages are entered and exited in nesting order: the stages of a stage's operands are entered and exited
+after it is entered and before it is exited. Stages which are short-circuited are neither.
+*/
+type TTracer interface {
+
+	/*
+		Called as [event]'s stage is entered, before its operands are evaluated.
+		Only the Label and Expression of [event] are set.
+	*/
+	TEnter(event TTraceEvent)
+
+	/*
+		Called as [event]'s stage is exited, with its operands, and either its result or the error which failed it.
+	*/
+	TExit(event TTraceEvent)
+}
+
+/*
+TTraceEvent describes a stage of an evaluation to a TTracer.
+Operands and results are masked as SensitiveParameters are wherever values are printed.
+*/
+type TTraceEvent struct {
+
+	// the operator symbol, function name, parameter, or literal of the stage, as a TStageProfiler labels it.
+	Label string
+
+	// the part of the expression the stage evaluates.
+	Expression string
+
+	// the values of the stage's operands, either of which is nil if it has none, or it was short-circuited.
+	Left  interface{}
+	Right interface{}
+
+	Result   interface{}
+	Err      error
+	Duration time.Duration
+}
+
+/*
+Tells [tracer] that [stage] has been entered, and returns a function which tells it the stage has been exited.
+*/
+func (t tEvaluableExpression) traceStage(tracer TTracer, stage *evaluationStage) func(left, right, result interface{}, err error) {
+
+	start := time.Now()
+	event := TTraceEvent{
+		Label:      stage.label(),
+		Expression: renderStage(stage),
+	}
+	tracer.TEnter(event)
+
+	return func(left, right, result interface{}, err error) {
+
+		if right == shortCircuitHolder {
+			right = nil
+		}
+
+		event.Left = t.displayValue(stage.leftStage, left)
+		event.Right = t.displayValue(stage.rightStage, right)
+		event.Result = t.displayValue(stage, result)
+		event.Err = err
+		event.Duration = time.Since(start)
+		tracer.TExit(event)
+	}
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"regexp"
+	"time"
+)
+
+/*
+TValueType is the static type of a value, as far as it can be known before evaluation.
+*/
+type TValueType int
+
+const (
+	TAnyType TValueType = iota
+	TNumberType
+	TStringType
+	TBoolType
+	TTimeType
+	TArrayType
+	TNilType
+)
+
+func (t TValueType) String() string {
+
+	switch t {
+	case TNumberType:
+		return "number"
+	case TStringType:
+		return "string"
+	case TBoolType:
+		return "bool"
+	case TTimeType:
+		return "time"
+	case TArrayType:
+		return "array"
+	case TNilType:
+		return "nil"
+	}
+	return "any"
+}
+
+/*
+TSchema declares the types of parameters (and accessor paths, such as "user.Age").
+Parameters which are not declared may be of any type.
+*/
+type TSchema map[string]TValueType
+
+/*
+Returns the type this expression will evaluate to, given parameters of the types declared in [schema].
+Returns TAnyType if the type cannot be known without evaluating the expression.
+*/
+func (t tEvaluableExpression) TResultType(schema TSchema) TValueType {
+	return inferStageType(t.evaluationStages, schema)
+}
+
+func inferStageType(stage *evaluationStage, schema TSchema) TValueType {
+
+	if stage == nil {
+		return TNilType
+	}
+
+	switch stage.symbol {
+	case tLITERAL:
+		value, err := stage.operator(nil, nil, nil)
+		if err != nil {
+			return TAnyType
+		}
+		return typeOfValue(value)
+
+	case tVALUE:
+		fallthrough
+	case tACCESS:
+		return schema[stage.name]
+
+	case tNOOP:
+		return inferStageType(stage.rightStage, schema)
+
+	case tEQ, tNEQ, tGT, tLT, tGTE, tLTE, tREQ, tNREQ, tIN, tAND, tOR, tXOR, tINVERT:
+		return TBoolType
+
+	case tMINUS, tMULTIPLY, tDIVIDE, tFLOOR_DIVIDE, tMODULUS, tEXPONENT, tNEGATE,
+		tBITWISE_AND, tBITWISE_OR, tBITWISE_XOR, tBITWISE_LSHIFT, tBITWISE_RSHIFT, tBITWISE_NOT:
+		return TNumberType
+
+	case tPLUS:
+		left := inferStageType(stage.leftStage, schema)
+		right := inferStageType(stage.rightStage, schema)
+
+		if left == TArrayType && right == TArrayType {
+			return TArrayType
+		}
+		if left == TArrayType || right == TArrayType {
+			return TAnyType
+		}
+		if left == TStringType || right == TStringType {
+			return TStringType
+		}
+		if left == TNumberType && right == TNumberType {
+			return TNumberType
+		}
+		return TAnyType
+
+	case tSEPARATE:
+		return TArrayType
+
+	case tTERNARY_TRUE:
+		// evaluates to nil when the condition is false, which the enclosing ':' (if any) replaces.
+		return inferStageType(stage.rightStage, schema)
+
+	case tTERNARY_FALSE:
+		fallthrough
+	case tCOALESCE:
+		left := inferStageType(stage.leftStage, schema)
+		right := inferStageType(stage.rightStage, schema)
+
+		if left == right {
+			return left
+		}
+		return TAnyType
+	}
+
+	return TAnyType
+}
+
+func typeOfValue(value interface{}) TValueType {
+
+	switch value.(type) {
+	case float64:
+		return TNumberType
+	case string:
+		return TStringType
+	case *regexp.Regexp:
+		return TStringType
+	case bool:
+		return TBoolType
+	case time.Time:
+		return TTimeType
+	case []interface{}:
+		return TArrayType
+	case nil:
+		return TNilType
+	}
+	return TAnyType
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+/*
+Evaluates this expression, returning an error if the result is not a bool.
+*/
+func (t tEvaluableExpression) TEvaluateBool(parameters map[string]interface{}) (bool, error) {
+
+	result, err := t.TEvaluate(parameters)
+	if err != nil {
+		return false, err
+	}
+
+	ret, ok := result.(bool)
+	if !ok {
+		return false, resultTypeError(result, "bool")
+	}
+	return ret, nil
+}
+
+/*
+Evaluates this expression, returning an error if the result is not a number.
+*/
+func (t tEvaluableExpression) TEvaluateFloat64(parameters map[string]interface{}) (float64, error) {
+
+	result, err := t.TEvaluate(parameters)
+	if err != nil {
+		return 0, err
+	}
+
+	ret, ok := castToFloat64(result).(float64)
+	if !ok {
+		return 0, resultTypeError(result, "float64")
+	}
+	return ret, nil
+}
+
+/*
+Evaluates this expression, returning an error if the result is not a string.
+*/
+func (t tEvaluableExpression) TEvaluateString(parameters map[string]interface{}) (string, error) {
+
+	result, err := t.TEvaluate(parameters)
+	if err != nil {
+		return "", err
+	}
+
+	ret, ok := result.(string)
+	if !ok {
+		return "", resultTypeError(result, "string")
+	}
+	return ret, nil
+}
+
+/*
+Evaluates this expression, returning an error if the result cannot be interpreted as a time.
+Since time literals are planned as unix timestamps, numbers are read as seconds since the epoch;
+strings are parsed with the same formats as time literals.
+*/
+func (t tEvaluableExpression) TEvaluateTime(parameters map[string]interface{}) (time.Time, error) {
+
+	result, err := t.TEvaluate(parameters)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch value := castToFloat64(result).(type) {
+	case time.Time:
+		return value, nil
+	case float64:
+		seconds, fraction := math.Modf(value)
+		return time.Unix(int64(seconds), int64(fraction*float64(time.Second))), nil
+	case string:
+		ret, found := tryParseTime(value)
+		if found {
+			return ret, nil
+		}
+	}
+	return time.Time{}, resultTypeError(result, "time.Time")
+}
+
+func resultTypeError(result interface{}, expected string) error {
+	return fmt.Errorf("Expression result '%v' (%T) is not a %s", result, result, expected)
+}
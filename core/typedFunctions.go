@@ -0,0 +1,144 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+/*
+Wraps an ordinary Go function, such as `func(a float64, b string) (bool, error)`, as a tExpressionFunction.
+The wrapper checks the number of arguments given, converts each argument to the type the function declares
+(so numbers may be passed to any integer or float parameter), and converts integer results back to float64.
+[function] must return either one value, or a value and an error.
+[name] is only used in error messages.
+*/
+func TWrapFunction(name string, function interface{}) (tExpressionFunction, error) {
+
+	value := reflect.ValueOf(function)
+	if value.Kind() != reflect.Func {
+		return nil, fmt.Errorf("Function '%s' must be a func, not %T", name, function)
+	}
+
+	functionType := value.Type()
+
+	switch functionType.NumOut() {
+	case 1:
+	case 2:
+		if functionType.Out(1) != errorType {
+			return nil, fmt.Errorf("Function '%s' must return a value and an error, but its second return is %v", name, functionType.Out(1))
+		}
+	default:
+		return nil, fmt.Errorf("Function '%s' must return either one value, or a value and an error", name)
+	}
+
+	return func(arguments ...interface{}) (interface{}, error) {
+
+		params, err := convertArguments(name, functionType, arguments)
+		if err != nil {
+			return nil, err
+		}
+
+		returned := value.Call(params)
+
+		if len(returned) == 2 && !returned[1].IsNil() {
+			return nil, returned[1].Interface().(error)
+		}
+		return castToFloat64(returned[0].Interface()), nil
+	}, nil
+}
+
+/*
+Registers an ordinary Go function (see TWrapFunction) for every expression created after this call.
+*/
+func TRegisterTypedFunction(name string, function interface{}) error {
+
+	wrapped, err := TWrapFunction(name, function)
+	if err != nil {
+		return err
+	}
+
+	TRegisterFunction(name, wrapped)
+	return nil
+}
+
+func convertArguments(name string, functionType reflect.Type, arguments []interface{}) ([]reflect.Value, error) {
+
+	numIn := functionType.NumIn()
+	variadic := functionType.IsVariadic()
+
+	if variadic && len(arguments) < numIn-1 {
+		return nil, fmt.Errorf("Function '%s' expects at least %d arguments, got %d", name, numIn-1, len(arguments))
+	}
+	if !variadic && len(arguments) != numIn {
+		return nil, fmt.Errorf("Function '%s' expects %d arguments, got %d", name, numIn, len(arguments))
+	}
+
+	ret := make([]reflect.Value, len(arguments))
+
+	for i, argument := range arguments {
+
+		var target reflect.Type
+
+		if variadic && i >= numIn-1 {
+			target = functionType.In(numIn - 1).Elem()
+		} else {
+			target = functionType.In(i)
+		}
+
+		converted, err := convertArgument(argument, target)
+		if err != nil {
+			return nil, fmt.Errorf("Function '%s' argument %d: %v", name, i+1, err)
+		}
+		ret[i] = converted
+	}
+
+	return ret, nil
+}
+
+func convertArgument(argument interface{}, target reflect.Type) (reflect.Value, error) {
+
+	if argument == nil {
+		switch target.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			return reflect.Zero(target), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot use nil as %v", target)
+	}
+
+	value := reflect.ValueOf(argument)
+
+	if value.Type().AssignableTo(target) {
+		return value, nil
+	}
+
+	if isFloat64(argument) {
+
+		number := argument.(float64)
+
+		switch target.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+
+			if number != math.Trunc(number) {
+				return reflect.Value{}, fmt.Errorf("cannot use %v as %v, it is not a whole number", number, target)
+			}
+			if number < 0 && target.Kind() >= reflect.Uint {
+				return reflect.Value{}, fmt.Errorf("cannot use %v as %v, it is negative", number, target)
+			}
+			return value.Convert(target), nil
+
+		case reflect.Float32, reflect.Float64:
+			return value.Convert(target), nil
+		}
+	}
+
+	if value.Kind() == reflect.String && target.Kind() == reflect.String {
+		return value.Convert(target), nil
+	}
+
+	return reflect.Value{}, errors.New(fmt.Sprintf("cannot use '%v' (%T) as %v", argument, argument, target))
+}
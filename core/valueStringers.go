@@ -0,0 +1,45 @@
+package core
+
+import (
+	"reflect"
+	"sync"
+)
+
+var globalStringersLock sync.RWMutex
+var globalStringers = make(map[reflect.Type]func(value interface{}) string)
+
+/*
+Registers [stringer] to render values of [valueType] wherever the engine prints a value, such as in type errors,
+in place of Go's default formatting - which for structs like decimals or money is rarely meaningful.
+Values of sensitive parameters are still masked. Registering a nil stringer removes any stringer for that type.
+*/
+func TRegisterStringer(valueType reflect.Type, stringer func(value interface{}) string) {
+
+	globalStringersLock.Lock()
+	defer globalStringersLock.Unlock()
+
+	if stringer == nil {
+		delete(globalStringers, valueType)
+		return
+	}
+	globalStringers[valueType] = stringer
+}
+
+/*
+Returns the registered rendering of [value], if a stringer is registered for its type.
+*/
+func stringifyValue(value interface{}) (string, bool) {
+
+	if value == nil {
+		return "", false
+	}
+
+	globalStringersLock.RLock()
+	stringer, found := globalStringers[reflect.TypeOf(value)]
+	globalStringersLock.RUnlock()
+
+	if !found {
+		return "", false
+	}
+	return stringer(value), true
+}
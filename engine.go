@@ -0,0 +1,164 @@
+package geval
+
+import (
+	"sync"
+	"time"
+
+	"github.com/myfstd/geval/core"
+)
+
+// Engine bundles the functions, options, and default parameter values shared by a set of expressions,
+// and caches the most recently used compiled expressions.
+// An Engine is safe for concurrent use.
+type Engine struct {
+	mutex     sync.RWMutex
+	functions map[string]core.TExpressionFunction
+	defaults  map[string]interface{}
+	options   core.TExpressionOptions
+	cache     *expressionCache
+	stats     Stats
+}
+
+// NewEngine returns an Engine with no functions, no defaults, and default options.
+func NewEngine() *Engine {
+	return &Engine{
+		functions: make(map[string]core.TExpressionFunction),
+		defaults:  make(map[string]interface{}),
+		cache:     newExpressionCache(DefaultCacheSize),
+	}
+}
+
+// RegisterFunction makes [function] callable as [name] from every expression compiled by this engine.
+func (e *Engine) RegisterFunction(name string, function func(arguments ...interface{}) (interface{}, error)) *Engine {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.functions[name] = function
+	e.cache.clear()
+	return e
+}
+
+// SetDefault gives the parameter [name] a value to use whenever it is not supplied to Evaluate.
+func (e *Engine) SetDefault(name string, value interface{}) *Engine {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	// defaults are read without holding the lock during evaluation, so they are replaced rather than modified.
+	defaults := make(map[string]interface{}, len(e.defaults)+1)
+	for key, existing := range e.defaults {
+		defaults[key] = existing
+	}
+	defaults[name] = value
+
+	e.defaults = defaults
+	return e
+}
+
+// SetOptions changes the options used to compile expressions.
+func (e *Engine) SetOptions(options core.TExpressionOptions) *Engine {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.options = options
+	e.cache.clear()
+	return e
+}
+
+// SetCacheSize changes how many compiled expressions are kept. A size of zero or less disables caching.
+func (e *Engine) SetCacheSize(size int) *Engine {
+	e.cache.resize(size)
+	return e
+}
+
+// SetStats makes this engine report its evaluations and cache hits to [stats]. A nil Stats reports nothing.
+func (e *Engine) SetStats(stats Stats) *Engine {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.stats = stats
+	return e
+}
+
+// Compile parses and plans the expression, or returns a cached copy if it was compiled recently.
+// Cached copies are not returned once a function has been registered globally since they were compiled,
+// as by RegisterFunction, since they may call the function it replaced.
+func (e *Engine) Compile(expression string) (*core.TEvaluableExpression, error) {
+	// read before compiling, so that a function registered while compiling leaves the compilation stale.
+	generation := core.TRegisteredFunctionsGeneration()
+	compiled, found := e.cache.get(expression, generation)
+
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.stats != nil {
+		if found {
+			e.stats.RecordCacheHit()
+		} else {
+			e.stats.RecordCacheMiss()
+		}
+	}
+
+	if found {
+		return compiled, nil
+	}
+
+	compiled, err := core.TNewEvaluableExpressionWithFunctionsAndOptions(expression, e.functions, e.options)
+	if err != nil {
+		return nil, err
+	}
+
+	e.cache.put(expression, compiled, generation)
+	return compiled, nil
+}
+
+// Validate reports whether the expression can be compiled, without evaluating it.
+func (e *Engine) Validate(expression string) error {
+	_, err := e.Compile(expression)
+	return err
+}
+
+// Format compiles the expression and returns it in canonical form, with normalized spacing, quoting, and parentheses.
+func (e *Engine) Format(expression string) (string, error) {
+	compiled, err := e.Compile(expression)
+	if err != nil {
+		return "", err
+	}
+	return compiled.TFormat(), nil
+}
+
+// CompileProto compiles an expression encoded as protobuf by its TMarshalProto method, with this engine's functions and options.
+func (e *Engine) CompileProto(data []byte) (*core.TEvaluableExpression, error) {
+	expression, err := core.TFromProto(data)
+	if err != nil {
+		return nil, err
+	}
+	return e.Compile(expression)
+}
+
+// Evaluate compiles the expression and evaluates it against [parameters], falling back to the engine's defaults.
+func (e *Engine) Evaluate(expression string, parameters map[string]interface{}) (result interface{}, err error) {
+	e.mutex.RLock()
+	stats := e.stats
+	e.mutex.RUnlock()
+
+	if stats != nil {
+		start := time.Now()
+		defer func() {
+			stats.RecordEvaluation(time.Since(start), err)
+		}()
+	}
+
+	compiled, err := e.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mutex.RLock()
+	defaults := e.defaults
+	e.mutex.RUnlock()
+
+	return compiled.TEvaluateParameters(core.TNewChainedParameters(
+		core.TNewMapParameters(parameters),
+		core.TNewMapParameters(defaults),
+	))
+}
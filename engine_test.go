@@ -0,0 +1,118 @@
+package geval
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type countingStats struct {
+	mutex                             sync.Mutex
+	evaluations, cacheHits, cacheMiss int
+}
+
+func (s *countingStats) RecordEvaluation(duration time.Duration, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.evaluations++
+}
+
+func (s *countingStats) RecordCacheHit() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cacheHits++
+}
+
+func (s *countingStats) RecordCacheMiss() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cacheMiss++
+}
+
+func TestEngineCachesCompiledExpressions(t *testing.T) {
+	stats := new(countingStats)
+	engine := NewEngine().SetStats(stats)
+
+	for i := 0; i < 3; i++ {
+		result, err := engine.Evaluate("a + 1", map[string]interface{}{"a": i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != float64(i+1) {
+			t.Errorf("expected %d, got %v", i+1, result)
+		}
+	}
+
+	if stats.evaluations != 3 || stats.cacheMiss != 1 || stats.cacheHits != 2 {
+		t.Errorf("expected 3 evaluations, 1 miss, and 2 hits, got %+v", stats)
+	}
+}
+
+func TestEngineCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	stats := new(countingStats)
+	engine := NewEngine().SetStats(stats).SetCacheSize(2)
+
+	for _, expression := range []string{"1", "2", "1", "3", "1", "2"} {
+		_, err := engine.Compile(expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// "2" was evicted by "3", having been used less recently than "1".
+	if stats.cacheMiss != 4 || stats.cacheHits != 2 {
+		t.Errorf("expected 4 misses and 2 hits, got %+v", stats)
+	}
+}
+
+func TestEngineDefaultsAndFunctions(t *testing.T) {
+	engine := NewEngine().
+		SetDefault("limit", 10).
+		RegisterFunction("double", func(arguments ...interface{}) (interface{}, error) {
+			return arguments[0].(float64) * 2, nil
+		})
+
+	result, err := engine.Evaluate("double(a) > limit", map[string]interface{}{"a": 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != true {
+		t.Errorf("expected true, got %v", result)
+	}
+
+	result, err = engine.Evaluate("double(a) > limit", map[string]interface{}{"a": 6, "limit": 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != false {
+		t.Errorf("expected given parameters to override defaults, got %v", result)
+	}
+}
+
+func TestGlobalRegistrationInvalidatesEveryEngine(t *testing.T) {
+	version := func(value float64) func(arguments ...interface{}) (interface{}, error) {
+		return func(arguments ...interface{}) (interface{}, error) {
+			return value, nil
+		}
+	}
+
+	RegisterFunction("engineTestVersion", version(1))
+	defer RegisterFunction("engineTestVersion", nil)
+
+	engines := []*Engine{NewEngine(), NewEngine(), defaultEngine}
+	for _, engine := range engines {
+		result, err := engine.Evaluate("engineTestVersion()", nil)
+		if err != nil || result != 1.0 {
+			t.Fatalf("expected 1, got %v (%v)", result, err)
+		}
+	}
+
+	RegisterFunction("engineTestVersion", version(2))
+
+	for _, engine := range engines {
+		result, err := engine.Evaluate("engineTestVersion()", nil)
+		if err != nil || result != 2.0 {
+			t.Errorf("expected every engine to call the function registered since, got %v (%v)", result, err)
+		}
+	}
+}
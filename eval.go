@@ -1,15 +1,117 @@
 package geval
 
-import "github.com/myfstd/geval/core"
+import (
+	"fmt"
+	"reflect"
 
+	"github.com/myfstd/geval/core"
+)
+
+// defaultEngine compiles and caches expressions for the package-level functions.
+var defaultEngine = NewEngine()
+
+// Eval evaluates the expression, returning false if it cannot be parsed or evaluated.
+// Since false is also a legitimate result, prefer EvalE when failures need to be told apart.
 func Eval(expression string) interface{} {
-	evalExpression, err := core.TNewEvaluableExpression(expression)
-	if err != nil {
+	evaluate, err := EvalE(expression)
+	if err != nil || evaluate == nil {
 		return false
 	}
-	evaluate, err := evalExpression.TEvaluate(nil)
-	if evaluate == nil {
-		return false
+	return evaluate
+}
+
+// EvalE evaluates the expression, returning any error encountered while parsing or evaluating it.
+// Compiled expressions are cached, so repeatedly evaluating the same expression only parses it once.
+func EvalE(expression string) (interface{}, error) {
+	return EvalWithParameters(expression, nil)
+}
+
+// EvalWithParameters is like EvalE, but evaluates the expression against [parameters].
+func EvalWithParameters(expression string, parameters map[string]interface{}) (interface{}, error) {
+	return defaultEngine.Evaluate(expression, parameters)
+}
+
+// Format returns the expression in canonical form, as gofmt does Go source, so that stored rules can be kept consistent.
+// Expressions which are formatted the same are equivalent, whichever spacing, quotes, or redundant parentheses they were written with.
+func Format(expression string) (string, error) {
+	return defaultEngine.Format(expression)
+}
+
+// FromSQL translates the predicate of a SQL WHERE clause, such as `age >= 18 AND name LIKE 'a%'`,
+// into an equivalent expression, so that filters stored as SQL can be moved to expressions.
+func FromSQL(where string) (string, error) {
+	return core.TFromSQL(where)
+}
+
+// FromCEL translates an expression in a subset of the Common Expression Language, such as `user.Age >= 18`,
+// into an equivalent expression. Compiled expressions can be written as CEL with their TToCEL method.
+func FromCEL(cel string) (string, error) {
+	return core.TFromCEL(cel)
+}
+
+// FromProto decodes an expression encoded as protobuf by its TMarshalProto method (see core/expression.proto)
+// back into expression text, so that rules stored or exchanged as protobuf can be compiled again.
+func FromProto(data []byte) (string, error) {
+	return core.TFromProto(data)
+}
+
+// SetCacheSize changes how many compiled expressions the package-level functions keep.
+// A size of zero or less disables caching.
+func SetCacheSize(size int) {
+	defaultEngine.SetCacheSize(size)
+}
+
+// MustEval is like EvalE, but panics if the expression cannot be parsed or evaluated.
+func MustEval(expression string) interface{} {
+	evaluate, err := EvalE(expression)
+	if err != nil {
+		panic(fmt.Sprintf("geval: Eval(%q): %v", expression, err))
+	}
+	return evaluate
+}
+
+// EvalOr is like EvalE, but returns [fallback] if the expression cannot be parsed or evaluated.
+func EvalOr(expression string, fallback interface{}) interface{} {
+	evaluate, err := EvalE(expression)
+	if err != nil {
+		return fallback
 	}
 	return evaluate
 }
+
+// RegisterFunction makes [function] callable as [name] from every expression compiled after this call,
+// including those compiled by any Engine, unless the Engine registers its own function of that name.
+// Engines compile again any expression they had cached, so that none calls a function this replaces.
+func RegisterFunction(name string, function func(arguments ...interface{}) (interface{}, error)) {
+	core.TRegisterFunction(name, function)
+}
+
+// RegisterTypedFunction is like RegisterFunction, but accepts an ordinary Go function such as
+// func(a float64, b string) (bool, error), converting arguments to the types it declares.
+func RegisterTypedFunction(name string, function interface{}) error {
+	return core.TRegisterTypedFunction(name, function)
+}
+
+// RegisterMathFunctions makes sqrt, log, log2, exp, sin, cos, pow, clamp, and sign callable under [namespace],
+// such as math.sqrt(x), from every expression compiled after this call.
+func RegisterMathFunctions(namespace string) {
+	core.TRegisterMathFunctions(namespace)
+}
+
+// RegisterEncodingFunctions makes md5, sha1, sha256, crc32, base64encode, base64decode, and urlencode callable
+// under [namespace], such as hash.sha256(s), from every expression compiled after this call.
+func RegisterEncodingFunctions(namespace string) {
+	core.TRegisterEncodingFunctions(namespace)
+}
+
+// RegisterNetworkFunctions makes cidrContains, isPrivateIP, and ipInRange callable under [namespace],
+// such as net.cidrContains('10.0.0.0/8', ip), from every expression compiled after this call.
+func RegisterNetworkFunctions(namespace string) {
+	core.TRegisterNetworkFunctions(namespace)
+}
+
+// RegisterStringer makes values of [valueType] print as [stringer] renders them, rather than with Go's default
+// formatting, wherever expressions print values - such as in type errors.
+func RegisterStringer(valueType reflect.Type, stringer func(value interface{}) string) {
+	core.TRegisterStringer(valueType, stringer)
+}
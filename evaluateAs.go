@@ -0,0 +1,71 @@
+package geval
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// EvaluateAs compiles the expression (reusing a cached copy if it was compiled before), evaluates it against [parameters],
+// and converts the result to T. Numeric results may be converted to any numeric T which holds them exactly,
+// or to any float type; a result such as 2.5, which an int cannot hold, is an error rather than truncated.
+func EvaluateAs[T any](expression string, parameters map[string]interface{}) (T, error) {
+	var zero T
+
+	result, err := defaultEngine.Evaluate(expression, parameters)
+	if err != nil {
+		return zero, err
+	}
+
+	return convertResult[T](result)
+}
+
+func convertResult[T any](result interface{}) (T, error) {
+	var zero T
+
+	ret, ok := result.(T)
+	if ok {
+		return ret, nil
+	}
+
+	target := reflect.TypeOf(&zero).Elem()
+	value := reflect.ValueOf(result)
+
+	if result != nil && isNumericKind(value.Kind()) && isNumericKind(target.Kind()) {
+
+		converted := value.Convert(target)
+		if !isLossless(value, converted) {
+			return zero, fmt.Errorf("geval: result '%v' (%T) cannot be converted to %v without loss", result, result, target)
+		}
+		return converted.Interface().(T), nil
+	}
+
+	return zero, fmt.Errorf("geval: result '%v' (%T) cannot be converted to %v", result, result, target)
+}
+
+// isLossless returns whether [converted] is the number [value] is: whether it converts back to the same value,
+// such that 2.5 is not converted to an int, nor -1 to a uint, nor a number to a type too small to hold it.
+// Conversions to floats need only keep the number finite, since most numbers are rounded to be floats at all.
+func isLossless(value reflect.Value, converted reflect.Value) bool {
+
+	switch converted.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if value.Kind() == reflect.Float32 || value.Kind() == reflect.Float64 {
+			return !math.IsInf(converted.Float(), 0) || math.IsInf(value.Float(), 0)
+		}
+		return true
+	}
+
+	// NaN converts to nothing but a float, and is not equal even to itself.
+	return converted.Convert(value.Type()).Interface() == value.Interface()
+}
+
+func isNumericKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package geval
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvertResult(t *testing.T) {
+
+	if ret, err := convertResult[int](3.0); err != nil || ret != 3 {
+		t.Errorf("expected 3.0 to convert to 3, got %v (%v)", ret, err)
+	}
+	if ret, err := convertResult[uint8](255.0); err != nil || ret != 255 {
+		t.Errorf("expected 255.0 to convert to 255, got %v (%v)", ret, err)
+	}
+	if ret, err := convertResult[float32](0.1); err != nil || ret != float32(0.1) {
+		t.Errorf("expected 0.1 to be rounded to a float32, got %v (%v)", ret, err)
+	}
+	if ret, err := convertResult[float32](math.NaN()); err != nil || !math.IsNaN(float64(ret)) {
+		t.Errorf("expected NaN to convert to a float32, got %v (%v)", ret, err)
+	}
+	if ret, err := convertResult[float64](int64(7)); err != nil || ret != 7 {
+		t.Errorf("expected an int64 to convert to a float64, got %v (%v)", ret, err)
+	}
+}
+
+func TestConvertResultRejectsLoss(t *testing.T) {
+
+	lossy := []struct {
+		description string
+		convert     func() error
+	}{
+		{"2.5 to int", func() error { _, err := convertResult[int](2.5); return err }},
+		{"-1 to uint", func() error { _, err := convertResult[uint](-1.0); return err }},
+		{"NaN to int", func() error { _, err := convertResult[int](math.NaN()); return err }},
+		{"+Inf to int64", func() error { _, err := convertResult[int64](math.Inf(1)); return err }},
+		{"1e20 to int64", func() error { _, err := convertResult[int64](1e20); return err }},
+		{"256 to uint8", func() error { _, err := convertResult[uint8](256.0); return err }},
+		{"1e300 to float32", func() error { _, err := convertResult[float32](1e300); return err }},
+		{"'a' to int", func() error { _, err := convertResult[int]("a"); return err }},
+	}
+
+	for _, c := range lossy {
+		if c.convert() == nil {
+			t.Errorf("expected converting %s to fail", c.description)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package geval
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/myfstd/geval/core"
+)
+
+// SelfTestReport is the outcome of SelfTest.
+type SelfTestReport struct {
+	Passed   int
+	Failures []SelfTestFailure
+}
+
+// OK reports whether every check passed.
+func (r SelfTestReport) OK() bool {
+	return len(r.Failures) == 0
+}
+
+func (r SelfTestReport) String() string {
+	if r.OK() {
+		return fmt.Sprintf("geval: self-test passed %d checks", r.Passed)
+	}
+
+	lines := make([]string, len(r.Failures))
+	for i, failure := range r.Failures {
+		lines[i] = failure.String()
+	}
+	return fmt.Sprintf("geval: self-test failed %d of %d checks:\n%s",
+		len(r.Failures), len(r.Failures)+r.Passed, strings.Join(lines, "\n"))
+}
+
+// SelfTestFailure describes a single failed check of SelfTest.
+type SelfTestFailure struct {
+	Expression string
+	Expected   interface{}
+	Actual     interface{}
+	Err        error
+}
+
+func (f SelfTestFailure) String() string {
+	if f.Err != nil {
+		return fmt.Sprintf("'%s': expected %v, got error: %v", f.Expression, f.Expected, f.Err)
+	}
+	return fmt.Sprintf("'%s': expected %v (%T), got %v (%T)", f.Expression, f.Expected, f.Expected, f.Actual, f.Actual)
+}
+
+type selfTestCase struct {
+	expression string
+	expected   interface{}
+}
+
+// selfTestParameters are given to every self-test case.
+var selfTestParameters = map[string]interface{}{
+	"n":     10,
+	"s":     "abc",
+	"yes":   true,
+	"no":    false,
+	"empty": nil,
+}
+
+var selfTestCases = []selfTestCase{
+	// operators
+	{"1 + 2", 3.0},
+	{"7 - 10", -3.0},
+	{"6 * 7", 42.0},
+	{"7 / 2", 3.5},
+	{"7 % 4", 3.0},
+	{"-7 // 2", -4.0},
+	{"2 ** 10", 1024.0},
+	{"-n", -10.0},
+	{"!yes", false},
+	{"~0", -1.0},
+	{"6 & 3", 2.0},
+	{"6 | 3", 7.0},
+	{"6 ^ 3", 5.0},
+	{"1 << 4", 16.0},
+	{"256 >> 4", 16.0},
+	{"s + 'def'", "abcdef"},
+	{"'a\\tb\\u00e9'", "a\tb\u00e9"},
+	{"n == 10", true},
+	{"n != 10", false},
+	{"n > 9 && n >= 10 && n < 11 && n <= 10", true},
+	{"s =~ '^a.c$'", true},
+	{"s !~ 'z'", true},
+	{"'b' in ('a', 'b')", true},
+	{"yes ? 'y' : 'n'", "y"},
+	{"empty ?? 'fallback'", "fallback"},
+
+	// precedence and associativity
+	{"1 + 2 * 3", 7.0},
+	{"(1 + 2) * 3", 9.0},
+	{"10 - 4 - 3", 3.0},
+	{"2 * 3 ** 2", 18.0},
+	{"no || yes && no", false},
+	{"1 + 1 == 2 && 'a' + 'b' == 'ab'", true},
+	{"no ? 1 : yes ? 2 : 3", 2.0},
+
+	// short-circuiting, which never calls the failing function
+	{"no && fail()", false},
+	{"yes || fail()", true},
+	{"n ?? fail()", 10.0},
+	{"'set' ?? fail()", "set"},
+	{"no ? fail() : 'skipped'", "skipped"},
+
+	// parameters of other numeric types
+	{"n + 0.5", 10.5},
+}
+
+// selfTestFolding lists expressions which are entirely literals, and so must be folded into a single literal when planned.
+var selfTestFolding = []selfTestCase{
+	{"1 + 2 * 3", "7"},
+	{"'a' + 'b'", "'ab'"},
+	{"2 > 1 && 3 > 2", "true"},
+}
+
+// SelfTest runs a compact suite of checks of operators, precedence, short-circuiting, and literal folding,
+// so that embedders can detect a miscompiled or modified build at startup, before serving traffic.
+// It uses its own Engine, so it is unaffected by the functions and options of other engines. Functions registered
+// globally, with RegisterFunction, still apply to it as to every expression: one which overrides a built-in,
+// or shares the name of a parameter the checks use, may fail checks - as it would change expressions in use.
+func SelfTest() SelfTestReport {
+	var report SelfTestReport
+
+	engine := NewEngine().
+		SetOptions(core.TExpressionOptions{Features: map[string]bool{core.TFeatureTruthiness: false}}).
+		RegisterFunction("fail", func(arguments ...interface{}) (interface{}, error) {
+			return nil, errors.New("short-circuited function was called")
+		})
+
+	for _, check := range selfTestCases {
+		result, err := engine.Evaluate(check.expression, selfTestParameters)
+		if err != nil || !reflect.DeepEqual(result, check.expected) {
+			report.Failures = append(report.Failures, SelfTestFailure{check.expression, check.expected, result, err})
+			continue
+		}
+		report.Passed++
+	}
+
+	for _, check := range selfTestFolding {
+		compiled, err := engine.Compile(check.expression)
+		if err != nil || compiled.String() != check.expected {
+			var planned interface{}
+			if compiled != nil {
+				planned = compiled.String()
+			}
+			report.Failures = append(report.Failures, SelfTestFailure{check.expression, check.expected, planned, err})
+			continue
+		}
+		report.Passed++
+	}
+
+	return report
+}
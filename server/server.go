@@ -0,0 +1,228 @@
+// Package server serves an Engine over HTTP, so that services not written in Go can compile, validate, and evaluate
+// expressions with the same semantics. Each endpoint accepts a JSON object by POST, and responds with one:
+//
+//	POST /compile   {"expression": "score > limit"}
+//	                -> {"expression": "score > limit", "vars": ["score", "limit"]}
+//	POST /validate  {"expression": "score >"}
+//	                -> {"valid": false, "error": "Unexpected end of expression"}
+//	POST /evaluate  {"expression": "score > limit", "parameters": {"score": 12, "limit": 10}}
+//	                -> {"result": true}
+//
+// Expressions which cannot be compiled are rejected with 400 Bad Request, and those which fail to evaluate with
+// 422 Unprocessable Entity, each with an "error" message. Clients which make too many requests are told so with
+// 429 Too Many Requests. Compiled expressions are cached by the engine.
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/myfstd/geval"
+)
+
+// DefaultMaxBodyBytes is the largest request body a Handler accepts unless told otherwise.
+const DefaultMaxBodyBytes = 1 << 20
+
+// Options configures a Handler. The zero value accepts any number of requests.
+type Options struct {
+	// RatePerSecond is how many requests each client, identified by its IP address, may make per second on average.
+	// Zero or less means no limit.
+	RatePerSecond float64
+
+	// Burst is how many requests a client may make at once, before being held to RatePerSecond. At least 1.
+	Burst int
+
+	// MaxBodyBytes is the largest request body accepted. Zero means DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+}
+
+type request struct {
+	Expression string                 `json:"expression"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// Handler serves the compile, validate, and evaluate endpoints for an Engine.
+type Handler struct {
+	engine  *geval.Engine
+	options Options
+	limiter *rateLimiter
+	mux     *http.ServeMux
+}
+
+// NewHandler returns a Handler which compiles and evaluates expressions with [engine].
+func NewHandler(engine *geval.Engine, options Options) *Handler {
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	ret := &Handler{
+		engine:  engine,
+		options: options,
+		mux:     http.NewServeMux(),
+	}
+	if options.RatePerSecond > 0 {
+		ret.limiter = newRateLimiter(options.RatePerSecond, options.Burst)
+	}
+
+	ret.mux.HandleFunc("/compile", ret.post(ret.compile))
+	ret.mux.HandleFunc("/validate", ret.post(ret.validate))
+	ret.mux.HandleFunc("/evaluate", ret.post(ret.evaluate))
+	return ret
+}
+
+func (h *Handler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if h.limiter != nil && !h.limiter.allow(clientAddress(request), time.Now()) {
+		respond(writer, http.StatusTooManyRequests, map[string]interface{}{"error": "Too many requests"})
+		return
+	}
+	h.mux.ServeHTTP(writer, request)
+}
+
+// post adapts an endpoint to http, accepting only POSTs of a JSON request.
+func (h *Handler) post(endpoint func(request) (int, map[string]interface{})) http.HandlerFunc {
+	return func(writer http.ResponseWriter, httpRequest *http.Request) {
+		if httpRequest.Method != http.MethodPost {
+			writer.Header().Set("Allow", http.MethodPost)
+			respond(writer, http.StatusMethodNotAllowed, map[string]interface{}{"error": "Only POST is allowed"})
+			return
+		}
+
+		var body request
+		decoder := json.NewDecoder(http.MaxBytesReader(writer, httpRequest.Body, h.options.MaxBodyBytes))
+		err := decoder.Decode(&body)
+		if err != nil {
+			respond(writer, http.StatusBadRequest, map[string]interface{}{"error": "Invalid request: " + err.Error()})
+			return
+		}
+
+		status, response := endpoint(body)
+		respond(writer, status, response)
+	}
+}
+
+func (h *Handler) compile(body request) (int, map[string]interface{}) {
+	compiled, err := h.engine.Compile(body.Expression)
+	if err != nil {
+		return http.StatusBadRequest, map[string]interface{}{"error": err.Error()}
+	}
+
+	vars := compiled.TVars()
+	if vars == nil {
+		vars = []string{}
+	}
+	return http.StatusOK, map[string]interface{}{"expression": compiled.TFormat(), "vars": vars}
+}
+
+func (h *Handler) validate(body request) (int, map[string]interface{}) {
+	err := h.engine.Validate(body.Expression)
+	if err != nil {
+		return http.StatusOK, map[string]interface{}{"valid": false, "error": err.Error()}
+	}
+	return http.StatusOK, map[string]interface{}{"valid": true}
+}
+
+func (h *Handler) evaluate(body request) (int, map[string]interface{}) {
+	_, err := h.engine.Compile(body.Expression)
+	if err != nil {
+		return http.StatusBadRequest, map[string]interface{}{"error": err.Error()}
+	}
+
+	result, err := h.engine.Evaluate(body.Expression, body.Parameters)
+	if err != nil {
+		return http.StatusUnprocessableEntity, map[string]interface{}{"error": err.Error()}
+	}
+	return http.StatusOK, map[string]interface{}{"result": result}
+}
+
+// respond writes [body] as JSON, or an error if it cannot be, such as a result of NaN.
+func respond(writer http.ResponseWriter, status int, body map[string]interface{}) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+
+	err := encoder.Encode(body)
+	if err != nil {
+		status = http.StatusUnprocessableEntity
+		buffer.Reset()
+		encoder.Encode(map[string]interface{}{"error": "Unable to encode result as JSON: " + err.Error()})
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	writer.Write(buffer.Bytes())
+}
+
+func clientAddress(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+// rateLimiter keeps a token bucket for each client.
+type rateLimiter struct {
+	mutex   sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// the number of clients tracked before those whose buckets have refilled are forgotten.
+const maxTrackedClients = 10000
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token from [client]'s bucket, returning false if it has none left.
+func (l *rateLimiter) allow(client string, now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	current, found := l.buckets[client]
+	if !found {
+		if len(l.buckets) >= maxTrackedClients {
+			l.forgetFull(now)
+		}
+		current = &bucket{tokens: l.burst, updated: now}
+		l.buckets[client] = current
+	}
+
+	current.tokens += now.Sub(current.updated).Seconds() * l.rate
+	if current.tokens > l.burst {
+		current.tokens = l.burst
+	}
+	current.updated = now
+
+	if current.tokens < 1 {
+		return false
+	}
+	current.tokens--
+	return true
+}
+
+// forgetFull forgets clients whose buckets have refilled, since they are no different from new clients.
+func (l *rateLimiter) forgetFull(now time.Time) {
+	for client, current := range l.buckets {
+		if current.tokens+now.Sub(current.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
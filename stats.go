@@ -0,0 +1,25 @@
+package geval
+
+import (
+	"time"
+)
+
+// Stats is told how an Engine is used, so that it can be backed by Prometheus, OpenTelemetry, or any other metrics library.
+// Its methods are called on the goroutine doing the work, so they must be safe for concurrent use, and should be quick.
+type Stats interface {
+
+	// RecordEvaluation is called once per call to Evaluate, with how long the call took, including compiling
+	// the expression if it was not cached, and the error it returned, if any.
+	RecordEvaluation(duration time.Duration, err error)
+
+	// RecordCacheHit is called whenever an expression is found already compiled in the cache.
+	RecordCacheHit()
+
+	// RecordCacheMiss is called whenever an expression is not found in the cache, and so is compiled.
+	RecordCacheMiss()
+}
+
+// SetStats makes the package-level functions report to [stats]. A nil Stats reports nothing.
+func SetStats(stats Stats) {
+	defaultEngine.SetStats(stats)
+}
@@ -0,0 +1,83 @@
+package geval
+
+import (
+	"fmt"
+	"reflect"
+	"text/template"
+)
+
+// FuncMap returns functions which evaluate expressions from text/template pipelines, using the package-level engine.
+// See Engine.FuncMap.
+func FuncMap() template.FuncMap {
+	return defaultEngine.FuncMap()
+}
+
+// FuncMap returns functions which evaluate expressions from text/template pipelines, compiling (and caching)
+// them with this engine. It can be converted to an html/template.FuncMap.
+//
+// evalRule evaluates an expression against the keys of a map, or the exported fields of a struct,
+// such as the template's data, as in `{{ if evalRule "score > 10" . }}`. The data may be left out
+// for expressions which need no parameters. An expression which fails stops the template with its error.
+func (e *Engine) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"evalRule": func(expression string, data ...interface{}) (interface{}, error) {
+			if len(data) > 1 {
+				return nil, fmt.Errorf("evalRule expects an expression and at most one map or struct, got %d values", len(data))
+			}
+
+			var parameters map[string]interface{}
+			if len(data) == 1 {
+				var err error
+				parameters, err = templateParameters(data[0])
+				if err != nil {
+					return nil, err
+				}
+			}
+			return e.Evaluate(expression, parameters)
+		},
+	}
+}
+
+// templateParameters returns the keys of the map, or the exported fields of the struct, [data].
+func templateParameters(data interface{}) (map[string]interface{}, error) {
+	if parameters, ok := data.(map[string]interface{}); ok {
+		return parameters, nil
+	}
+
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		parameters := make(map[string]interface{}, value.Len())
+		iterator := value.MapRange()
+		for iterator.Next() {
+			parameters[iterator.Key().String()] = iterator.Value().Interface()
+		}
+		return parameters, nil
+
+	case reflect.Struct:
+		parameters := make(map[string]interface{}, value.NumField())
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.IsExported() {
+				parameters[field.Name] = value.Field(i).Interface()
+			}
+		}
+		return parameters, nil
+	}
+
+	return nil, fmt.Errorf("evalRule expects a map or struct, got %T", data)
+}
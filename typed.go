@@ -0,0 +1,65 @@
+package geval
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/myfstd/geval/core"
+)
+
+// Compiled is an expression which is known to evaluate to a T.
+type Compiled[T any] struct {
+	expression *core.TEvaluableExpression
+}
+
+// CompileTyped compiles the expression, and verifies that, given parameters of the types declared in [schema],
+// it can evaluate to a T. Expressions whose result type cannot be known until they are evaluated are accepted,
+// and their results are checked when evaluated instead.
+func CompileTyped[T any](expression string, schema core.TSchema) (*Compiled[T], error) {
+	compiled, err := defaultEngine.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	var zero T
+	target := reflect.TypeOf(&zero).Elem()
+
+	resultType := compiled.TResultType(schema)
+	if !canHold(target, resultType) {
+		return nil, fmt.Errorf("geval: expression '%s' evaluates to a %v, which cannot be converted to %v", expression, resultType, target)
+	}
+
+	return &Compiled[T]{expression: compiled}, nil
+}
+
+// Evaluate evaluates the expression against [parameters], returning its result as a T -
+// or an error if a T cannot hold it exactly, as for EvaluateAs.
+func (c *Compiled[T]) Evaluate(parameters map[string]interface{}) (T, error) {
+	var zero T
+
+	result, err := c.expression.TEvaluate(parameters)
+	if err != nil {
+		return zero, err
+	}
+
+	return convertResult[T](result)
+}
+
+// canHold returns true if a value of the given static type may be converted to [target].
+func canHold(target reflect.Type, valueType core.TValueType) bool {
+	if target.Kind() == reflect.Interface || valueType == core.TAnyType {
+		return true
+	}
+
+	switch valueType {
+	case core.TNumberType:
+		return isNumericKind(target.Kind())
+	case core.TStringType:
+		return target.Kind() == reflect.String
+	case core.TBoolType:
+		return target.Kind() == reflect.Bool
+	case core.TArrayType:
+		return target.Kind() == reflect.Slice
+	}
+	return false
+}